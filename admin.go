@@ -0,0 +1,81 @@
+package otelchi
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// maxAdminRequestSize bounds the size of the settings posted to the
+// AdminHandler.
+const maxAdminRequestSize = 1 << 20
+
+// AdminHandler returns a handler controlling the capture settings of config
+// at runtime, meant to be mounted on an internal route:
+//
+//   - GET responds with the current settings, as JSON.
+//   - POST updates the settings given in the JSON request body, e.g.
+//     {"metadata_only": false} or {"route_metadata_only": {"/orders/{id}": false}},
+//     leaving the others unchanged, and responds with the updated settings.
+//     Route overrides are added to the existing ones, and setting a value
+//     to null unsets it, e.g. {"route_metadata_only": {"/orders/{id}": null}}
+//     removes the override of the route. The body is limited to 1 MiB.
+//   - DELETE unsets all the settings, restoring the behavior configured by
+//     the options of the middleware.
+//
+// The handler does not authenticate the requests, which is left to the
+// router it is mounted on. It panics if config is nil.
+func AdminHandler(config *CaptureConfig) http.Handler {
+	if config == nil {
+		panic("otelchi: AdminHandler with a nil CaptureConfig")
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			// the body is read before the settings are locked
+			data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxAdminRequestSize))
+			if err == nil {
+				err = config.modify(func(settings *CaptureSettings) error {
+					return patchCaptureSettings(settings, data)
+				})
+			}
+			if err != nil {
+				http.Error(w, "invalid capture settings: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		case http.MethodDelete:
+			config.Update(CaptureSettings{})
+		default:
+			w.Header().Set("Allow", "GET, POST, DELETE")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(config.Settings())
+	})
+}
+
+// patchCaptureSettings applies the JSON settings in data to settings. The
+// route overrides given as null are removed, which decoding them into the
+// map would set to false instead.
+func patchCaptureSettings(settings *CaptureSettings, data []byte) error {
+	if err := json.Unmarshal(data, settings); err != nil {
+		return err
+	}
+	var nulls struct {
+		RouteMetadataOnly map[string]*bool `json:"route_metadata_only"`
+	}
+	if err := json.Unmarshal(data, &nulls); err != nil {
+		return err
+	}
+	for route, metadataOnly := range nulls.RouteMetadataOnly {
+		if metadataOnly == nil {
+			delete(settings.RouteMetadataOnly, route)
+		}
+	}
+	if len(settings.RouteMetadataOnly) == 0 {
+		settings.RouteMetadataOnly = nil
+	}
+	return nil
+}
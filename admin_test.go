@@ -0,0 +1,89 @@
+package otelchi
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestAdminHandler(t *testing.T) {
+	metadataOnly := true
+	c := NewCaptureConfig(CaptureSettings{MetadataOnly: &metadataOnly, MaxBodySize: 16})
+	admin := AdminHandler(c)
+	serve := func(method, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		admin.ServeHTTP(w, httptest.NewRequest(method, "/", strings.NewReader(body)))
+		return w
+	}
+
+	w := serve("GET", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"metadata_only": true, "max_body_size": 16}`, w.Body.String())
+
+	w = serve("POST", `{"capture_ratio": 0.5, "route_metadata_only": {"/user/{id}": false}}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"metadata_only": true, "max_body_size": 16, "capture_ratio": 0.5, "route_metadata_only": {"/user/{id}": false}}`, w.Body.String())
+	w = serve("POST", `{"metadata_only": null, "route_metadata_only": {"/login": true}}`)
+	assert.JSONEq(t, `{"max_body_size": 16, "capture_ratio": 0.5, "route_metadata_only": {"/user/{id}": false, "/login": true}}`, w.Body.String())
+
+	w = serve("POST", `{"route_metadata_only": {"/login": null}}`)
+	assert.JSONEq(t, `{"max_body_size": 16, "capture_ratio": 0.5, "route_metadata_only": {"/user/{id}": false}}`, w.Body.String())
+	w = serve("POST", `{"route_metadata_only": {"/user/{id}": null}}`)
+	assert.JSONEq(t, `{"max_body_size": 16, "capture_ratio": 0.5}`, w.Body.String())
+
+	// invalid settings are rejected
+	w = serve("POST", `{"capture_ratio": "all"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, 0.5, *c.Settings().CaptureRatio)
+	w = serve("POST", `{"header_allowlist": ["`+strings.Repeat("a", maxAdminRequestSize)+`"]}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Nil(t, c.Settings().HeaderAllowlist)
+
+	w = serve("DELETE", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, CaptureSettings{}, c.Settings())
+
+	w = serve("PUT", "{}")
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Equal(t, "GET, POST, DELETE", w.Header().Get("Allow"))
+
+	assert.Panics(t, func() { AdminHandler(nil) })
+}
+
+func TestSDKIntegrationWithRouteMetadataOnly(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	c := NewCaptureConfig(CaptureSettings{})
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar", WithTracerProvider(provider), WithChiRoutes(router), WithMetadataOnly(true), WithCaptureConfig(c)))
+	router.HandleFunc("/user/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+	})
+	router.HandleFunc("/order/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+	})
+	router.Mount("/admin/capture", AdminHandler(c))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/admin/capture", strings.NewReader(`{"route_metadata_only": {"/user/{id}": false}}`)))
+	require.Equal(t, http.StatusOK, w.Code)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("PUT", "/user/123", strings.NewReader("valid")))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("PUT", "/order/123", strings.NewReader("valid")))
+
+	spans := sr.Ended()
+	require.Len(t, spans, 3)
+	assert.Contains(t, spans[1].Attributes(), attribute.String("http.request.body", "valid"))
+	assert.False(t, hasAttribute(spans[2].Attributes(), "http.request.body"))
+}
@@ -0,0 +1,112 @@
+package otelchi
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+)
+
+const (
+	baggageTruncatedKey      = attribute.Key("baggage.truncated")
+	baggageDroppedMembersKey = attribute.Key("baggage.dropped_members")
+	baggageDroppedBytesKey   = attribute.Key("baggage.dropped_bytes")
+	baggageDroppedKeysKey    = attribute.Key("baggage.dropped_keys")
+)
+
+// baggageLimits bounds the incoming baggage. A zero limit is no limit.
+type baggageLimits struct {
+	maxMembers int
+	maxBytes   int
+}
+
+// enforce drops the members of the baggage of ctx past the limits, keeping
+// them in the order the client sent them, and returns the attributes
+// reporting what was dropped, if anything.
+func (l *baggageLimits) enforce(ctx context.Context, header http.Header) (context.Context, []attribute.KeyValue) {
+	bag := baggage.FromContext(ctx)
+	if bag.Len() == 0 {
+		return ctx, nil
+	}
+	order := baggageKeyOrder(header)
+	members := bag.Members()
+	sort.SliceStable(members, func(i, j int) bool {
+		return order.before(members[i].Key(), members[j].Key())
+	})
+
+	var (
+		kept    []baggage.Member
+		size    int
+		dropped []string
+		lost    int
+	)
+	for _, member := range members {
+		n := len(member.String())
+		if len(kept) > 0 {
+			// the list separator
+			n++
+		}
+		if (l.maxMembers > 0 && len(kept) >= l.maxMembers) || (l.maxBytes > 0 && size+n > l.maxBytes) {
+			dropped = append(dropped, member.Key())
+			lost += len(member.String())
+			continue
+		}
+		kept = append(kept, member)
+		size += n
+	}
+	if len(dropped) == 0 {
+		return ctx, nil
+	}
+
+	truncated, err := baggage.New(kept...)
+	if err != nil {
+		otel.Handle(err)
+		return ctx, nil
+	}
+	return baggage.ContextWithBaggage(ctx, truncated), []attribute.KeyValue{
+		baggageTruncatedKey.Bool(true),
+		baggageDroppedMembersKey.Int(len(dropped)),
+		baggageDroppedBytesKey.Int(lost),
+		baggageDroppedKeysKey.StringSlice(dropped),
+	}
+}
+
+// baggageOrder ranks baggage keys by their first position in the baggage
+// header.
+type baggageOrder map[string]int
+
+func baggageKeyOrder(header http.Header) baggageOrder {
+	order := baggageOrder{}
+	for _, value := range header.Values("Baggage") {
+		for _, member := range strings.Split(value, ",") {
+			key := member
+			if i := strings.IndexAny(member, "=;"); i >= 0 {
+				key = member[:i]
+			}
+			key = strings.TrimSpace(key)
+			if _, ok := order[key]; !ok {
+				order[key] = len(order)
+			}
+		}
+	}
+	return order
+}
+
+// before reports whether a comes before b. Keys missing from the header
+// come last, by name.
+func (o baggageOrder) before(a, b string) bool {
+	i, aok := o[a]
+	j, bok := o[b]
+	switch {
+	case aok && bok:
+		return i < j
+	case aok != bok:
+		return aok
+	default:
+		return a < b
+	}
+}
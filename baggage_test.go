@@ -0,0 +1,86 @@
+package otelchi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSDKIntegrationWithBaggageLimits(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		maxMembers int
+		maxBytes   int
+		want       []string
+		dropped    []string
+		lost       int
+	}{
+		{
+			name:       "members",
+			maxMembers: 2,
+			want:       []string{"tenant", "user"},
+			dropped:    []string{"blob"},
+			lost:       len("blob=0123456789"),
+		},
+		{
+			name:     "bytes",
+			maxBytes: len("tenant=acme,user=42,"),
+			want:     []string{"tenant", "user"},
+			dropped:  []string{"blob"},
+			lost:     len("blob=0123456789"),
+		},
+		{
+			name:       "within limits",
+			maxMembers: 3,
+			want:       []string{"blob", "tenant", "user"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			sr := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider()
+			provider.RegisterSpanProcessor(sr)
+
+			var got []string
+			router := chi.NewRouter()
+			router.Use(Middleware("foobar",
+				WithTracerProvider(provider),
+				WithPropagators(propagation.Baggage{}),
+				WithBaggageLimits(tc.maxMembers, tc.maxBytes),
+			))
+			router.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+				for _, m := range baggage.FromContext(r.Context()).Members() {
+					got = append(got, m.Key())
+				}
+			})
+
+			r := httptest.NewRequest("GET", "/user", nil)
+			r.Header.Set("Baggage", "tenant=acme,user=42,blob=0123456789")
+			router.ServeHTTP(httptest.NewRecorder(), r)
+
+			assert.ElementsMatch(t, tc.want, got)
+			require.Len(t, sr.Ended(), 1)
+			if tc.dropped == nil {
+				for _, a := range sr.Ended()[0].Attributes() {
+					assert.NotEqual(t, attribute.Key("baggage.truncated"), a.Key)
+				}
+				return
+			}
+			assertSpan(t, sr.Ended()[0], "/user", trace.SpanKindServer,
+				attribute.Bool("baggage.truncated", true),
+				attribute.Int("baggage.dropped_members", len(tc.dropped)),
+				attribute.Int("baggage.dropped_bytes", tc.lost),
+				attribute.StringSlice("baggage.dropped_keys", tc.dropped),
+			)
+		})
+	}
+}
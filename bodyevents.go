@@ -0,0 +1,22 @@
+package otelchi
+
+import (
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// addBodyEvent adds the event named name recording a body with attrs, at
+// the time the body ended. Bodies whose end is unknown, e.g. request
+// bodies the handler did not read in full, are stamped with the current
+// time of clock. Nothing is added without attributes, i.e. for empty bodies.
+func addBodyEvent(span oteltrace.Span, clock func() time.Time, name string, ended time.Time, attrs []attribute.KeyValue) {
+	if len(attrs) == 0 {
+		return
+	}
+	if ended.IsZero() {
+		ended = now(clock)
+	}
+	span.AddEvent(name, oteltrace.WithTimestamp(ended), oteltrace.WithAttributes(attrs...))
+}
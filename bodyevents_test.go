@@ -0,0 +1,54 @@
+package otelchi
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSDKIntegrationWithBodiesAsEvents(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar", WithTracerProvider(provider), WithBodiesAsEvents()))
+	router.HandleFunc("/user/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		time.Sleep(10 * time.Millisecond)
+		_, _ = w.Write([]byte(`{"id":"123"}`))
+		time.Sleep(10 * time.Millisecond)
+	})
+
+	r := httptest.NewRequest("PUT", "/user/123", strings.NewReader(`{"name":"foo"}`))
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	require.Len(t, sr.Ended(), 1)
+	span := sr.Ended()[0]
+	for _, a := range span.Attributes() {
+		assert.NotEqual(t, attribute.Key("http.request.body"), a.Key)
+		assert.NotEqual(t, attribute.Key("http.response.body"), a.Key)
+	}
+
+	events := span.Events()
+	require.Len(t, events, 2)
+	assert.Equal(t, "http.request.body", events[0].Name)
+	assert.Equal(t, []attribute.KeyValue{attribute.String("http.request.body", `{"name":"foo"}`)}, events[0].Attributes)
+	assert.Equal(t, "http.response.body", events[1].Name)
+	assert.Equal(t, []attribute.KeyValue{attribute.String("http.response.body", `{"id":"123"}`)}, events[1].Attributes)
+
+	// the events are stamped when the bodies ended, not when the span did
+	assert.True(t, events[0].Time.Before(events[1].Time))
+	assert.GreaterOrEqual(t, events[1].Time.Sub(events[0].Time), 10*time.Millisecond)
+	assert.GreaterOrEqual(t, span.EndTime().Sub(events[1].Time), 10*time.Millisecond)
+}
@@ -0,0 +1,21 @@
+package otelchi
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	requestBodySizeKey  = attribute.Key("http.request.body.size")
+	responseBodySizeKey = attribute.Key("http.response.body.size")
+)
+
+// bodySizeAttributes returns the number of bytes read from the request
+// body and written to the response body. They are tracked whether or not
+// the payloads are captured. Requests without a body have no request size.
+func bodySizeAttributes(bw *bodyWrapper, rrw *recordingResponseWriter) []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, 2)
+	if bw.ReadCloser != nil {
+		attrs = append(attrs, requestBodySizeKey.Int64(bw.read))
+	}
+	return append(attrs, responseBodySizeKey.Int64(rrw.bytesWritten))
+}
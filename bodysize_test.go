@@ -0,0 +1,50 @@
+package otelchi
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSDKIntegrationWithBodySizes(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar", WithTracerProvider(provider), WithMetadataOnly(true)))
+	router.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		_, _ = w.Write([]byte("created"))
+	})
+
+	r := httptest.NewRequest("POST", "/user", strings.NewReader(`{"name":"foo"}`))
+	router.ServeHTTP(httptest.NewRecorder(), r)
+	r = httptest.NewRequest("GET", "/user", nil)
+	r.Body = http.NoBody
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	spans := sr.Ended()
+	require.Len(t, spans, 2)
+	assertSpan(t, spans[0], "/user", trace.SpanKindServer,
+		attribute.Int64("http.request.body.size", 14),
+		attribute.Int64("http.response.body.size", 7),
+	)
+	assert.NotContains(t, spans[0].Attributes(), attribute.String("http.request.body", `{"name":"foo"}`))
+	assertSpan(t, spans[1], "/user", trace.SpanKindServer,
+		attribute.Int64("http.response.body.size", 7),
+	)
+	for _, a := range spans[1].Attributes() {
+		assert.NotEqual(t, attribute.Key("http.request.body.size"), a.Key)
+	}
+}
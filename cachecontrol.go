@@ -0,0 +1,59 @@
+package otelchi
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	cachePolicyViolationKey = attribute.Key("http.cache.policy_violation")
+	cachePolicyExpectedKey  = attribute.Key("http.cache.policy.expected")
+	cachePolicyActualKey    = attribute.Key("http.cache.policy.actual")
+)
+
+// cacheControlAttributes compares the Cache-Control response header against
+// the policy expected for the route. Directives are compared regardless of
+// their order and case. Nothing is returned for routes without a policy.
+func cacheControlAttributes(policies map[string]string, routePattern string, header http.Header) []attribute.KeyValue {
+	expected, ok := policies[routePattern]
+	if !ok {
+		return nil
+	}
+	actual := strings.Join(header.Values("Cache-Control"), ", ")
+	if cacheDirectivesEqual(expected, actual) {
+		return []attribute.KeyValue{cachePolicyViolationKey.Bool(false)}
+	}
+	return []attribute.KeyValue{
+		cachePolicyViolationKey.Bool(true),
+		cachePolicyExpectedKey.String(expected),
+		cachePolicyActualKey.String(actual),
+	}
+}
+
+func cacheDirectivesEqual(a, b string) bool {
+	da, db := cacheDirectives(a), cacheDirectives(b)
+	if len(da) != len(db) {
+		return false
+	}
+	for i := range da {
+		if da[i] != db[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func cacheDirectives(value string) []string {
+	var directives []string
+	for _, d := range strings.Split(value, ",") {
+		d = strings.ToLower(strings.TrimSpace(d))
+		if d != "" {
+			directives = append(directives, strings.Replace(d, " ", "", -1))
+		}
+	}
+	sort.Strings(directives)
+	return directives
+}
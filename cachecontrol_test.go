@@ -0,0 +1,57 @@
+package otelchi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSDKIntegrationWithCacheControlPolicies(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar",
+		WithTracerProvider(provider),
+		WithCacheControlPolicies(map[string]string{
+			"/products/{id}": "public, max-age=300",
+			"/cart":          "no-store",
+		}),
+	))
+	router.HandleFunc("/products/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=300, Public")
+		w.WriteHeader(http.StatusOK)
+	})
+	router.HandleFunc("/cart", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=60")
+		w.WriteHeader(http.StatusOK)
+	})
+	router.HandleFunc("/book/{title}", ok)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/products/1", nil))
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/cart", nil))
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/book/foo", nil))
+
+	require.Len(t, sr.Ended(), 3)
+	assertSpan(t, sr.Ended()[0], "/products/{id}", trace.SpanKindServer,
+		attribute.Bool("http.cache.policy_violation", false),
+	)
+	assertSpan(t, sr.Ended()[1], "/cart", trace.SpanKindServer,
+		attribute.Bool("http.cache.policy_violation", true),
+		attribute.String("http.cache.policy.expected", "no-store"),
+		attribute.String("http.cache.policy.actual", "public, max-age=60"),
+	)
+	for _, kv := range sr.Ended()[2].Attributes() {
+		assert.NotEqual(t, attribute.Key("http.cache.policy_violation"), kv.Key)
+	}
+}
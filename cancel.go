@@ -0,0 +1,88 @@
+package otelchi
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+const (
+	cancelCauseKey     = attribute.Key("http.request.cancel.cause")
+	cancelStageKey     = attribute.Key("http.request.cancel.stage")
+	requestAbortedKey  = attribute.Key("http.request.aborted")
+	clientDisconnected = "client.disconnected"
+)
+
+// Stages of the request in which a cancellation can be observed.
+const (
+	stageReadingBody     = "reading_body"
+	stageHandler         = "handler"
+	stageWritingResponse = "writing_response"
+)
+
+// cancellationTracker records the stage of the request in which the
+// cancellation of the request context was first observed.
+type cancellationTracker struct {
+	ctx context.Context
+
+	mu    sync.Mutex
+	stage string
+}
+
+func newCancellationTracker(ctx context.Context) *cancellationTracker {
+	return &cancellationTracker{ctx: ctx}
+}
+
+// observe is called with the result of an I/O operation done in stage. The
+// stage is recorded if the operation failed because the request context is
+// done.
+func (c *cancellationTracker) observe(stage string, err error) {
+	if c == nil || err == nil || c.ctx.Err() == nil {
+		return
+	}
+	c.mu.Lock()
+	if c.stage == "" {
+		c.stage = stage
+	}
+	c.mu.Unlock()
+}
+
+// attributes returns the cancellation cause and stage, or nothing if the
+// request context is not done. Cancellations not observed while reading the
+// body or writing the response are attributed to the handler.
+func (c *cancellationTracker) attributes() []attribute.KeyValue {
+	if c.ctx.Err() == nil {
+		return nil
+	}
+	c.mu.Lock()
+	stage := c.stage
+	c.mu.Unlock()
+	if stage == "" {
+		stage = stageHandler
+	}
+	return []attribute.KeyValue{
+		cancelCauseKey.String(contextCause(c.ctx).Error()),
+		cancelStageKey.String(stage),
+	}
+}
+
+// aborted reports whether the request was canceled, typically by the
+// client disconnecting, before any response was written.
+func (c *cancellationTracker) aborted(rrw *recordingResponseWriter) bool {
+	return !rrw.written && errors.Is(c.ctx.Err(), context.Canceled)
+}
+
+// recordAborted marks the span of an aborted request with a
+// client.disconnected event and an error status, in place of the status
+// code of the response that was never written.
+func (c *cancellationTracker) recordAborted(span oteltrace.Span) {
+	span.AddEvent(clientDisconnected, oteltrace.WithAttributes(
+		cancelCauseKey.String(contextCause(c.ctx).Error()),
+	))
+	span.SetAttributes(requestAbortedKey.Bool(true))
+	span.SetStatus(codes.Error, "request canceled before a response was written")
+}
@@ -0,0 +1,11 @@
+//go:build go1.20
+// +build go1.20
+
+package otelchi
+
+import "context"
+
+// contextCause returns the cause of the cancellation of ctx.
+func contextCause(ctx context.Context) error {
+	return context.Cause(ctx)
+}
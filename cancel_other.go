@@ -0,0 +1,12 @@
+//go:build !go1.20
+// +build !go1.20
+
+package otelchi
+
+import "context"
+
+// contextCause returns the cause of the cancellation of ctx. Before Go 1.20
+// only the context error is available.
+func contextCause(ctx context.Context) error {
+	return ctx.Err()
+}
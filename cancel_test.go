@@ -0,0 +1,100 @@
+package otelchi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func TestSDKIntegrationWithCancellation(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar", WithTracerProvider(provider)))
+	router.HandleFunc("/upload", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+	})
+	router.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	router.HandleFunc("/user/{id}", ok)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/upload", io.NopCloser(failingReader{})).WithContext(ctx))
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/slow", nil).WithContext(ctx))
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/user/123", nil))
+
+	require.Len(t, sr.Ended(), 3)
+	assertSpan(t, sr.Ended()[0], "/upload", trace.SpanKindServer,
+		attribute.String("http.request.cancel.cause", "context canceled"),
+		attribute.String("http.request.cancel.stage", "reading_body"),
+	)
+	assertSpan(t, sr.Ended()[1], "/slow", trace.SpanKindServer,
+		attribute.String("http.request.cancel.cause", "context canceled"),
+		attribute.String("http.request.cancel.stage", "handler"),
+	)
+	for _, kv := range sr.Ended()[2].Attributes() {
+		assert.NotEqual(t, attribute.Key("http.request.cancel.cause"), kv.Key)
+	}
+}
+
+func TestSDKIntegrationWithClientDisconnect(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar", WithTracerProvider(provider)))
+	router.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	router.HandleFunc("/written", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		<-r.Context().Done()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/slow", nil).WithContext(ctx))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/written", nil).WithContext(ctx))
+
+	spans := sr.Ended()
+	require.Len(t, spans, 2)
+	assertSpan(t, spans[0], "/slow", trace.SpanKindServer,
+		attribute.Bool("http.request.aborted", true),
+	)
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	require.Len(t, spans[0].Events(), 1)
+	assert.Equal(t, "client.disconnected", spans[0].Events()[0].Name)
+	for _, kv := range spans[0].Attributes() {
+		assert.NotEqual(t, attribute.Key("http.status_code"), kv.Key)
+	}
+
+	// the client got a response
+	assertSpan(t, spans[1], "/written", trace.SpanKindServer,
+		attribute.Int("http.status_code", http.StatusAccepted),
+	)
+	assert.Empty(t, spans[1].Events())
+}
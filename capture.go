@@ -0,0 +1,87 @@
+package otelchi
+
+import (
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// CaptureOutcome describes how a request was served, for the capture
+// predicates to decide whether the payloads buffered while serving it are
+// attached to its span.
+type CaptureOutcome struct {
+	Request *http.Request
+	// Route is the route pattern the request was routed to, if any.
+	Route string
+	// Status is the response status code. A response never written
+	// counts as a 200.
+	Status   int
+	Duration time.Duration
+	// SpanStatus is the status code of the span, as set by the handler
+	// or derived from the response status code.
+	SpanStatus codes.Code
+}
+
+// CapturePredicate reports whether the payloads of a request are attached
+// to its span.
+type CapturePredicate func(outcome CaptureOutcome) bool
+
+// statusCapturePredicate accepts the requests answered with a status code
+// of at least min.
+func statusCapturePredicate(min int) CapturePredicate {
+	return func(outcome CaptureOutcome) bool {
+		return outcome.Status >= min
+	}
+}
+
+// recordedStatus returns the status code set on span, looking through the
+// spans the middleware wraps the SDK span in. ok is false unless the span
+// is recorded by the SDK.
+func recordedStatus(span oteltrace.Span) (code codes.Code, ok bool) {
+	for {
+		switch s := span.(type) {
+		case sdktrace.ReadOnlySpan:
+			return s.Status().Code, true
+		case *mirrorSpan:
+			span = s.Span
+		case *truncatingSpan:
+			span = s.Span
+		case *clockSpan:
+			span = s.Span
+		default:
+			return codes.Unset, false
+		}
+	}
+}
+
+// attachPayloads reports whether the payloads buffered while serving a
+// request are attached to its span, i.e. whether all the capture
+// predicates accept its outcome.
+func (tw traceware) attachPayloads(r *http.Request, routePattern string, rrw *recordingResponseWriter, start time.Time, span oteltrace.Span) bool {
+	if len(tw.capturePredicates) == 0 {
+		return true
+	}
+	outcome := CaptureOutcome{
+		Request:  r,
+		Route:    routePattern,
+		Status:   rrw.status,
+		Duration: now(tw.clock).Sub(start),
+	}
+	if !rrw.written {
+		outcome.Status = http.StatusOK
+	}
+	if code, ok := recordedStatus(span); ok {
+		outcome.SpanStatus = code
+	} else {
+		outcome.SpanStatus, _ = tw.spanStatus(outcome.Status)
+	}
+	for _, predicate := range tw.capturePredicates {
+		if !predicate(outcome) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,108 @@
+package otelchi
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSDKIntegrationWithCaptureOnStatus(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar", WithTracerProvider(provider), WithCaptureOnStatus(http.StatusBadRequest)))
+	router.HandleFunc("/user/{id}", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) == "invalid" {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		}
+		_, _ = w.Write([]byte("done"))
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("PUT", "/user/123", strings.NewReader("valid")))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("PUT", "/user/123", strings.NewReader("invalid")))
+
+	spans := sr.Ended()
+	require.Len(t, spans, 2)
+	for _, a := range spans[0].Attributes() {
+		assert.NotEqual(t, attribute.Key("http.request.body"), a.Key)
+		assert.NotEqual(t, attribute.Key("http.response.body"), a.Key)
+	}
+	assertSpan(t, spans[0], "/user/{id}", trace.SpanKindServer,
+		attribute.Int("http.status_code", http.StatusOK),
+		attribute.Int64("http.request.body.size", 5),
+	)
+	assertSpan(t, spans[1], "/user/{id}", trace.SpanKindServer,
+		attribute.Int("http.status_code", http.StatusUnprocessableEntity),
+		attribute.String("http.request.body", "invalid"),
+		attribute.String("http.response.body", "done"),
+	)
+}
+
+func TestSDKIntegrationWithCapturePredicate(t *testing.T) {
+	for name, opts := range map[string][]Option{
+		"sdk span":        nil,
+		"truncating span": {WithAttributeValueLimit(1024, false)},
+		"mirrored span":   {WithSecondaryTracerProvider(sdktrace.NewTracerProvider(), false)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			testCapturePredicate(t, opts)
+		})
+	}
+}
+
+func testCapturePredicate(t *testing.T, opts []Option) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	var outcomes []CaptureOutcome
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar", append(opts,
+		WithTracerProvider(provider),
+		WithCapturePredicate(func(outcome CaptureOutcome) bool {
+			outcomes = append(outcomes, outcome)
+			return outcome.SpanStatus == codes.Error || outcome.Duration > time.Hour
+		}),
+	)...))
+	router.HandleFunc("/user/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("fail") != "" {
+			// the request failed, but the client is told otherwise
+			trace.SpanFromContext(r.Context()).SetStatus(codes.Error, "partial failure")
+		}
+		_, _ = w.Write([]byte("done"))
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/user/123", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/user/123?fail=1", nil))
+
+	require.Len(t, outcomes, 2)
+	assert.Equal(t, "/user/{id}", outcomes[0].Route)
+	assert.Equal(t, http.StatusOK, outcomes[0].Status)
+	assert.Equal(t, codes.Unset, outcomes[0].SpanStatus)
+	assert.Positive(t, outcomes[0].Duration)
+	assert.Equal(t, codes.Error, outcomes[1].SpanStatus)
+
+	spans := sr.Ended()
+	require.Len(t, spans, 2)
+	for _, a := range spans[0].Attributes() {
+		assert.NotEqual(t, attribute.Key("http.response.body"), a.Key)
+	}
+	assertSpan(t, spans[1], "/user/{id}", trace.SpanKindServer,
+		attribute.String("http.response.body", "done"),
+	)
+}
@@ -0,0 +1,79 @@
+package otelchi
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// CapturedRequest is the summary of a traced request kept by a
+// CaptureBuffer. Headers and bodies are only set when payload capture is
+// enabled, and are redacted the same way as the span attributes.
+type CapturedRequest struct {
+	Time           time.Time   `json:"time"`
+	TraceID        string      `json:"trace_id"`
+	SpanID         string      `json:"span_id"`
+	Method         string      `json:"method"`
+	Path           string      `json:"path"`
+	Route          string      `json:"route,omitempty"`
+	Status         int         `json:"status"`
+	DurationMillis float64     `json:"duration_ms"`
+	RequestHeaders http.Header `json:"request_headers,omitempty"`
+	RequestBody    string      `json:"request_body,omitempty"`
+	ResponseBody   string      `json:"response_body,omitempty"`
+}
+
+// CaptureBuffer is a bounded in-memory ring buffer of the most recent
+// requests traced by the middleware it is given to with WithCaptureBuffer.
+// It implements http.Handler, serving the buffered requests as JSON, newest
+// first, which makes it a lightweight traffic inspector for environments
+// without a tracing backend, e.g. local development. Since it exposes
+// captured payloads, it must only be mounted on internal routes.
+type CaptureBuffer struct {
+	mu      sync.Mutex
+	entries []CapturedRequest
+	next    int
+	full    bool
+}
+
+// NewCaptureBuffer returns a CaptureBuffer keeping the last size requests.
+func NewCaptureBuffer(size int) *CaptureBuffer {
+	if size < 1 {
+		size = 1
+	}
+	return &CaptureBuffer{entries: make([]CapturedRequest, size)}
+}
+
+func (b *CaptureBuffer) add(entry CapturedRequest) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.entries[b.next] = entry
+	b.next = (b.next + 1) % len(b.entries)
+	if b.next == 0 {
+		b.full = true
+	}
+}
+
+// Captures returns the buffered requests, newest first.
+func (b *CaptureBuffer) Captures() []CapturedRequest {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	n := b.next
+	if b.full {
+		n = len(b.entries)
+	}
+	captures := make([]CapturedRequest, 0, n)
+	for i := 1; i <= n; i++ {
+		captures = append(captures, b.entries[(b.next-i+len(b.entries))%len(b.entries)])
+	}
+	return captures
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (b *CaptureBuffer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(b.Captures())
+}
@@ -0,0 +1,50 @@
+package otelchi
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestCaptureBuffer(t *testing.T) {
+	buffer := NewCaptureBuffer(2)
+	provider := sdktrace.NewTracerProvider()
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar", WithTracerProvider(provider), WithCaptureBuffer(buffer)))
+	router.HandleFunc("/echo/{n}", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(body)
+	})
+
+	for _, n := range []string{"1", "2", "3"} {
+		r := httptest.NewRequest("POST", "/echo/"+n, strings.NewReader("body "+n))
+		r.Header.Set("Authorization", "secret")
+		router.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	captures := buffer.Captures()
+	require.Len(t, captures, 2)
+	assert.Equal(t, "/echo/3", captures[0].Path)
+	assert.Equal(t, "/echo/2", captures[1].Path)
+	assert.Equal(t, "/echo/{n}", captures[0].Route)
+	assert.Equal(t, http.StatusOK, captures[0].Status)
+	assert.Equal(t, "body 3", captures[0].RequestBody)
+	assert.Equal(t, "body 3", captures[0].ResponseBody)
+	assert.Equal(t, "[REDACTED]", captures[0].RequestHeaders.Get("Authorization"))
+	assert.Len(t, captures[0].TraceID, 32)
+
+	w := httptest.NewRecorder()
+	buffer.ServeHTTP(w, httptest.NewRequest("GET", "/debug/captures", nil))
+	var served []CapturedRequest
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &served))
+	assert.Len(t, served, 2)
+}
@@ -0,0 +1,256 @@
+package otelchi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"gopkg.in/yaml.v3"
+)
+
+// CaptureSettings are the capture settings that can be changed on live
+// traffic through a CaptureConfig. The settings left unset keep the
+// behavior configured by the options of the middleware.
+type CaptureSettings struct {
+	// MetadataOnly overrides WithMetadataOnly.
+	MetadataOnly *bool `json:"metadata_only,omitempty" yaml:"metadata_only,omitempty"`
+	// RouteMetadataOnly overrides MetadataOnly for the route patterns it
+	// maps, e.g. to capture the payloads of a single route. The route of a
+	// request is only known before it is served with WithChiRoutes.
+	RouteMetadataOnly map[string]bool `json:"route_metadata_only,omitempty" yaml:"route_metadata_only,omitempty"`
+	// HeaderAllowlist overrides WithHeaderAllowlist.
+	HeaderAllowlist []string `json:"header_allowlist,omitempty" yaml:"header_allowlist,omitempty"`
+	// MaxBodySize overrides WithMaxBodySize.
+	MaxBodySize int `json:"max_body_size,omitempty" yaml:"max_body_size,omitempty"`
+	// CaptureRatio overrides WithBodyCaptureRatio.
+	CaptureRatio *float64 `json:"capture_ratio,omitempty" yaml:"capture_ratio,omitempty"`
+	// SkipCaptureRoutes lists the route patterns whose payloads are not
+	// captured.
+	SkipCaptureRoutes []string `json:"skip_capture_routes,omitempty" yaml:"skip_capture_routes,omitempty"`
+}
+
+// captureSettings are the settings of a CaptureConfig, prepared to be
+// applied to requests. Its methods may be called on nil settings, standing
+// for no CaptureConfig.
+type captureSettings struct {
+	CaptureSettings
+	headerAllowlist map[string]bool
+	skipRoutes      map[string]bool
+}
+
+func newCaptureSettings(settings CaptureSettings) *captureSettings {
+	s := &captureSettings{CaptureSettings: settings}
+	if settings.HeaderAllowlist != nil {
+		s.headerAllowlist = make(map[string]bool, len(settings.HeaderAllowlist))
+		for _, name := range settings.HeaderAllowlist {
+			s.headerAllowlist[http.CanonicalHeaderKey(name)] = true
+		}
+	}
+	s.skipRoutes = make(map[string]bool, len(settings.SkipCaptureRoutes))
+	for _, route := range settings.SkipCaptureRoutes {
+		s.skipRoutes[route] = true
+	}
+	return s
+}
+
+// metadataOnly returns whether only the metadata of a request to
+// routePattern is captured, given that it is by default otherwise.
+func (s *captureSettings) metadataOnly(otherwise bool, routePattern string) bool {
+	if s == nil {
+		return otherwise
+	}
+	if metadataOnly, ok := s.RouteMetadataOnly[routePattern]; ok && routePattern != "" {
+		return metadataOnly
+	}
+	if s.MetadataOnly == nil {
+		return otherwise
+	}
+	return *s.MetadataOnly
+}
+
+// captureRatio returns the body capture ratio, otherwise by default.
+func (s *captureSettings) captureRatio(otherwise float64) float64 {
+	if s == nil || s.CaptureRatio == nil {
+		return otherwise
+	}
+	return *s.CaptureRatio
+}
+
+// skipsCapture reports whether the payloads of routePattern are not
+// captured.
+func (s *captureSettings) skipsCapture(routePattern string) bool {
+	return s != nil && s.skipRoutes[routePattern]
+}
+
+// headerCapture returns hc with the header allowlist of the settings.
+func (s *captureSettings) headerCapture(hc headerCapture) headerCapture {
+	if s != nil && s.headerAllowlist != nil {
+		hc.allowlist = s.headerAllowlist
+	}
+	return hc
+}
+
+// limitBody returns body truncated to the maximum body size, otherwise by
+// default.
+func (s *captureSettings) limitBody(body []byte, otherwise int) []byte {
+	max := otherwise
+	if s != nil && s.MaxBodySize > 0 {
+		max = s.MaxBodySize
+	}
+	if max <= 0 || len(body) <= max {
+		return body
+	}
+	return body[:max]
+}
+
+// CaptureConfig holds capture settings applied to live traffic, see
+// WithCaptureConfig. The settings can be replaced at any time, each request
+// being captured with the settings current when it started.
+type CaptureConfig struct {
+	settings atomic.Value // *captureSettings
+
+	// mu serializes the updates of the settings, so that none is lost
+	// during a read-modify-write update
+	mu sync.Mutex
+}
+
+// NewCaptureConfig returns a CaptureConfig holding settings.
+func NewCaptureConfig(settings CaptureSettings) *CaptureConfig {
+	c := &CaptureConfig{}
+	c.Update(settings)
+	return c
+}
+
+// Settings returns the current settings.
+func (c *CaptureConfig) Settings() CaptureSettings {
+	return c.load().CaptureSettings
+}
+
+// Update replaces the settings with the given ones.
+func (c *CaptureConfig) Update(settings CaptureSettings) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.store(settings)
+}
+
+// store replaces the settings, c.mu being held.
+func (c *CaptureConfig) store(settings CaptureSettings) {
+	c.settings.Store(newCaptureSettings(settings))
+}
+
+// modify replaces the settings with the ones modified by fn, unless it
+// fails. fn is handed a copy of the current settings.
+func (c *CaptureConfig) modify(fn func(settings *CaptureSettings) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	settings := c.Settings().clone()
+	if err := fn(&settings); err != nil {
+		return err
+	}
+	c.store(settings)
+	return nil
+}
+
+// clone returns a deep copy of s.
+func (s CaptureSettings) clone() CaptureSettings {
+	if s.MetadataOnly != nil {
+		metadataOnly := *s.MetadataOnly
+		s.MetadataOnly = &metadataOnly
+	}
+	if s.RouteMetadataOnly != nil {
+		routes := make(map[string]bool, len(s.RouteMetadataOnly))
+		for route, metadataOnly := range s.RouteMetadataOnly {
+			routes[route] = metadataOnly
+		}
+		s.RouteMetadataOnly = routes
+	}
+	if s.HeaderAllowlist != nil {
+		s.HeaderAllowlist = append([]string{}, s.HeaderAllowlist...)
+	}
+	if s.CaptureRatio != nil {
+		ratio := *s.CaptureRatio
+		s.CaptureRatio = &ratio
+	}
+	if s.SkipCaptureRoutes != nil {
+		s.SkipCaptureRoutes = append([]string{}, s.SkipCaptureRoutes...)
+	}
+	return s
+}
+
+// load returns the current settings, nil if c is.
+func (c *CaptureConfig) load() *captureSettings {
+	if c == nil {
+		return nil
+	}
+	return c.settings.Load().(*captureSettings)
+}
+
+// LoadFile replaces the settings with the ones of the YAML or JSON file at
+// path. A file lacking some settings unsets them.
+func (c *CaptureConfig) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	settings, err := parseCaptureSettings(data)
+	if err != nil {
+		return fmt.Errorf("otelchi: capture config %s: %w", path, err)
+	}
+	c.Update(settings)
+	return nil
+}
+
+// parseCaptureSettings parses YAML or JSON settings.
+func parseCaptureSettings(data []byte) (CaptureSettings, error) {
+	var settings CaptureSettings
+	// YAML being a superset of JSON, this parses both
+	err := yaml.Unmarshal(data, &settings)
+	return settings, err
+}
+
+// WatchFile loads the settings of the YAML or JSON file at path, see
+// LoadFile, then reloads them whenever the file changes until ctx is done,
+// checking its modification time every interval. Failing to reload the file
+// leaves the settings unchanged, the error being reported to the global
+// OTel error handler. The interval must be positive.
+func (c *CaptureConfig) WatchFile(ctx context.Context, path string, interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("otelchi: capture config: non-positive watch interval %s", interval)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if err := c.LoadFile(path); err != nil {
+		return err
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			current, err := os.Stat(path)
+			if err != nil {
+				otel.Handle(fmt.Errorf("otelchi: capture config: %w", err))
+				continue
+			}
+			if current.ModTime().Equal(info.ModTime()) && current.Size() == info.Size() {
+				continue
+			}
+			info = current
+			if err := c.LoadFile(path); err != nil {
+				otel.Handle(err)
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,121 @@
+package otelchi
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestCaptureConfigLoadFile(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "capture.yaml")
+	require.NoError(t, os.WriteFile(yamlPath, []byte("metadata_only: true\nheader_allowlist: [Accept]\nmax_body_size: 16\n"), 0o600))
+	jsonPath := filepath.Join(dir, "capture.json")
+	require.NoError(t, os.WriteFile(jsonPath, []byte(`{"capture_ratio": 0.5, "skip_capture_routes": ["/login"]}`), 0o600))
+
+	metadataOnly, ratio := true, 0.5
+	c := NewCaptureConfig(CaptureSettings{})
+	require.NoError(t, c.LoadFile(yamlPath))
+	assert.Equal(t, CaptureSettings{MetadataOnly: &metadataOnly, HeaderAllowlist: []string{"Accept"}, MaxBodySize: 16}, c.Settings())
+	require.NoError(t, c.LoadFile(jsonPath))
+	assert.Equal(t, CaptureSettings{CaptureRatio: &ratio, SkipCaptureRoutes: []string{"/login"}}, c.Settings())
+
+	require.NoError(t, os.WriteFile(jsonPath, []byte(`{"capture_ratio": "all"}`), 0o600))
+	assert.Error(t, c.LoadFile(jsonPath))
+	// the settings are left unchanged
+	assert.Equal(t, CaptureSettings{CaptureRatio: &ratio, SkipCaptureRoutes: []string{"/login"}}, c.Settings())
+}
+
+func TestCaptureConfigWatchFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capture.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("max_body_size: 16\n"), 0o600))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := NewCaptureConfig(CaptureSettings{})
+	require.NoError(t, c.WatchFile(ctx, path, time.Millisecond))
+	assert.Equal(t, 16, c.Settings().MaxBodySize)
+
+	require.NoError(t, os.WriteFile(path, []byte("max_body_size: 32768\n"), 0o600))
+	assert.Eventually(t, func() bool {
+		return c.Settings().MaxBodySize == 32768
+	}, time.Second, time.Millisecond)
+
+	assert.Error(t, c.WatchFile(ctx, filepath.Join(t.TempDir(), "missing.yaml"), time.Millisecond))
+	assert.Error(t, c.WatchFile(ctx, path, 0))
+}
+
+func TestSDKIntegrationWithCaptureConfig(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	c := NewCaptureConfig(CaptureSettings{MaxBodySize: 4, HeaderAllowlist: []string{"accept"}})
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar", WithTracerProvider(provider), WithCaptureConfig(c)))
+	router.HandleFunc("/user/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		_, _ = w.Write([]byte("done"))
+	})
+	router.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+	})
+	serve := func(path string) {
+		r := httptest.NewRequest("PUT", path, strings.NewReader("valid"))
+		r.Header.Set("Accept", "text/plain")
+		r.Header.Set("X-Custom", "custom")
+		router.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	serve("/user/123")
+	metadataOnly := true
+	c.Update(CaptureSettings{MetadataOnly: &metadataOnly})
+	serve("/user/123")
+	c.Update(CaptureSettings{SkipCaptureRoutes: []string{"/login"}})
+	serve("/login")
+	serve("/user/123")
+
+	spans := sr.Ended()
+	require.Len(t, spans, 4)
+	assert.Contains(t, spans[0].Attributes(), attribute.String("http.request.body", "vali"))
+	assert.Contains(t, spans[0].Attributes(), attribute.String("http.request.headers", `{"Accept":["text/plain"]}`))
+	assert.False(t, hasAttribute(spans[1].Attributes(), "http.request.body"))
+	assert.False(t, hasAttribute(spans[2].Attributes(), "http.request.body"))
+	assert.Contains(t, spans[3].Attributes(), attribute.String("http.request.body", "valid"))
+	assert.Contains(t, spans[3].Attributes(), attribute.String("http.request.headers", `{"Accept":["text/plain"],"X-Custom":["custom"]}`))
+}
+
+func TestCaptureConfigHeaderAllowlistInCaptureBuffer(t *testing.T) {
+	buffer := NewCaptureBuffer(1)
+	c := NewCaptureConfig(CaptureSettings{HeaderAllowlist: []string{"Accept"}})
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar",
+		WithTracerProvider(sdktrace.NewTracerProvider()),
+		WithHeaderAllowlist("Accept", "X-Custom"),
+		WithCaptureBuffer(buffer),
+		WithCaptureConfig(c),
+	))
+	router.HandleFunc("/user/{id}", ok)
+
+	r := httptest.NewRequest("GET", "/user/123", nil)
+	r.Header.Set("Accept", "text/plain")
+	r.Header.Set("X-Custom", "custom")
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	captures := buffer.Captures()
+	require.Len(t, captures, 1)
+	assert.Equal(t, http.Header{"Accept": []string{"text/plain"}}, captures[0].RequestHeaders)
+}
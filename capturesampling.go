@@ -0,0 +1,104 @@
+package otelchi
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// sampleCapture reports whether the payloads of a request are captured,
+// given the body capture ratio.
+func sampleCapture(ratio float64) bool {
+	return ratio >= 1 || rand.Float64() < ratio
+}
+
+const captureDroppedKey = attribute.Key("capture.dropped")
+
+// captureLimiter limits the rate of the requests whose payloads are
+// captured with one token bucket per route. The buckets hold up to a
+// second worth of tokens. With a store, the requests are instead counted
+// in the store over fixed windows, so that the limit holds across the
+// replicas sharing it, the buckets being a fallback when it fails.
+type captureLimiter struct {
+	rate  float64
+	burst float64
+
+	store StateStore
+	// window is the length of the windows the requests are counted over
+	// in the store, and windowLimit the number of captures in each
+	window      time.Duration
+	windowLimit int64
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newCaptureLimiter(perSecond float64, store StateStore) *captureLimiter {
+	burst := perSecond
+	if burst < 1 {
+		burst = 1
+	}
+	// windows of a second, or long enough for a capture at low rates
+	window := time.Second
+	if perSecond < 1 {
+		window = time.Duration(float64(time.Second) / perSecond)
+	}
+	return &captureLimiter{
+		rate:        perSecond,
+		burst:       burst,
+		store:       store,
+		window:      window,
+		windowLimit: int64(math.Max(1, math.Floor(perSecond*window.Seconds()))),
+		buckets:     map[string]*tokenBucket{},
+	}
+}
+
+// allow reports whether the payloads of a request to routePattern at now
+// may be captured, counting it in the window of the route if so.
+func (l *captureLimiter) allow(ctx context.Context, routePattern string, now time.Time) bool {
+	if l.store != nil {
+		window := now.UnixNano() / int64(l.window)
+		key := "otelchi:capture:" + routePattern + ":" + strconv.FormatInt(window, 10)
+		count, err := l.store.Incr(ctx, key, 2*l.window)
+		if err == nil {
+			return count <= l.windowLimit
+		}
+		otel.Handle(fmt.Errorf("otelchi: capture rate limit: %w", err))
+	}
+	return l.allowLocally(routePattern, now)
+}
+
+// allowLocally takes a token from the bucket of the route, if any.
+func (l *captureLimiter) allowLocally(routePattern string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, ok := l.buckets[routePattern]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[routePattern] = b
+	}
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * l.rate
+		if b.tokens > l.burst {
+			b.tokens = l.burst
+		}
+		b.last = now
+	}
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
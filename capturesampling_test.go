@@ -0,0 +1,119 @@
+package otelchi
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSDKIntegrationWithBodyCaptureRatio(t *testing.T) {
+	for _, tc := range []struct {
+		ratio    float64
+		captured bool
+	}{
+		{ratio: 0, captured: false},
+		{ratio: 1, captured: true},
+	} {
+		sr := tracetest.NewSpanRecorder()
+		provider := sdktrace.NewTracerProvider()
+		provider.RegisterSpanProcessor(sr)
+
+		router := chi.NewRouter()
+		router.Use(Middleware("foobar", WithTracerProvider(provider), WithBodyCaptureRatio(tc.ratio)))
+		router.HandleFunc("/user/{id}", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.ReadAll(r.Body)
+			_, _ = w.Write([]byte("done"))
+		})
+
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("PUT", "/user/123", strings.NewReader("valid")))
+
+		// the request is traced either way
+		spans := sr.Ended()
+		require.Len(t, spans, 1)
+		assert.Equal(t, tc.captured, hasAttribute(spans[0].Attributes(), "http.request.body"), tc.ratio)
+		assert.Equal(t, tc.captured, hasAttribute(spans[0].Attributes(), "http.response.body"), tc.ratio)
+	}
+}
+
+func TestCaptureLimiter(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	l := newCaptureLimiter(2, nil)
+	assert.True(t, l.allow(ctx, "/user/{id}", now))
+	assert.True(t, l.allow(ctx, "/user/{id}", now))
+	assert.False(t, l.allow(ctx, "/user/{id}", now))
+	// routes have their own bucket
+	assert.True(t, l.allow(ctx, "/order/{id}", now))
+
+	// tokens are refilled over time, up to the burst
+	assert.True(t, l.allow(ctx, "/user/{id}", now.Add(500*time.Millisecond)))
+	assert.False(t, l.allow(ctx, "/user/{id}", now.Add(500*time.Millisecond)))
+	now = now.Add(time.Hour)
+	assert.True(t, l.allow(ctx, "/user/{id}", now))
+	assert.True(t, l.allow(ctx, "/user/{id}", now))
+	assert.False(t, l.allow(ctx, "/user/{id}", now))
+}
+
+func TestCaptureLimiterStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1000, 0)
+	// the replicas share the limit through the store
+	store := NewMemoryStateStore(100)
+	replicas := []*captureLimiter{newCaptureLimiter(2, store), newCaptureLimiter(2, store)}
+	assert.True(t, replicas[0].allow(ctx, "/user/{id}", now))
+	assert.True(t, replicas[1].allow(ctx, "/user/{id}", now))
+	assert.False(t, replicas[0].allow(ctx, "/user/{id}", now.Add(500*time.Millisecond)))
+	assert.False(t, replicas[1].allow(ctx, "/user/{id}", now))
+	assert.True(t, replicas[1].allow(ctx, "/order/{id}", now))
+	// the next window
+	assert.True(t, replicas[1].allow(ctx, "/user/{id}", now.Add(time.Second)))
+
+	// low rates are counted over longer windows
+	slow := newCaptureLimiter(0.1, store)
+	assert.True(t, slow.allow(ctx, "/user/{id}", now))
+	assert.False(t, slow.allow(ctx, "/user/{id}", now.Add(5*time.Second)))
+}
+
+func TestSDKIntegrationWithBodyCaptureRateLimit(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar", WithTracerProvider(provider), WithChiRoutes(router), WithBodyCaptureRateLimit(0.001)))
+	router.HandleFunc("/user/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		_, _ = w.Write([]byte("done"))
+	})
+
+	for i := 0; i < 2; i++ {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("PUT", "/user/123", strings.NewReader("valid")))
+	}
+
+	spans := sr.Ended()
+	require.Len(t, spans, 2)
+	assert.Contains(t, spans[0].Attributes(), attribute.String("http.request.body", "valid"))
+	assert.False(t, hasAttribute(spans[0].Attributes(), "capture.dropped"))
+	assert.Contains(t, spans[1].Attributes(), attribute.Bool("capture.dropped", true))
+	assert.False(t, hasAttribute(spans[1].Attributes(), "http.request.body"))
+}
+
+func hasAttribute(attrs []attribute.KeyValue, key attribute.Key) bool {
+	for _, a := range attrs {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
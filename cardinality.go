@@ -0,0 +1,96 @@
+package otelchi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+)
+
+// otherSpanName is the name the span names past the cardinality limit are
+// collapsed into, prefixed by the request method.
+const otherSpanName = "<other>"
+
+// otherMethod replaces the request methods which are not known, as they are
+// chosen by clients, in the span names meant to have a bounded cardinality.
+const otherMethod = "_OTHER"
+
+// knownMethods are the methods defined by RFC 9110 and RFC 5789.
+var knownMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodConnect: true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+}
+
+// boundedMethod returns method if it is known, and otherMethod otherwise.
+func boundedMethod(method string) string {
+	if knownMethods[method] {
+		return method
+	}
+	return otherMethod
+}
+
+// spanNameGuard bounds the number of distinct span names given by the
+// middleware. Once limit names were seen, new names are collapsed into a
+// bucket name, e.g. "GET <other>", while the names seen before are kept.
+type spanNameGuard struct {
+	limit int
+
+	mu    sync.RWMutex
+	names map[string]struct{}
+	warn  sync.Once
+}
+
+func newSpanNameGuard(limit int) *spanNameGuard {
+	return &spanNameGuard{limit: limit, names: make(map[string]struct{}, limit)}
+}
+
+// guard returns name, or the bucket name of method if name is new and the
+// limit is reached, in which case collapsed is true.
+func (g *spanNameGuard) guard(method, name string) (guarded string, collapsed bool) {
+	if name == "" {
+		return name, false
+	}
+	g.mu.RLock()
+	_, seen := g.names[name]
+	g.mu.RUnlock()
+	if seen {
+		return name, false
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, seen := g.names[name]; !seen {
+		if len(g.names) >= g.limit {
+			return boundedMethod(method) + " " + otherSpanName, true
+		}
+		g.names[name] = struct{}{}
+	}
+	return name, false
+}
+
+// guardSpanName returns the span name to give a request instead of name,
+// counting the names collapsed by the cardinality guard, if any.
+func (tw traceware) guardSpanName(ctx context.Context, method, name string) string {
+	if tw.spanNameGuard == nil {
+		return name
+	}
+	guarded, collapsed := tw.spanNameGuard.guard(method, name)
+	if collapsed {
+		tw.spanNameGuard.warn.Do(func() {
+			otel.Handle(fmt.Errorf("otelchi: more than %d distinct span names, new names are collapsed into %q", tw.spanNameGuard.limit, guarded))
+		})
+		if tw.metrics != nil {
+			tw.metrics.spanNameCollapsed(ctx, boundedMethod(method))
+		}
+	}
+	return guarded
+}
@@ -0,0 +1,49 @@
+package otelchi
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSDKIntegrationWithSpanNameLimit(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar",
+		WithTracerProvider(provider),
+		WithRequestMethodInSpanName(true),
+		// a misconfigured normalizer naming spans after raw paths
+		WithPathNormalizer(func(path string) string { return path }),
+		WithSpanNameLimit(2),
+	))
+	router.HandleFunc("/*", ok)
+
+	for _, path := range []string{"/a", "/b", "/c", "/a", "/d"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	var names []string
+	for _, span := range sr.Ended() {
+		names = append(names, span.Name())
+	}
+	assert.Equal(t, []string{"GET /a", "GET /b", "GET <other>", "GET /a", "GET <other>"}, names)
+}
+
+func TestSpanNameGuardUnknownMethod(t *testing.T) {
+	g := newSpanNameGuard(1)
+	for _, tc := range []struct{ method, name, guarded string }{
+		{"GET", "GET /a", "GET /a"},
+		{"GET", "GET /b", "GET <other>"},
+		{"X-RANDOM-1", "X-RANDOM-1 /a", "_OTHER <other>"},
+	} {
+		guarded, _ := g.guard(tc.method, tc.name)
+		assert.Equal(t, tc.guarded, guarded)
+	}
+}
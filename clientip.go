@@ -0,0 +1,244 @@
+package otelchi
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+)
+
+const (
+	clientAddressKey       = attribute.Key("client.address")
+	clientAddressSourceKey = attribute.Key("client.address.source")
+)
+
+// Sources of the client address, recorded in the client.address.source
+// attribute.
+const (
+	clientSourcePeer      = "peer"
+	clientSourceForwarded = "forwarded"
+	clientSourceRealIP    = "real_ip"
+	clientSourceHeader    = "header"
+)
+
+// clientAddressResolver resolves the address of the client that sent the
+// request, skipping the proxies it went through.
+type clientAddressResolver struct {
+	trusted []*net.IPNet
+	realIP  bool
+}
+
+// newClientAddressResolver returns a resolver trusting the proxies in the
+// given CIDR ranges or IP addresses. Invalid entries are reported to the
+// global OTel error handler and ignored. When realIP is set, the resolver
+// cooperates with chi's middleware.RealIP, see WithRealIP.
+func newClientAddressResolver(trustedProxies []string, realIP bool) *clientAddressResolver {
+	c := &clientAddressResolver{realIP: realIP}
+	for _, proxy := range trustedProxies {
+		if !strings.Contains(proxy, "/") {
+			if ip := net.ParseIP(proxy); ip != nil {
+				bits := 8 * len(ip.To16())
+				if ip.To4() != nil {
+					ip, bits = ip.To4(), 32
+				}
+				c.trusted = append(c.trusted, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+				continue
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			otel.Handle(fmt.Errorf("otelchi: invalid trusted proxy %q: %w", proxy, err))
+			continue
+		}
+		c.trusted = append(c.trusted, ipNet)
+	}
+	return c
+}
+
+func (c *clientAddressResolver) isTrusted(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range c.trusted {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedHop is a hop of the forwarding chain: the address of the
+// client a proxy received the request from and, when the proxy reported
+// them, the scheme and host it was received with. The source tells where
+// the address was found.
+type forwardedHop struct {
+	address string
+	scheme  string
+	host    string
+	source  string
+}
+
+// resolve returns the client hop. The peer address is the client address
+// unless it is a trusted proxy, in which case the hops reported by the
+// Forwarded header, or the X-Forwarded-For header when it is absent, are
+// walked from the closest one, and the first hop that is not a trusted
+// proxy is the client. Hops added before that one can be spoofed by the
+// client and are ignored.
+//
+// In RealIP mode, a peer address without a port has been rewritten by
+// middleware.RealIP, or a similar middleware, and is the client address.
+// Otherwise, when no proxy is trusted, the headers used by RealIP are
+// parsed the same way, so that the result does not depend on whether this
+// middleware runs before or after RealIP.
+func (c *clientAddressResolver) resolve(r *http.Request) forwardedHop {
+	if c.realIP {
+		if _, _, err := net.SplitHostPort(r.RemoteAddr); err != nil && net.ParseIP(r.RemoteAddr) != nil {
+			return forwardedHop{address: r.RemoteAddr, source: clientSourceRealIP}
+		}
+		if len(c.trusted) == 0 {
+			if ip := realIP(r.Header); ip != "" {
+				return forwardedHop{address: ip, source: clientSourceHeader}
+			}
+		}
+	}
+	client := forwardedHop{address: remoteHost(r.RemoteAddr), source: clientSourcePeer}
+	if !c.isTrusted(client.address) {
+		return client
+	}
+	hops := forwardedHops(r.Header)
+	for i := len(hops) - 1; i >= 0; i-- {
+		client = hops[i]
+		client.source = clientSourceForwarded
+		if !c.isTrusted(client.address) {
+			break
+		}
+	}
+	return client
+}
+
+// realIP returns the client address the way middleware.RealIP finds it:
+// from the True-Client-IP, X-Real-IP or first X-Forwarded-For entry, in
+// that order.
+func realIP(header http.Header) string {
+	ip := header.Get("True-Client-IP")
+	if ip == "" {
+		ip = header.Get("X-Real-IP")
+	}
+	if ip == "" {
+		ip = firstListValue(header.Get("X-Forwarded-For"))
+	}
+	if net.ParseIP(ip) == nil {
+		return ""
+	}
+	return ip
+}
+
+func (c *clientAddressResolver) attributes(r *http.Request) []attribute.KeyValue {
+	client := c.resolve(r)
+	var attrs []attribute.KeyValue
+	if client.address != "" {
+		attrs = append(attrs,
+			clientAddressKey.String(client.address),
+			semconv.HTTPClientIPKey.String(client.address),
+			clientAddressSourceKey.String(client.source),
+		)
+	}
+	if client.scheme != "" {
+		attrs = append(attrs, semconv.HTTPSchemeKey.String(client.scheme))
+	}
+	if client.host != "" {
+		attrs = append(attrs, semconv.HTTPHostKey.String(client.host))
+	}
+	return attrs
+}
+
+// forwardedHops returns the hops listed by the Forwarded headers or, when
+// there are none, by the X-Forwarded-For headers, the closest proxy last.
+// X-Forwarded-For hops carry the scheme and host of the X-Forwarded-Proto
+// and X-Forwarded-Host headers, which are set by the first proxy only.
+func forwardedHops(header http.Header) []forwardedHop {
+	if values := header.Values("Forwarded"); len(values) > 0 {
+		return parseForwarded(values)
+	}
+	var hops []forwardedHop
+	for _, value := range header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(value, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, forwardedHop{address: hop})
+			}
+		}
+	}
+	if len(hops) > 0 {
+		hops[0].scheme = firstListValue(header.Get("X-Forwarded-Proto"))
+		hops[0].host = firstListValue(header.Get("X-Forwarded-Host"))
+	}
+	return hops
+}
+
+// parseForwarded parses RFC 7239 Forwarded header values. Elements without
+// a for parameter are skipped.
+func parseForwarded(values []string) []forwardedHop {
+	var hops []forwardedHop
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			var hop forwardedHop
+			for _, pair := range strings.Split(element, ";") {
+				i := strings.IndexByte(pair, '=')
+				if i < 0 {
+					continue
+				}
+				name := strings.ToLower(strings.TrimSpace(pair[:i]))
+				param := strings.Trim(strings.TrimSpace(pair[i+1:]), `"`)
+				switch name {
+				case "for":
+					hop.address = forwardedNode(param)
+				case "proto":
+					hop.scheme = strings.ToLower(param)
+				case "host":
+					hop.host = param
+				}
+			}
+			if hop.address != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	return hops
+}
+
+// forwardedNode returns the address of a Forwarded node, which may carry a
+// port and, for IPv6, brackets. Obfuscated identifiers and "unknown" are
+// returned as is.
+func forwardedNode(node string) string {
+	if strings.HasPrefix(node, "[") {
+		if i := strings.IndexByte(node, ']'); i > 0 {
+			return node[1:i]
+		}
+		return node
+	}
+	if i := strings.IndexByte(node, ':'); i >= 0 && strings.Count(node, ":") == 1 {
+		return node[:i]
+	}
+	return node
+}
+
+func firstListValue(value string) string {
+	if i := strings.IndexByte(value, ','); i >= 0 {
+		value = value[:i]
+	}
+	return strings.TrimSpace(value)
+}
+
+// remoteHost returns the host part of a remote address, which may lack the
+// port.
+func remoteHost(remoteAddr string) string {
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		return host
+	}
+	return remoteAddr
+}
@@ -0,0 +1,60 @@
+package otelchi
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+)
+
+func TestClientAddressResolver(t *testing.T) {
+	resolver := newClientAddressResolver([]string{"10.0.0.0/8", "192.168.1.1", "not-an-ip"}, false)
+
+	testCases := []struct {
+		name       string
+		remoteAddr string
+		xff        []string
+		want       string
+	}{
+		{name: "untrusted peer", remoteAddr: "203.0.113.7:1234", xff: []string{"198.51.100.1"}, want: "203.0.113.7"},
+		{name: "single trusted proxy", remoteAddr: "10.0.0.1:1234", xff: []string{"203.0.113.7"}, want: "203.0.113.7"},
+		{name: "spoofed first hop", remoteAddr: "10.0.0.1:1234", xff: []string{"1.2.3.4, 203.0.113.7", "192.168.1.1"}, want: "203.0.113.7"},
+		{name: "only trusted hops", remoteAddr: "10.0.0.1:1234", xff: []string{"10.0.0.2, 10.0.0.3"}, want: "10.0.0.2"},
+		{name: "no forwarded header", remoteAddr: "10.0.0.1:1234", want: "10.0.0.1"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			r := &http.Request{RemoteAddr: testCase.remoteAddr, Header: http.Header{}}
+			for _, value := range testCase.xff {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+			assert.Equal(t, testCase.want, resolver.resolve(r).address)
+		})
+	}
+}
+
+func TestClientAddressResolverForwarded(t *testing.T) {
+	resolver := newClientAddressResolver([]string{"10.0.0.0/8"}, false)
+
+	r := &http.Request{RemoteAddr: "10.0.0.1:1234", Header: http.Header{}}
+	r.Header.Add("Forwarded", `for=1.2.3.4;proto=http, for="[2001:db8::1]:4711";proto=https;host=example.com`)
+	r.Header.Add("Forwarded", "for=10.0.0.2:80;proto=http;host=internal")
+	r.Header.Set("X-Forwarded-For", "198.51.100.1")
+
+	attrs := resolver.attributes(r)
+	assert.Contains(t, attrs, clientAddressKey.String("2001:db8::1"))
+	assert.Contains(t, attrs, semconv.HTTPSchemeKey.String("https"))
+	assert.Contains(t, attrs, semconv.HTTPHostKey.String("example.com"))
+}
+
+func TestClientAddressResolverForwardedProto(t *testing.T) {
+	resolver := newClientAddressResolver([]string{"10.0.0.0/8"}, false)
+
+	r := &http.Request{RemoteAddr: "10.0.0.1:1234", Header: http.Header{}}
+	r.Header.Set("X-Forwarded-For", "203.0.113.7")
+	r.Header.Set("X-Forwarded-Proto", "https")
+	r.Header.Set("X-Forwarded-Host", "example.com")
+
+	assert.Equal(t, forwardedHop{address: "203.0.113.7", scheme: "https", host: "example.com", source: clientSourceForwarded}, resolver.resolve(r))
+}
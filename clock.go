@@ -0,0 +1,30 @@
+package otelchi
+
+import (
+	"time"
+
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// now returns the current time according to clock, the wall clock if nil.
+func now(clock func() time.Time) time.Time {
+	if clock == nil {
+		return time.Now()
+	}
+	return clock()
+}
+
+// clockSpan stamps the events added to the span, by the middleware or the
+// handler, with the time of clock, which the SDK does not know of.
+type clockSpan struct {
+	oteltrace.Span
+	clock func() time.Time
+}
+
+func (s *clockSpan) AddEvent(name string, options ...oteltrace.EventOption) {
+	s.Span.AddEvent(name, append([]oteltrace.EventOption{oteltrace.WithTimestamp(now(s.clock))}, options...)...)
+}
+
+func (s *clockSpan) RecordError(err error, options ...oteltrace.EventOption) {
+	s.Span.RecordError(err, append([]oteltrace.EventOption{oteltrace.WithTimestamp(now(s.clock))}, options...)...)
+}
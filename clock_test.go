@@ -0,0 +1,47 @@
+package otelchi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSDKIntegrationWithClockEvents(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	current := start
+	clock := func() time.Time {
+		current = current.Add(time.Millisecond)
+		return current
+	}
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar",
+		WithTracerProvider(provider),
+		WithClock(clock),
+		WithSlowRequestThreshold(time.Nanosecond),
+	))
+	router.HandleFunc("/user/{id}", func(w http.ResponseWriter, r *http.Request) {
+		trace.SpanFromContext(r.Context()).AddEvent("handled")
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/user/123", nil))
+
+	require.Len(t, sr.Ended(), 1)
+	span := sr.Ended()[0]
+	require.Len(t, span.Events(), 2)
+	for _, event := range span.Events() {
+		assert.True(t, event.Time.After(span.StartTime()), event.Name)
+		assert.True(t, event.Time.Before(span.EndTime()), event.Name)
+	}
+}
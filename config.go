@@ -2,8 +2,12 @@ package otelchi
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/propagation"
 	oteltrace "go.opentelemetry.io/otel/trace"
 )
@@ -14,7 +18,112 @@ type config struct {
 	Propagators             propagation.TextMapPropagator
 	ChiRoutes               chi.Routes
 	RequestMethodInSpanName bool
-	Filter                  func(r *http.Request) bool
+	Filters                 []func(r *http.Request) bool
+	PriorityPropagation     bool
+	PriorityHeader          string
+	BodyAttributeExtractors map[string]string
+	CacheControlPolicies    map[string]string
+	GraphQLRoutes           []string
+	XMLExtractors           map[string]string
+	XMLRedactedElements     []string
+	XMLCapture              bool
+	EnabledFunc             func() bool
+	RouteHeader             string
+	HeaderAttributes        bool
+	CookieCapture           bool
+	CookieAllowlist         []string
+	RedactedHeaders         []string
+	EventLimits             bool
+	DefaultEventLimit       int
+	RouteEventLimits        map[string]int
+	PathNormalizer          func(path string) string
+	MinimalAttributes       bool
+	HTTP2Attributes         bool
+	HTTP2StreamID           func(r *http.Request) (uint32, bool)
+	JWTEnduser              bool
+	JWTVerifier             JWTVerifier
+	TrustedProxies          []string
+	RealIP                  bool
+	CaptureBuffer           *CaptureBuffer
+	OperationNames          map[string]OperationNameFunc
+	QuarantineSink          QuarantineSink
+	QuarantineSampleEvery   int
+	HTMLTraceContext        bool
+	SecondaryTracerProvider oteltrace.TracerProvider
+	SecondaryFullCapture    bool
+	ZeroStatusPolicy        ZeroStatusPolicy
+	MetadataOnly            *bool
+	RouteOptions            []routeOptions
+	MultipartFileEvents     bool
+	RouteSampleRatios       map[string]float64
+	DebugHeader             string
+	DebugSecret             []byte
+	ContentLengthCheck      bool
+	StateStore              StateStore
+	IdempotencyHeader       string
+	SpanStartOptions        []oteltrace.SpanStartOption
+	AsyncEnricher           AsyncEnricher
+	AsyncEnrichmentDeadline time.Duration
+	SpanKind                oteltrace.SpanKind
+	WebSocketPolicy         WebSocketPolicy
+	FilteredPropagation     bool
+	StreamChunkEvents       bool
+	StreamChunkInterval     time.Duration
+	HeaderAllowlist         []string
+	BodyDedup               bool
+	BodyDedupWindow         time.Duration
+	ServerTiming            bool
+	BaggageLimits           *baggageLimits
+	MeterProvider           metric.MeterProvider
+	PrometheusMetricNames   bool
+	LogEmitter              LogEmitter
+	BodiesAsEvents          bool
+	CapturePredicates       []CapturePredicate
+	SlowRequestThreshold    time.Duration
+	RouteMatchTiming        bool
+	LazyRouteNaming         bool
+	RouteSamplerHint        bool
+	RouteCacheSize          int
+	ServerNameFn            func(r *http.Request) string
+	UnmatchedSpanNames      *unmatchedSpanNames
+	SpanNameLimit           int
+	PropagatorAutodetection bool
+	QueryTraceContext       *queryTraceContext
+	HeaderLinks             []headerLink
+	SyntheticDetection      bool
+	UserAgentParser         UserAgentParser
+	RequestID               *requestIDCorrelation
+	SpanStatusMapper        func(status int) (codes.Code, string)
+	ErrorEvents             bool
+	StackTraces             bool
+	StackTraceDepth         int
+	StackTraceSkips         []string
+	SpanHooks               []spanHooks
+	RequestAttributesFns    []func(r *http.Request) []attribute.KeyValue
+	AttributeKeys           *AttributeKeys
+	AttributeNamespace      string
+	AttributeValueLimit     int
+	TruncatedAttributes     bool
+	BodyDigests             bool
+	PayloadStore            PayloadStore
+	PayloadInlineLimit      int
+	BodyCaptureRatio        *float64
+	CaptureRateLimit        float64
+	CaptureConfig           *CaptureConfig
+	MaxBodySize             int
+	Clock                   func() time.Time
+	PanicRecovery           bool
+}
+
+// newConfig returns the configuration set by the environment variables,
+// then opts.
+func newConfig(opts []Option) config {
+	cfg := config{}
+	applyEnvironment(&cfg)
+	for _, opt := range opts {
+		opt.apply(&cfg)
+	}
+	return cfg
 }
 
 // Option specifies instrumentation configuration options.
@@ -51,6 +160,16 @@ func WithTracerProvider(provider oteltrace.TracerProvider) Option {
 // execution. For some people, this behavior is not desirable since they want
 // to override the span name on underlying handler. By setting this option, it
 // is possible for them to override the span name.
+//
+// The patterns of the routers mounted on the routes are joined, e.g.
+// /api/users/{id} for /users/{id} mounted on /api. When the middleware is
+// used by a mounted router, routes is that router, and the patterns of the
+// routers it is mounted on are prepended.
+//
+// Without this option, the routes of the chi router using the middleware,
+// e.g. through its Use method, are detected from the requests. The routes
+// of a mounted router can not be detected, the request being routed to it
+// by its parent router, and must be given to this option.
 func WithChiRoutes(routes chi.Routes) Option {
 	return optionFunc(func(cfg *config) {
 		cfg.ChiRoutes = routes
@@ -74,9 +193,1028 @@ func WithRequestMethodInSpanName(isActive bool) Option {
 
 // WithFilter is used for filtering request that should not be traced.
 // This is useful for filtering health check request, etc.
-// A Filter must return true if the request should be traced.
+// A Filter must return true if the request should be traced. When several
+// filters are set, a request is traced only if all of them return true.
 func WithFilter(filter func(r *http.Request) bool) Option {
 	return optionFunc(func(cfg *config) {
-		cfg.Filter = filter
+		cfg.Filters = append(cfg.Filters, filter)
+	})
+}
+
+// WithPriorityPropagation enables resolution of a request priority hint.
+// The priority is read from the "priority" baggage member or, when the
+// baggage does not carry one, from the given header. The resolved value is
+// recorded on the span, made available through Priority, and added to the
+// baggage so it is propagated to downstream services. Pass an empty header
+// to only honor the baggage member.
+func WithPriorityPropagation(header string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.PriorityPropagation = true
+		cfg.PriorityHeader = header
+	})
+}
+
+// WithBodyAttributeExtractors sets up extraction of individual fields of
+// JSON request and response bodies into span attributes. The map keys are
+// attribute names and the values JSONPath expressions, for example
+// "app.order_id" mapped to "$.order.id". The request body is searched first,
+// then the response body. Bodies are only inspected when payload capture is
+// enabled.
+func WithBodyAttributeExtractors(extractors map[string]string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.BodyAttributeExtractors = extractors
+	})
+}
+
+// WithCacheControlPolicies sets the Cache-Control response header expected
+// for route patterns, e.g. "/products/{id}" mapped to "public, max-age=300".
+// Spans of routes with a policy get the http.cache.policy_violation
+// attribute, which is true when the actual header differs from the
+// expected one.
+func WithCacheControlPolicies(policies map[string]string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.CacheControlPolicies = policies
+	})
+}
+
+// WithGraphQLRoutes marks route patterns serving GraphQL over HTTP. For POST
+// requests on these routes the request body is parsed to find the executed
+// operation, which is used as span name (e.g. "query GetUser") and recorded
+// in the graphql.operation.type and graphql.operation.name attributes. The
+// body is parsed even when payload capture is disabled, but it is only
+// exported when payload capture is enabled.
+func WithGraphQLRoutes(patterns ...string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.GraphQLRoutes = append(cfg.GraphQLRoutes, patterns...)
+	})
+}
+
+// WithXMLCapture enables analysis of XML request and response bodies (e.g.
+// SOAP payloads). Each XML body gets well-formedness, root element and
+// element count attributes. The extractors map attribute names to element
+// paths whose text content is recorded, either absolute ("/Envelope/Body/Id")
+// or descendant ("//Id"). The text content of the elements named in
+// redactElements, and of their children, is replaced by "[REDACTED]" in the
+// exported bodies and extracted values. Bodies are only inspected when
+// payload capture is enabled.
+func WithXMLCapture(extractors map[string]string, redactElements ...string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.XMLCapture = true
+		cfg.XMLExtractors = extractors
+		cfg.XMLRedactedElements = redactElements
+	})
+}
+
+// WithEnabledFunc sets a function, e.g. backed by a feature flag client,
+// that is checked on every request to decide whether the middleware is
+// active. When it returns false the request is passed to the next handler
+// untouched. The check fails open: if the function panics, the request is
+// traced as if it returned true.
+func WithEnabledFunc(fn func() bool) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.EnabledFunc = fn
+	})
+}
+
+// WithRouteHeader sets a request header, set by an upstream gateway (e.g.
+// "X-Route-Name"), whose value is used as http.route and span name when chi
+// routing yields no route pattern or only a catch-all one such as "/*".
+// This keeps span names low-cardinality for pass-through services. Only use
+// it with headers set by a trusted gateway.
+func WithRouteHeader(header string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.RouteHeader = header
+	})
+}
+
+// WithHeaderAttributes records each request header as an individual
+// http.request.header.<name> attribute, as defined by the semantic
+// conventions, instead of a single JSON encoded http.request.headers
+// attribute. Header names are lowercased.
+func WithHeaderAttributes() Option {
+	return optionFunc(func(cfg *config) {
+		cfg.HeaderAttributes = true
+	})
+}
+
+// WithCookieCapture records the names of the request cookies in the
+// http.request.cookie.names attribute, and the values of the cookies named
+// in allowlist as http.request.cookie.<name> attributes. Cookie and
+// Set-Cookie header values are redacted from the captured headers by
+// default, see DefaultRedactedHeaders.
+func WithCookieCapture(allowlist ...string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.CookieCapture = true
+		cfg.CookieAllowlist = allowlist
+	})
+}
+
+// WithRedactedHeaders sets the headers whose values are replaced by
+// "[REDACTED]" in the captured headers, replacing DefaultRedactedHeaders.
+// To extend the default set, pass
+// append(otelchi.DefaultRedactedHeaders(), "X-Custom-Token"). Without
+// headers, the default set is kept: use WithoutHeaderRedaction to disable
+// the redaction.
+func WithRedactedHeaders(headers ...string) Option {
+	return optionFunc(func(cfg *config) {
+		if len(headers) == 0 {
+			cfg.RedactedHeaders = nil
+			return
+		}
+		cfg.RedactedHeaders = append([]string{}, headers...)
+	})
+}
+
+// WithoutHeaderRedaction disables the redaction of sensitive headers, so
+// credentials and cookies are captured verbatim.
+func WithoutHeaderRedaction() Option {
+	return optionFunc(func(cfg *config) {
+		cfg.RedactedHeaders = []string{}
+	})
+}
+
+// WithEventLimits caps the number of events, including recorded errors,
+// that handlers add to the server span. defaultLimit applies to all routes
+// and routeLimits overrides it for specific route patterns; a negative
+// limit disables the cap. When a request produces more events than its
+// limit, a uniform sample of them is kept and the number of dropped events
+// is recorded in the otelchi.span.events.dropped attribute. Events are
+// added to the span, with their original timestamps, right before it ends.
+func WithEventLimits(defaultLimit int, routeLimits map[string]int) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.EventLimits = true
+		cfg.DefaultEventLimit = defaultLimit
+		cfg.RouteEventLimits = routeLimits
+	})
+}
+
+// WithPathNormalizer sets a function used to name spans after the request
+// path when no route pattern can be resolved, or only a catch-all one, as
+// with proxy handlers. The function should mask the variable parts of the
+// path to keep span names low-cardinality, NormalizePath being a good
+// default.
+func WithPathNormalizer(normalizer func(path string) string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.PathNormalizer = normalizer
+	})
+}
+
+// WithMinimalAttributes reduces the attributes set by the middleware to the
+// request method, route and response status code, skipping the network,
+// end user and server attributes as well as header and payload capture.
+// It is meant for users who want the smallest possible spans and the
+// lowest overhead. Attributes of explicitly enabled options are still
+// recorded.
+func WithMinimalAttributes() Option {
+	return optionFunc(func(cfg *config) {
+		cfg.MinimalAttributes = true
+	})
+}
+
+// WithHTTP2Attributes records, for HTTP/2 and later requests, the request
+// priority signalled through the RFC 9218 Priority header in the
+// http2.priority.urgency and http2.priority.incremental attributes. Since
+// net/http does not expose the stream a request is served on, the
+// http2.stream.id attribute is only recorded when streamID is set and
+// returns true, e.g. for servers that track streams themselves.
+func WithHTTP2Attributes(streamID func(r *http.Request) (uint32, bool)) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.HTTP2Attributes = true
+		cfg.HTTP2StreamID = streamID
+	})
+}
+
+// WithJWTEnduser sets the enduser.id, enduser.role and enduser.scope
+// attributes from the standard claims of the Bearer token of the request.
+// The token signature is not verified unless a verifier is configured with
+// WithJWTVerifier, so the attributes must not be trusted for anything else
+// than observability.
+func WithJWTEnduser() Option {
+	return optionFunc(func(cfg *config) {
+		cfg.JWTEnduser = true
+	})
+}
+
+// WithJWTVerifier sets the verifier used to validate Bearer tokens and get
+// their claims, and enables WithJWTEnduser. Requests whose token is
+// rejected by the verifier get no enduser attributes from it.
+func WithJWTVerifier(verifier JWTVerifier) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.JWTEnduser = true
+		cfg.JWTVerifier = verifier
+	})
+}
+
+// WithRouteSamplerHint sets the routes used by the application, like
+// WithChiRoutes, and guarantees that the route pattern is resolved before
+// the span starts, so that it is present in the http.route attribute seen by
+// samplers such as RouteSampler. It takes precedence over
+// WithLazyRouteNaming.
+func WithRouteSamplerHint(routes chi.Routes) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.ChiRoutes = routes
+		cfg.RouteSamplerHint = true
+	})
+}
+
+// WithTrustedProxies sets the proxies, as CIDR ranges or IP addresses, that
+// are trusted to report the client address in the RFC 7239 Forwarded
+// header or, when it is absent, the X-Forwarded-For header. The client
+// address recorded in the client.address and http.client_ip attributes is
+// then the closest address that is not a trusted proxy, rather than the
+// address of the load balancer or a spoofable first X-Forwarded-For entry.
+// The scheme and host reported for the client, by the Forwarded proto and
+// host parameters or X-Forwarded-Proto and X-Forwarded-Host, override the
+// http.scheme and http.host attributes.
+func WithTrustedProxies(cidrs ...string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.TrustedProxies = append(cfg.TrustedProxies, cidrs...)
+	})
+}
+
+// WithRealIP records the client address when chi's middleware.RealIP is
+// used. If RealIP runs before this middleware, the RemoteAddr it rewrote
+// is recorded as client.address, with "real_ip" as client.address.source.
+// If RealIP runs after this middleware, its rewrite is not visible yet, so
+// the True-Client-IP, X-Real-IP and X-Forwarded-For headers are parsed the
+// way RealIP does, with "header" as source, unless WithTrustedProxies is
+// also used, in which case the trusted proxies resolution applies. Either
+// way the recorded address is the same regardless of the middleware order.
+func WithRealIP() Option {
+	return optionFunc(func(cfg *config) {
+		cfg.RealIP = true
+	})
+}
+
+// WithCaptureBuffer keeps a summary of every traced request, including the
+// captured headers and payloads, in the given buffer. Mount the buffer on
+// an internal route to inspect recent traffic without a tracing backend.
+func WithCaptureBuffer(buffer *CaptureBuffer) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.CaptureBuffer = buffer
+	})
+}
+
+// WithOperationNames sets, for RPC-style routes serving several operations
+// on a single endpoint, the functions naming the operation of each request,
+// so that their spans are not all named after the route. Keys are route
+// patterns, optionally prefixed by a method, e.g. "POST /internal/rpc". The
+// name returned by the function, if any, is used as span name. The request
+// body is buffered for these routes even when payload capture is disabled,
+// but it is only exported when payload capture is enabled.
+func WithOperationNames(routes map[string]OperationNameFunc) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.OperationNames = routes
+	})
+}
+
+// WithHealthCheckFilter filters out health check and probe requests: the
+// requests on the given paths, defaulting to DefaultHealthCheckPaths when
+// none is given, and the requests sent by known health checkers such as
+// Kubernetes probes and AWS load balancers, whatever their path.
+func WithHealthCheckFilter(paths ...string) Option {
+	if len(paths) == 0 {
+		paths = DefaultHealthCheckPaths()
+	}
+	return WithFilter(healthCheckFilter(paths))
+}
+
+// WithScrubFailureQuarantine sends raw copies of the captured bodies that
+// could not be scrubbed, e.g. malformed XML documents with redacted
+// elements, to the given sink, to help debugging the scrubbing rules. Such
+// bodies are always exported as a placeholder, along with a scrub_error
+// attribute; only one in every sampleEvery of them is quarantined, and marked
+// with a quarantined attribute.
+func WithScrubFailureQuarantine(sink QuarantineSink, sampleEvery int) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.QuarantineSink = sink
+		cfg.QuarantineSampleEvery = sampleEvery
+	})
+}
+
+// WithSkipPreflight filters out CORS preflight requests, i.e. OPTIONS
+// requests carrying an Access-Control-Request-Method header, which browsers
+// send before cross-origin requests and which carry no useful information.
+func WithSkipPreflight() Option {
+	return WithFilter(func(r *http.Request) bool {
+		return !isPreflight(r)
+	})
+}
+
+// WithHTMLTraceContext injects a <meta name="traceparent"> tag, holding the
+// context of the server span, right after the opening head tag of text/html
+// responses, so that browser RUM agents can join page loads to the trace of
+// the backend that served them. The Content-Length header of these
+// responses is dropped, as the injection changes their length.
+func WithHTMLTraceContext() Option {
+	return optionFunc(func(cfg *config) {
+		cfg.HTMLTraceContext = true
+	})
+}
+
+// WithSecondaryTracerProvider mirrors the server spans to a second tracer
+// provider: each request gets a span from both providers, with the same
+// name, attributes, events and status, without collector-side routing.
+// Spans of the handler are only created by the primary provider. When
+// fullCapture is true and payload capture is disabled, e.g. by
+// WithMinimalAttributes, headers and payloads are still captured for the
+// secondary span, e.g. to send full-capture spans to an internal collector
+// while a vendor provider only gets the metadata.
+func WithSecondaryTracerProvider(provider oteltrace.TracerProvider, fullCapture bool) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.SecondaryTracerProvider = provider
+		cfg.SecondaryFullCapture = fullCapture
+	})
+}
+
+// WithStaticAssetFilter filters out requests for static content, whose path
+// matches one of the given patterns, defaulting to
+// DefaultStaticAssetPatterns when none is given. Patterns like "*.js" match
+// the path extension, patterns ending with a slash like "/static/" match
+// the path prefix and other patterns match the path exactly.
+func WithStaticAssetFilter(patterns ...string) Option {
+	if len(patterns) == 0 {
+		patterns = DefaultStaticAssetPatterns()
+	}
+	return WithFilter(staticAssetFilter(patterns))
+}
+
+// WithZeroStatusPolicy sets how the status of responses for which the
+// handler never called Write or WriteHeader is recorded. By default it is
+// recorded as 0, see ZeroStatusPolicy for the alternatives.
+func WithZeroStatusPolicy(policy ZeroStatusPolicy) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.ZeroStatusPolicy = policy
+	})
+}
+
+// WithMetadataOnly sets whether only the request metadata is captured,
+// leaving out headers and payloads. It takes precedence over the
+// HS_METADATA_ONLY environment variable, and is typically used with
+// WithRouteOptions to capture payloads on some routes only.
+func WithMetadataOnly(metadataOnly bool) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.MetadataOnly = &metadataOnly
+	})
+}
+
+// WithRouteOptions overrides the configuration for the requests matching
+// the given route pattern, which uses the chi routing syntax and is matched
+// against the full request path before the handler is executed. The
+// options are applied on top of the other options of the middleware, e.g.
+// WithRouteOptions("/login", WithMetadataOnly(true)) disables payload
+// capture on the login route, WithRouteOptions("/api/orders/*",
+// WithMetadataOnly(false)) enables it for the order API, and
+// WithRouteOptions("/internal/*", WithFilter(func(*http.Request) bool {
+// return false })) disables tracing of internal routes. When several
+// patterns match, the most specific one wins, following chi's routing
+// rules. The routes share the stateful components of the middleware, i.e.
+// the span name limit, the capture rate limit, the route cache, the state
+// store and the metric instruments, whatever their options.
+func WithRouteOptions(pattern string, opts ...Option) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.RouteOptions = append(cfg.RouteOptions, routeOptions{pattern: pattern, opts: opts})
+	})
+}
+
+// WithMultipartFileEvents adds, for multipart uploads parsed by the handler,
+// an http.request.multipart.file span event per uploaded file, with its
+// field name, file name, declared content type, size and SHA-256 digest.
+// The digest is computed by streaming the parsed file, whose bytes are
+// never recorded. The events are only added when payload capture is
+// enabled.
+func WithMultipartFileEvents() Option {
+	return optionFunc(func(cfg *config) {
+		cfg.MultipartFileEvents = true
+	})
+}
+
+// WithRouteSampleRatios sets the fraction of requests traced for route
+// patterns, e.g. 1 for "/checkout" and 0.01 for "/search", decided by the
+// middleware before the span starts, since samplers of the tracer provider
+// can't see the route. The patterns use the chi routing syntax and are
+// matched against the full request path, unless the route is resolved
+// beforehand with WithChiRoutes. Requests that are not sampled are served
+// without span, with a span context that is not sampled so that the handler
+// and the downstream services follow the decision. Requests of routes
+// without ratio are left to the sampler of the tracer provider.
+func WithRouteSampleRatios(ratios map[string]float64) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.RouteSampleRatios = ratios
+	})
+}
+
+// WithDebugHeader makes requests carrying the given header debug requests,
+// which are captured with full fidelity, headers and payloads included,
+// regardless of the payload capture settings and of WithRouteSampleRatios,
+// and whose spans get the otelchi.debug and otelchi.debug.token attributes.
+// When secret is not empty, the header value must be signed with it, see
+// SignDebugToken, so that only support engineers can trigger debug traces.
+// The span is only guaranteed to be sampled when the sampler of the tracer
+// provider is wrapped with DebugSampler.
+func WithDebugHeader(header string, secret []byte) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.DebugHeader = header
+		cfg.DebugSecret = secret
+	})
+}
+
+// WithContentLengthCheck compares the bytes read from request bodies with
+// their Content-Length, and records mismatches in the
+// http.request.content_length.mismatch attribute, along with the bytes read
+// in http.request.body.read_bytes: "client_sent_less" when the body ended
+// early, "client_sent_more" when it went past its length, and
+// "handler_stopped_early" when the handler did not read it all. Bodies
+// ending early although the request asked for a 100 Continue, typically
+// uploads the client abandoned to retry them, also get the
+// http.request.body.short_after_continue attribute.
+func WithContentLengthCheck() Option {
+	return optionFunc(func(cfg *config) {
+		cfg.ContentLengthCheck = true
+	})
+}
+
+// WithStateStore sets the store keeping state across requests for the
+// features needing it. Without it, these features use a MemoryStateStore
+// private to the middleware, which is not shared by the replicas of a
+// service.
+func WithStateStore(store StateStore) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.StateStore = store
+	})
+}
+
+// WithIdempotencyDetection detects retries of requests carrying an
+// idempotency key in the given header, defaulting to "Idempotency-Key".
+// Requests with the same method, path and key within a day are counted in
+// the http.request.idempotency.attempt attribute, and the retries are
+// marked with http.request.idempotency.duplicate. Attempts are counted in
+// the state store, see WithStateStore.
+func WithIdempotencyDetection(header string) Option {
+	if header == "" {
+		header = "Idempotency-Key"
+	}
+	return optionFunc(func(cfg *config) {
+		cfg.IdempotencyHeader = header
+	})
+}
+
+// WithSpanStartOptions adds options applied to every span started by the
+// middleware, e.g. oteltrace.WithAttributes for a static team attribute or
+// oteltrace.WithLinks. They are applied after the options of the
+// middleware, so they can override the span kind or add attributes.
+func WithSpanStartOptions(opts ...oteltrace.SpanStartOption) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.SpanStartOptions = append(cfg.SpanStartOptions, opts...)
+	})
+}
+
+// WithAsyncEnricher runs enricher after the response is complete, in a new
+// goroutine, and adds the attributes it returns to the span, which ends
+// once the enricher returns. This enables enrichments needing I/O, like
+// feature flag lookups, without adding latency to the response. The span
+// still ends at the time the response was complete. When the enricher
+// does not return before the deadline, 5 seconds when 0, its attributes
+// are dropped and the span gets the otelchi.enrichment.timeout attribute.
+// The enricher must honor the snapshot Context, canceled at the deadline:
+// at most 128 enrichments run at once, further ones being dropped, their
+// spans ending right away with the otelchi.enrichment.dropped attribute.
+func WithAsyncEnricher(enricher AsyncEnricher, deadline time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.AsyncEnricher = enricher
+		cfg.AsyncEnrichmentDeadline = deadline
+	})
+}
+
+// WithSpanKind sets the kind of the spans started by the middleware, which
+// defaults to oteltrace.SpanKindServer. Use oteltrace.SpanKindInternal for
+// a middleware mounted on a sub-router behind an already instrumented
+// edge, so that a request does not get two server spans.
+func WithSpanKind(kind oteltrace.SpanKind) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.SpanKind = kind
+	})
+}
+
+// WithWebSocketPolicy sets when the span of WebSocket upgrade requests
+// ends, see WebSocketPolicy. Whatever the policy, the bodies of these
+// requests are never buffered, and their spans get the
+// network.protocol.name attribute set to "websocket".
+func WithWebSocketPolicy(policy WebSocketPolicy) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.WebSocketPolicy = policy
+	})
+}
+
+// WithFilteredPropagation keeps propagating the trace context of requests
+// filtered out by WithFilter and the other filtering options: no span is
+// started for them, but the incoming context is extracted, so that the
+// spans of the handler and of the downstream services still join the trace
+// of the caller instead of starting new ones.
+func WithFilteredPropagation() Option {
+	return optionFunc(func(cfg *config) {
+		cfg.FilteredPropagation = true
+	})
+}
+
+// WithStreamChunkEvents adds http.response.chunk span events to streamed
+// responses, i.e. text/event-stream responses and responses whose handler
+// calls Flush, with the bytes written since the previous event and in
+// total. An event is added on Flush, unless the previous one was added
+// less than interval ago. Streamed responses are never buffered, and their
+// spans get the http.response.streaming attribute.
+func WithStreamChunkEvents(interval time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.StreamChunkEvents = true
+		cfg.StreamChunkInterval = interval
+	})
+}
+
+// WithHeaderAllowlist restricts the captured request headers and response
+// trailers to the given ones. Allowlisted headers are still subject to
+// redaction, see WithRedactedHeaders.
+func WithHeaderAllowlist(headers ...string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.HeaderAllowlist = append(cfg.HeaderAllowlist, headers...)
+	})
+}
+
+// WithBodyDedup stops exporting captured bodies of 1 KiB or more that were
+// already exported within the given window. The first occurrence of a body
+// is recorded along with its SHA-256 hash in the http.request.body.sha256
+// or http.response.body.sha256 attribute, and the following ones only get
+// that hash in http.request.body.ref or http.response.body.ref. Bodies seen
+// are tracked in the state store, see WithStateStore.
+func WithBodyDedup(window time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.BodyDedup = true
+		cfg.BodyDedupWindow = window
+	})
+}
+
+// WithServerTiming adds a Server-Timing header carrying the context of the
+// server span, in the form traceparent;desc="00-<trace-id>-<span-id>-01",
+// so that browser RUM agents, which can read Server-Timing but not the
+// traceresponse header, can link their measurements to the backend span.
+// The header is set before the handler runs, and values set by the
+// handler are kept.
+func WithServerTiming() Option {
+	return optionFunc(func(cfg *config) {
+		cfg.ServerTiming = true
+	})
+}
+
+// WithBaggageLimits bounds the incoming baggage to maxMembers members and
+// maxBytes bytes, once serialized, before it reaches the handlers and is
+// propagated downstream. Members past the limits are dropped, the last
+// sent first, and the span records how many members and bytes were
+// dropped, and their keys. A zero limit disables the corresponding check.
+func WithBaggageLimits(maxMembers, maxBytes int) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.BaggageLimits = &baggageLimits{maxMembers: maxMembers, maxBytes: maxBytes}
+	})
+}
+
+// WithMeterProvider specifies a meter provider to use for creating the
+// instruments of the middleware. If none is specified, the global provider
+// is used.
+//
+// The middleware records the number of requests being served in the
+// http.server.active_requests up-down counter, by method and route. The
+// route of a request is only known when the middleware is configured with
+// WithChiRoutes, as the counter is incremented before the request is
+// routed. The duration of the requests served is recorded in the
+// http.server.duration histogram, by method, route and status code; its
+// bucket boundaries are set with DurationView.
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.MeterProvider = provider
+	})
+}
+
+// WithPrometheusMetricNames also records the metrics of the middleware
+// under Prometheus-conventional names, for teams migrating from promhttp
+// to keep their dashboards and alerts working during the transition:
+// http_server_request_duration_seconds, a histogram in seconds, and
+// http_server_active_requests. Their labels are method, route and, for the
+// histogram, code. The metrics under OpenTelemetry names keep being
+// recorded.
+func WithPrometheusMetricNames() Option {
+	return optionFunc(func(cfg *config) {
+		cfg.PrometheusMetricNames = true
+	})
+}
+
+// WithPayloadLogs emits the captured request and response bodies as log
+// records correlated with the span, through emitter, instead of recording
+// them in the span attributes, which most backends truncate. The OTel Logs
+// API is not available at the OTel version this module depends on, so
+// emitter bridges the records to the logging pipeline of the application.
+// Bodies are emitted under the same conditions they would be recorded in
+// the span, and WithBodyDedup does not apply to them.
+func WithPayloadLogs(emitter LogEmitter) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.LogEmitter = emitter
+	})
+}
+
+// WithBodiesAsEvents records the captured request and response bodies in
+// http.request.body and http.response.body span events instead of span
+// attributes. Events have their own limits in many backends, and their
+// timestamps tell when the request body was fully read and when the
+// response body was last written to.
+func WithBodiesAsEvents() Option {
+	return optionFunc(func(cfg *config) {
+		cfg.BodiesAsEvents = true
+	})
+}
+
+// WithCaptureOnStatus only attaches the captured payloads, headers and
+// bodies, to the spans of requests whose response status code is at least
+// min, e.g. 400 for failed requests only. The payloads are buffered while
+// the request is served and dropped once the status is known to be lower.
+// A response never written counts as a 200. Debug requests, see
+// WithDebugHeader, are always captured. It is a shorthand for a capture
+// predicate, see WithCapturePredicate.
+func WithCaptureOnStatus(min int) Option {
+	return WithCapturePredicate(statusCapturePredicate(min))
+}
+
+// WithCapturePredicate defers the decision to attach the captured
+// payloads, headers and bodies, to a span until the request is served:
+// they are buffered meanwhile, and only attached if predicate accepts the
+// outcome of the request, e.g. its duration or the status of the span.
+// This gives tail-sampling-like economics to payload capture. Use this
+// option multiple times to register multiple predicates, all of which must
+// accept the outcome. Debug requests, see WithDebugHeader, are always
+// captured.
+func WithCapturePredicate(predicate CapturePredicate) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.CapturePredicates = append(cfg.CapturePredicates, predicate)
+	})
+}
+
+// WithSlowRequestThreshold adds a slow_request span event, carrying the
+// route, the elapsed time and the threshold, when the handler takes longer
+// than threshold to serve a request. Slow requests are also counted in the
+// http.server.slow_requests counter, by method and route, whether or not
+// their span is sampled, to alert on slowness.
+func WithSlowRequestThreshold(threshold time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.SlowRequestThreshold = threshold
+	})
+}
+
+// WithRouteMatchTiming records how long matching the request against the
+// routes given to WithChiRoutes took before the span started, in the
+// http.route.match.duration_ms attribute, along with whether a route
+// matched in http.route.matched. It is meant to reveal the matching
+// overhead of routers with many or regular expression patterns. Nothing is
+// recorded without WithChiRoutes.
+func WithRouteMatchTiming() Option {
+	return optionFunc(func(cfg *config) {
+		cfg.RouteMatchTiming = true
+	})
+}
+
+// WithLazyRouteNaming skips matching the request against the routes given
+// to WithChiRoutes, or detected from the request, before the span starts,
+// and only names the span once the request is routed. This saves one
+// route match per request, at the cost of spans starting without a name
+// or http.route attribute, which tail samplers and handlers overriding the
+// span name may rely on. WithRouteSamplerHint still resolves the route
+// beforehand.
+func WithLazyRouteNaming() Option {
+	return optionFunc(func(cfg *config) {
+		cfg.LazyRouteNaming = true
+	})
+}
+
+// WithRouteCache caches the route patterns matched against the routes given
+// to WithChiRoutes for up to size method and path pairs, so the most
+// requested paths skip the route match before the span starts. The least
+// recently requested paths are evicted first. As the cache assumes the
+// routes do not change once requests are served, it must not be used with
+// routers registering routes dynamically. Nothing is cached without
+// WithChiRoutes.
+func WithRouteCache(size int) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.RouteCacheSize = size
+	})
+}
+
+// WithServerNameFn sets the function naming the server handling each
+// request, in the http.server_name attribute, for middlewares serving
+// multiple virtual hosts. HostServerName names them after the Host header.
+// The name given to Middleware is used when fn returns an empty string.
+func WithServerNameFn(fn func(r *http.Request) string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.ServerNameFn = fn
+	})
+}
+
+// WithUnmatchedSpanNames sets the names of the spans of the requests
+// matching no route, which are prefixed by the request method. By default,
+// they are "route not found", e.g. "GET route not found", and "method not
+// allowed" for the requests answered with 405. The span of an unmatched
+// request has no http.route attribute; its path is recorded in url.path
+// instead. An empty name leaves the spans unnamed.
+func WithUnmatchedSpanNames(notFound, methodNotAllowed string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.UnmatchedSpanNames = &unmatchedSpanNames{
+			notFound:         notFound,
+			methodNotAllowed: methodNotAllowed,
+		}
+	})
+}
+
+// WithSpanNameLimit bounds the number of distinct span names given by the
+// middleware to limit, guarding tracing backends against the unbounded
+// names of misconfigured catch-all routes. Past the limit, new names are
+// collapsed into "<other>", prefixed by the request method, e.g.
+// "GET <other>", and counted in the otelchi.span_names.collapsed counter;
+// the names seen before are kept. The route patterns spans start with
+// under WithChiRoutes, and the names set by the handlers themselves, are
+// not guarded.
+func WithSpanNameLimit(limit int) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.SpanNameLimit = limit
+	})
+}
+
+// WithPropagatorAutodetection extracts the trace context of the requests
+// from the first of the W3C traceparent, B3 single or multiple headers,
+// and Jaeger uber-trace-id formats they carry, for services called by
+// clients propagating different formats. The format used is recorded in
+// the otelchi.propagation.format attribute. The configured propagators are
+// still used, e.g. for baggage, though the trace context of a detected
+// format takes precedence over theirs.
+func WithPropagatorAutodetection() Option {
+	return optionFunc(func(cfg *config) {
+		cfg.PropagatorAutodetection = true
+	})
+}
+
+// WithQueryTraceContext extracts the W3C trace context of the requests
+// from the traceparent and tracestate query parameters, for clients unable
+// to set headers, like EventSource and WebSocket clients in browsers. The
+// trace context of the headers takes precedence. The parameters are
+// stripped from the http.target attribute.
+func WithQueryTraceContext(traceparent, tracestate string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.QueryTraceContext = &queryTraceContext{traceparent: traceparent, tracestate: tracestate}
+	})
+}
+
+// WithLinkFromHeader links the spans of the requests carrying header, such
+// as the ID of a batch job or the trace context of a message replayed over
+// HTTP, to the span context parser returns for each of its values. Invalid
+// span contexts are ignored. The links carry the otelchi.link.header
+// attribute naming header. It can be given once per header.
+func WithLinkFromHeader(header string, parser func(value string) oteltrace.SpanContext) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.HeaderLinks = append(cfg.HeaderLinks, headerLink{header: header, parser: parser})
+	})
+}
+
+// WithSyntheticTrafficDetection sets the user_agent.synthetic.type
+// attribute of the requests sent by well-known synthetic monitors and
+// probes, like Pingdom, UptimeRobot or kube-probe, to "test", and by
+// crawlers, like Googlebot, to "bot", so that dashboards can exclude them.
+// The detection is based on the User-Agent header.
+func WithSyntheticTrafficDetection() Option {
+	return optionFunc(func(cfg *config) {
+		cfg.SyntheticDetection = true
+	})
+}
+
+// WithSyntheticTrafficFilter filters out the requests detected as synthetic
+// traffic by WithSyntheticTrafficDetection, whether or not the latter is
+// set.
+func WithSyntheticTrafficFilter() Option {
+	return WithFilter(func(r *http.Request) bool {
+		return syntheticType(r.UserAgent()) == ""
+	})
+}
+
+// WithUserAgentParser parses the User-Agent header of the requests with
+// parser, setting the user_agent.name, user_agent.version, os.name and
+// os.version attributes, besides the raw http.user_agent attribute. A nil
+// parser uses DefaultUserAgentParser.
+func WithUserAgentParser(parser UserAgentParser) Option {
+	return optionFunc(func(cfg *config) {
+		if parser == nil {
+			parser = DefaultUserAgentParser()
+		}
+		cfg.UserAgentParser = parser
+	})
+}
+
+// WithRequestIDCorrelation records the request ID set by the chi
+// middleware.RequestID middleware, installed before this middleware, in the
+// http.request.id attribute. With generate, the requests without request
+// ID are given the trace ID as request ID, in their context, where
+// middleware.GetReqID finds it, and in the X-Request-Id response header.
+func WithRequestIDCorrelation(generate bool) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.RequestID = &requestIDCorrelation{generate: generate}
+	})
+}
+
+// WithSpanStatusMapper sets the function mapping the status codes of the
+// responses to the status and description of their spans, instead of the
+// semantic conventions, which mark the 4xx and 5xx responses as errors. It
+// can e.g. leave the expected 401 and 404 responses unset, or mark the 499
+// responses of the requests canceled by clients as errors. The mapper is
+// not used for aborted and timed out requests, whose spans are always
+// marked as errors.
+func WithSpanStatusMapper(mapper func(status int) (codes.Code, string)) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.SpanStatusMapper = mapper
+	})
+}
+
+// WithErrorEvents records the errors reported by the handlers with
+// RecordError, and the panics of the handlers, as exception events on the
+// server span, carrying their type and message. Whether or not it is set,
+// the spans of the requests answered with a 5xx status have an error.type
+// attribute set to the status code, and the spans of the requests whose
+// handler panicked have it set to the type of the panic value.
+func WithErrorEvents() Option {
+	return optionFunc(func(cfg *config) {
+		cfg.ErrorEvents = true
+	})
+}
+
+// WithStackTraces captures the stack traces of the errors reported with
+// RecordError, where it is called, and of the panics of the handlers, into
+// the exception.stacktrace attribute of their exception events, enabling
+// WithErrorEvents. The stack traces have at most maxDepth frames, 32 if it
+// is not positive, and skip the frames of the functions starting with one
+// of skippedPrefixes, DefaultStackTraceSkippedPrefixes if none is given,
+// so that they focus on the code of the application.
+func WithStackTraces(maxDepth int, skippedPrefixes ...string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.StackTraces = true
+		cfg.StackTraceDepth = maxDepth
+		cfg.StackTraceSkips = skippedPrefixes
+	})
+}
+
+// WithSpanHooks sets callbacks called with the server span of each traced
+// request: onStart right before the handler is called, with the request
+// carrying the span in its context, and onEnd once the request is served
+// and recorded on the span, right before the span ends, with the status
+// code of the response and the duration of the request. Either may be nil.
+// When given several times, the hooks are called in order.
+func WithSpanHooks(
+	onStart func(span oteltrace.Span, r *http.Request),
+	onEnd func(span oteltrace.Span, r *http.Request, status int, duration time.Duration),
+) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.SpanHooks = append(cfg.SpanHooks, spanHooks{onStart: onStart, onEnd: onEnd})
+	})
+}
+
+// WithRequestAttributesFn sets a function returning attributes set on the
+// span of each request when it starts, e.g. the tenant, the plan tier of
+// the API key or the shard serving the request, so that they are also
+// seen by samplers. They take precedence over the default attributes of
+// the middleware, such as http.target. When given several times,
+// the functions are called in order.
+func WithRequestAttributesFn(fn func(r *http.Request) []attribute.KeyValue) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.RequestAttributesFns = append(cfg.RequestAttributesFns, fn)
+	})
+}
+
+// WithAttributeKeys renames the attributes recording the captured request
+// and response bodies and request headers, e.g. to align them with the
+// conventions of an organization. The keys left empty keep their default,
+// see DefaultAttributeKeys.
+func WithAttributeKeys(keys AttributeKeys) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.AttributeKeys = &keys
+	})
+}
+
+// WithAttributeNamespace prefixes the keys of the attributes recording the
+// captured request and response bodies and request headers, set by
+// WithAttributeKeys or the default ones, with namespace, e.g. "acme." for
+// acme.http.request.body.
+func WithAttributeNamespace(namespace string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.AttributeNamespace = namespace
+	})
+}
+
+// WithAttributeValueLimit truncates the string values, and the values of
+// string slices, of the attributes set by the middleware to limit bytes,
+// since exporters drop oversized attributes. This applies to all the
+// attributes, independently of the body size limit. A truncated value ends
+// with a "…[truncated N bytes]" suffix, counted in the limit, or, with
+// companion, is recorded along with a <key>.truncated attribute holding the
+// number of bytes truncated.
+func WithAttributeValueLimit(limit int, companion bool) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.AttributeValueLimit = limit
+		cfg.TruncatedAttributes = companion
+	})
+}
+
+// WithBodyDigests records the digest of the captured request and response
+// bodies instead of their content: their SHA-256 hash in the
+// http.request.body.sha256 and http.response.body.sha256 attributes, and
+// their length in http.request.body.length and http.response.body.length.
+// This is enough to detect duplicate submissions, or payloads changing
+// across retries, without recording the payloads. No attribute is extracted
+// from the bodies, and they are neither emitted as logs nor kept in the
+// capture buffer.
+func WithBodyDigests() Option {
+	return optionFunc(func(cfg *config) {
+		cfg.BodyDigests = true
+	})
+}
+
+// WithPayloadStore offloads the captured bodies larger than inlineLimit
+// bytes, which backends would not accept as attributes, to store. The
+// reference returned by the store, e.g. a storage key or URL, is recorded
+// in the http.request.body.location or http.response.body.location
+// attribute in place of the body. Bodies the store fails to persist are
+// recorded inline. The store gets the whole bodies, the maximum body size
+// only limiting the bodies recorded inline. See NewDirPayloadStore to
+// persist payloads to a local directory.
+func WithPayloadStore(store PayloadStore, inlineLimit int) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.PayloadStore = store
+		cfg.PayloadInlineLimit = inlineLimit
+	})
+}
+
+// WithBodyCaptureRatio captures the payloads of the given fraction of the
+// requests only, e.g. 0.05 for 5% of them, chosen at random. All the
+// requests are still traced, and the payloads of debug requests are always
+// captured, see WithDebugHeader.
+func WithBodyCaptureRatio(ratio float64) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.BodyCaptureRatio = &ratio
+	})
+}
+
+// WithBodyCaptureRateLimit captures the payloads of at most perSecond
+// requests per second and per route, allowing bursts of up to a second
+// worth of requests, so that bursts of traffic do not multiply the memory
+// and egress spent on payloads. The requests over the limit are still
+// traced, with the capture.dropped attribute set. The limit applies to each
+// replica, unless a store is set with WithStateStore: the captures are then
+// counted in the store, per route and over windows of a second, longer
+// for limits below one per second, so that
+// the limit holds across the replicas sharing it. The route is the one the
+// request is matched to before it is served: with WithLazyRouteNaming, or
+// for requests matching no route, the requests share the same limit.
+func WithBodyCaptureRateLimit(perSecond float64) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.CaptureRateLimit = perSecond
+	})
+}
+
+// WithCaptureConfig applies the capture settings held by c, which can
+// be changed on live traffic, e.g. to stop capturing payloads without
+// restarting the service. Use CaptureConfig.WatchFile to load the settings
+// from a file and reload them whenever it changes.
+func WithCaptureConfig(c *CaptureConfig) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.CaptureConfig = c
+	})
+}
+
+// WithMaxBodySize records the first size bytes of the captured bodies only,
+// the bodies being recorded entirely by default.
+func WithMaxBodySize(size int) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.MaxBodySize = size
+	})
+}
+
+// WithClock sets the clock timing the requests, time.Now by default. The
+// spans start and end at the times it tells, their events are stamped with
+// it, including those added by the handler, and the durations recorded by
+// the middleware are measured with it, so that tests of the emitted spans
+// are reproducible. The IDs of the spans are generated by the tracer
+// provider, see sdktrace.WithIDGenerator and the otelchitest package.
+func WithClock(clock func() time.Time) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.Clock = clock
+	})
+}
+
+// WithPanicRecovery recovers the panics of the handlers, answering with a
+// 500 Internal Server Error unless the response header was already written.
+// The panic is recorded on the span, along with the status of the response,
+// and the request is counted by the metrics as any other. Without it, the
+// middleware records the panics and lets them through.
+func WithPanicRecovery() Option {
+	return optionFunc(func(cfg *config) {
+		cfg.PanicRecovery = true
 	})
 }
@@ -0,0 +1,56 @@
+package otelchi
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	requestBodyReadKey              = attribute.Key("http.request.body.read_bytes")
+	requestContentLengthMismatchKey = attribute.Key("http.request.content_length.mismatch")
+	requestShortAfterContinueKey    = attribute.Key("http.request.body.short_after_continue")
+)
+
+// Causes of a mismatch between the request Content-Length and the bytes
+// read from the body, recorded in the http.request.content_length.mismatch
+// attribute.
+const (
+	mismatchClientSentLess = "client_sent_less"
+	mismatchClientSentMore = "client_sent_more"
+	mismatchHandlerStopped = "handler_stopped_early"
+)
+
+// contentLengthAttributes compares the bytes read from the request body
+// with its declared length. Nothing is recorded when the length is unknown
+// or when they match. A body ending early although the client was told to
+// send it, with a 100 Continue, is flagged as such: this is how an upload
+// the client abandoned to retry it looks like, otherwise hard to tell from
+// other short bodies.
+func contentLengthAttributes(r *http.Request, bw *bodyWrapper) []attribute.KeyValue {
+	if r.ContentLength <= 0 {
+		return nil
+	}
+	var mismatch string
+	switch {
+	case bw.read > r.ContentLength:
+		mismatch = mismatchClientSentMore
+	case bw.read == r.ContentLength:
+		return nil
+	case errors.Is(bw.err, io.ErrUnexpectedEOF) || bw.err == io.EOF:
+		mismatch = mismatchClientSentLess
+	default:
+		mismatch = mismatchHandlerStopped
+	}
+	attrs := []attribute.KeyValue{
+		requestBodyReadKey.Int64(bw.read),
+		requestContentLengthMismatchKey.String(mismatch),
+	}
+	if mismatch == mismatchClientSentLess && strings.EqualFold(r.Header.Get("Expect"), "100-continue") {
+		attrs = append(attrs, requestShortAfterContinueKey.Bool(true))
+	}
+	return attrs
+}
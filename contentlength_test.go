@@ -0,0 +1,79 @@
+package otelchi
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestContentLengthAttributes(t *testing.T) {
+	testCases := []struct {
+		name          string
+		contentLength int64
+		read          int64
+		err           error
+		expect        string
+		want          []attribute.KeyValue
+	}{
+		{name: "unknown length", contentLength: -1, read: 10},
+		{name: "match", contentLength: 10, read: 10, err: io.EOF},
+		{name: "client sent less", contentLength: 10, read: 4, err: io.ErrUnexpectedEOF, want: []attribute.KeyValue{
+			attribute.Int64("http.request.body.read_bytes", 4),
+			attribute.String("http.request.content_length.mismatch", "client_sent_less"),
+		}},
+		{name: "client sent less after continue", contentLength: 10, read: 4, err: io.EOF, expect: "100-continue", want: []attribute.KeyValue{
+			attribute.Int64("http.request.body.read_bytes", 4),
+			attribute.String("http.request.content_length.mismatch", "client_sent_less"),
+			attribute.Bool("http.request.body.short_after_continue", true),
+		}},
+		{name: "client sent more", contentLength: 10, read: 12, want: []attribute.KeyValue{
+			attribute.Int64("http.request.body.read_bytes", 12),
+			attribute.String("http.request.content_length.mismatch", "client_sent_more"),
+		}},
+		{name: "handler stopped early", contentLength: 10, read: 0, want: []attribute.KeyValue{
+			attribute.Int64("http.request.body.read_bytes", 0),
+			attribute.String("http.request.content_length.mismatch", "handler_stopped_early"),
+		}},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/", strings.NewReader(""))
+			r.ContentLength = testCase.contentLength
+			if testCase.expect != "" {
+				r.Header.Set("Expect", testCase.expect)
+			}
+			bw := &bodyWrapper{read: testCase.read, err: testCase.err}
+			assert.Equal(t, testCase.want, contentLengthAttributes(r, bw))
+		})
+	}
+}
+
+func TestSDKIntegrationWithContentLengthCheck(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar", WithTracerProvider(provider), WithContentLengthCheck()))
+	router.HandleFunc("/upload", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadFull(r.Body, make([]byte, 2))
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/upload", strings.NewReader("hello")))
+
+	require.Len(t, sr.Ended(), 1)
+	assertSpan(t, sr.Ended()[0], "/upload", trace.SpanKindServer,
+		attribute.Int64("http.request.body.read_bytes", 2),
+		attribute.String("http.request.content_length.mismatch", "handler_stopped_early"),
+	)
+}
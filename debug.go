@@ -0,0 +1,90 @@
+package otelchi
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+const (
+	debugKey      = attribute.Key("otelchi.debug")
+	debugTokenKey = attribute.Key("otelchi.debug.token")
+)
+
+// debugHeader recognizes the requests asking for a debug trace.
+type debugHeader struct {
+	header string
+	secret []byte
+}
+
+// token reports whether r asks for a debug trace, and returns the token it
+// carries. Without secret, any value of the header is accepted as token.
+// With a secret, the value must be "<token>.<signature>", where the
+// signature is the hex encoded HMAC-SHA256 of the token keyed by the
+// secret.
+func (d *debugHeader) token(r *http.Request) (string, bool) {
+	value := r.Header.Get(d.header)
+	if value == "" {
+		return "", false
+	}
+	if len(d.secret) == 0 {
+		return value, true
+	}
+	i := strings.LastIndexByte(value, '.')
+	if i < 0 {
+		return "", false
+	}
+	signature, err := hex.DecodeString(value[i+1:])
+	if err != nil {
+		return "", false
+	}
+	mac := hmac.New(sha256.New, d.secret)
+	mac.Write([]byte(value[:i]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return "", false
+	}
+	return value[:i], true
+}
+
+func debugAttributes(token string) []attribute.KeyValue {
+	return []attribute.KeyValue{debugKey.Bool(true), debugTokenKey.String(token)}
+}
+
+// SignDebugToken returns the value of the debug header carrying token, for
+// a middleware configured with WithDebugHeader and the given secret.
+func SignDebugToken(token string, secret []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(token))
+	return token + "." + hex.EncodeToString(mac.Sum(nil))
+}
+
+// DebugSampler returns an sdktrace.Sampler that samples the spans of the
+// debug requests recognized by WithDebugHeader, and delegates the decision
+// for the other spans to the given sampler.
+func DebugSampler(delegate sdktrace.Sampler) sdktrace.Sampler {
+	return debugSampler{delegate: delegate}
+}
+
+type debugSampler struct {
+	delegate sdktrace.Sampler
+}
+
+// ShouldSample implements sdktrace.Sampler.
+func (s debugSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	for _, attr := range p.Attributes {
+		if attr.Key == debugKey && attr.Value.AsBool() {
+			return sdktrace.AlwaysSample().ShouldSample(p)
+		}
+	}
+	return s.delegate.ShouldSample(p)
+}
+
+// Description implements sdktrace.Sampler.
+func (s debugSampler) Description() string {
+	return "DebugSampler{" + s.delegate.Description() + "}"
+}
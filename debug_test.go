@@ -0,0 +1,78 @@
+package otelchi
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestDebugHeaderToken(t *testing.T) {
+	secret := []byte("s3cr3t")
+	d := &debugHeader{header: "X-Debug-Trace", secret: secret}
+
+	testCases := []struct {
+		name      string
+		value     string
+		wantToken string
+		wantOK    bool
+	}{
+		{name: "missing"},
+		{name: "signed", value: SignDebugToken("TICKET-42", secret), wantToken: "TICKET-42", wantOK: true},
+		{name: "unsigned", value: "TICKET-42"},
+		{name: "wrong secret", value: SignDebugToken("TICKET-42", []byte("other"))},
+		{name: "bad signature", value: "TICKET-42.zz"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			if testCase.value != "" {
+				r.Header.Set("X-Debug-Trace", testCase.value)
+			}
+			token, ok := d.token(r)
+			assert.Equal(t, testCase.wantOK, ok)
+			assert.Equal(t, testCase.wantToken, token)
+		})
+	}
+}
+
+func TestSDKIntegrationWithDebugHeader(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSampler(DebugSampler(sdktrace.NeverSample())))
+	provider.RegisterSpanProcessor(sr)
+
+	secret := []byte("s3cr3t")
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar",
+		WithTracerProvider(provider),
+		WithMetadataOnly(true),
+		WithDebugHeader("X-Debug-Trace", secret),
+	))
+	router.HandleFunc("/user/{id}", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(body)
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/user/123", strings.NewReader("hello")))
+	require.Len(t, sr.Ended(), 0)
+
+	r := httptest.NewRequest("POST", "/user/123", strings.NewReader("hello"))
+	r.Header.Set("X-Debug-Trace", SignDebugToken("TICKET-42", secret))
+	router.ServeHTTP(httptest.NewRecorder(), r)
+	require.Len(t, sr.Ended(), 1)
+	assertSpan(t, sr.Ended()[0], "/user/{id}", trace.SpanKindServer,
+		attribute.Bool("otelchi.debug", true),
+		attribute.String("otelchi.debug.token", "TICKET-42"),
+		attribute.String("http.request.body", "hello"),
+		attribute.String("http.response.body", "hello"),
+	)
+}
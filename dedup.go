@@ -0,0 +1,51 @@
+package otelchi
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// bodyDedupMinSize is the size from which bodies are deduplicated; smaller
+// bodies are cheaper to export than to reference.
+const bodyDedupMinSize = 1024
+
+// bodyDedup replaces the bodies already exported within a time window with
+// a reference to their content hash.
+type bodyDedup struct {
+	store  StateStore
+	window time.Duration
+}
+
+// bodyAttributes returns the attributes recording body under key, e.g.
+// "http.response.body": the body itself along with its hash the first time
+// it is seen within the window, and only the <key>.ref hash reference
+// afterwards. The dedup may be nil, in which case the body is always
+// recorded. Store errors are reported to the global OTel error handler and
+// the body is recorded.
+func (d *bodyDedup) bodyAttributes(ctx context.Context, key string, body []byte) []attribute.KeyValue {
+	if len(body) == 0 {
+		return nil
+	}
+	bodyAttr := attribute.String(key, string(body))
+	if d == nil || len(body) < bodyDedupMinSize {
+		return []attribute.KeyValue{bodyAttr}
+	}
+
+	sum := sha256.Sum256(body)
+	hash := hex.EncodeToString(sum[:])
+	seen, err := d.store.Incr(ctx, "otelchi:body:"+hash, d.window)
+	if err != nil {
+		otel.Handle(fmt.Errorf("otelchi: body dedup: %w", err))
+		return []attribute.KeyValue{bodyAttr}
+	}
+	if seen > 1 {
+		return []attribute.KeyValue{attribute.String(key+".ref", hash)}
+	}
+	return []attribute.KeyValue{bodyAttr, attribute.String(key+".sha256", hash)}
+}
@@ -0,0 +1,44 @@
+package otelchi
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestBodyDedup(t *testing.T) {
+	ctx := context.Background()
+	d := &bodyDedup{store: NewMemoryStateStore(10), window: time.Minute}
+
+	large := []byte(strings.Repeat("x", bodyDedupMinSize))
+	sum := sha256.Sum256(large)
+	hash := hex.EncodeToString(sum[:])
+
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String("http.response.body", string(large)),
+		attribute.String("http.response.body.sha256", hash),
+	}, d.bodyAttributes(ctx, "http.response.body", large))
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String("http.response.body.ref", hash),
+	}, d.bodyAttributes(ctx, "http.response.body", large))
+
+	// small bodies are always recorded
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, []attribute.KeyValue{
+			attribute.String("http.response.body", "small"),
+		}, d.bodyAttributes(ctx, "http.response.body", []byte("small")))
+	}
+
+	// without dedup, bodies are always recorded
+	var none *bodyDedup
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String("http.request.body", string(large)),
+	}, none.bodyAttributes(ctx, "http.request.body", large))
+	assert.Nil(t, none.bodyAttributes(ctx, "http.request.body", nil))
+}
@@ -0,0 +1,22 @@
+package otelchi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// bodyDigestAttributes returns the attributes recording the digest of body
+// under key, e.g. "http.response.body": its SHA-256 hash in <key>.sha256
+// and its length in <key>.length, but not its content.
+func bodyDigestAttributes(key string, body []byte) []attribute.KeyValue {
+	if len(body) == 0 {
+		return nil
+	}
+	sum := sha256.Sum256(body)
+	return []attribute.KeyValue{
+		attribute.String(key+".sha256", hex.EncodeToString(sum[:])),
+		attribute.Int(key+".length", len(body)),
+	}
+}
@@ -0,0 +1,56 @@
+package otelchi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSDKIntegrationWithBodyDigests(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar",
+		WithTracerProvider(provider),
+		WithBodyDigests(),
+		WithBodyAttributeExtractors(map[string]string{"user.name": "$.name"}),
+	))
+	router.HandleFunc("/user/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		_, _ = w.Write([]byte("done"))
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("PUT", "/user/123", strings.NewReader(`{"name":"alice"}`)))
+
+	digest := func(body string) string {
+		sum := sha256.Sum256([]byte(body))
+		return hex.EncodeToString(sum[:])
+	}
+	spans := sr.Ended()
+	require.Len(t, spans, 1)
+	assertSpan(t, spans[0], "/user/{id}", trace.SpanKindServer,
+		attribute.String("http.request.body.sha256", digest(`{"name":"alice"}`)),
+		attribute.Int("http.request.body.length", 16),
+		attribute.String("http.response.body.sha256", digest("done")),
+		attribute.Int("http.response.body.length", 4),
+	)
+	for _, a := range spans[0].Attributes() {
+		assert.NotEqual(t, attribute.Key("http.request.body"), a.Key)
+		assert.NotEqual(t, attribute.Key("http.response.body"), a.Key)
+		assert.NotEqual(t, attribute.Key("user.name"), a.Key)
+	}
+}
@@ -0,0 +1,106 @@
+package otelchi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+const (
+	enrichmentTimeoutKey = attribute.Key("otelchi.enrichment.timeout")
+	enrichmentDroppedKey = attribute.Key("otelchi.enrichment.dropped")
+)
+
+// defaultEnrichmentDeadline is the deadline of asynchronous enrichers when
+// none is given.
+const defaultEnrichmentDeadline = 5 * time.Second
+
+// maxConcurrentEnrichments bounds the enrichments in progress, so that a
+// slow enricher cannot pile up goroutines.
+const maxConcurrentEnrichments = 128
+
+// RequestSnapshot is the state of a served request handed to asynchronous
+// enrichers. It is a copy, which remains valid after the request is
+// served.
+type RequestSnapshot struct {
+	// Context is canceled at the enrichment deadline, and carries the span
+	// of the request. Enrichers must return once it is done: until then,
+	// they hold one of the limited enrichment slots.
+	Context        context.Context
+	SpanContext    oteltrace.SpanContext
+	Method         string
+	Path           string
+	Route          string
+	RequestHeader  http.Header
+	Status         int
+	ResponseHeader http.Header
+	Start, End     time.Time
+}
+
+// AsyncEnricher returns attributes to add to the span of a served request,
+// typically from I/O bound lookups like feature flags or user profiles.
+type AsyncEnricher func(snapshot RequestSnapshot) []attribute.KeyValue
+
+// asyncEnrichment runs an enricher after the response is complete, and
+// ends the span once it returns or its deadline is reached.
+type asyncEnrichment struct {
+	enricher AsyncEnricher
+	deadline time.Duration
+	// slots holds a token per enrichment in progress.
+	slots chan struct{}
+}
+
+func newAsyncEnrichment(enricher AsyncEnricher, deadline time.Duration) *asyncEnrichment {
+	return &asyncEnrichment{
+		enricher: enricher,
+		deadline: deadline,
+		slots:    make(chan struct{}, maxConcurrentEnrichments),
+	}
+}
+
+// enrich runs the enricher in a new goroutine, which ends span with the
+// snapshot end time. Attributes returned past the deadline are dropped and
+// the span gets the otelchi.enrichment.timeout attribute instead. When all
+// the slots are taken, the enrichment is dropped and the span ends at once
+// with the otelchi.enrichment.dropped attribute.
+func (e *asyncEnrichment) enrich(span oteltrace.Span, snapshot RequestSnapshot) {
+	select {
+	case e.slots <- struct{}{}:
+	default:
+		span.SetAttributes(enrichmentDroppedKey.Bool(true))
+		span.End(oteltrace.WithTimestamp(snapshot.End))
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(oteltrace.ContextWithSpan(context.Background(), span), e.deadline)
+		defer cancel()
+		snapshot.Context = ctx
+
+		done := make(chan []attribute.KeyValue, 1)
+		go func() {
+			// the slot is released once the enricher returns, which may be
+			// past the deadline
+			defer func() { <-e.slots }()
+			defer func() {
+				if p := recover(); p != nil {
+					otel.Handle(fmt.Errorf("otelchi: async enricher panicked: %v", p))
+					done <- nil
+				}
+			}()
+			done <- e.enricher(snapshot)
+		}()
+
+		select {
+		case attrs := <-done:
+			span.SetAttributes(attrs...)
+		case <-ctx.Done():
+			span.SetAttributes(enrichmentTimeoutKey.Bool(true))
+		}
+		span.End(oteltrace.WithTimestamp(snapshot.End))
+	}()
+}
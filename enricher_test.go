@@ -0,0 +1,98 @@
+package otelchi
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSDKIntegrationWithAsyncEnricher(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	release := make(chan struct{})
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar",
+		WithTracerProvider(provider),
+		WithAsyncEnricher(func(snapshot RequestSnapshot) []attribute.KeyValue {
+			<-release
+			return []attribute.KeyValue{
+				attribute.String("app.route", snapshot.Route),
+				attribute.String("app.user_class", snapshot.RequestHeader.Get("X-User-Class")),
+			}
+		}, time.Second),
+	))
+	router.HandleFunc("/user/{id}", ok)
+
+	r := httptest.NewRequest("GET", "/user/123", nil)
+	r.Header.Set("X-User-Class", "premium")
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	// the response is served before the enrichment
+	assert.Len(t, sr.Ended(), 0)
+	close(release)
+	require.Eventually(t, func() bool { return len(sr.Ended()) == 1 }, time.Second, time.Millisecond)
+	assertSpan(t, sr.Ended()[0], "/user/{id}", trace.SpanKindServer,
+		attribute.String("app.route", "/user/{id}"),
+		attribute.String("app.user_class", "premium"),
+	)
+}
+
+func TestSDKIntegrationWithAsyncEnricherDeadline(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar",
+		WithTracerProvider(provider),
+		WithAsyncEnricher(func(snapshot RequestSnapshot) []attribute.KeyValue {
+			<-snapshot.Context.Done()
+			return []attribute.KeyValue{attribute.Bool("app.late", true)}
+		}, 10*time.Millisecond),
+	))
+	router.HandleFunc("/user/{id}", ok)
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/user/123", nil))
+
+	require.Eventually(t, func() bool { return len(sr.Ended()) == 1 }, time.Second, time.Millisecond)
+	span := sr.Ended()[0]
+	assertSpan(t, span, "/user/{id}", trace.SpanKindServer, attribute.Bool("otelchi.enrichment.timeout", true))
+	assert.NotContains(t, span.Attributes(), attribute.Bool("app.late", true))
+}
+
+func TestSDKIntegrationWithAsyncEnricherSaturated(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	release := make(chan struct{})
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar",
+		WithTracerProvider(provider),
+		WithAsyncEnricher(func(snapshot RequestSnapshot) []attribute.KeyValue {
+			<-release
+			return nil
+		}, time.Minute),
+	))
+	router.HandleFunc("/user/{id}", ok)
+
+	for i := 0; i < maxConcurrentEnrichments+1; i++ {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/user/123", nil))
+	}
+
+	// the enrichment past the limit is dropped, its span ending at once
+	require.Len(t, sr.Ended(), 1)
+	assertSpan(t, sr.Ended()[0], "/user/{id}", trace.SpanKindServer, attribute.Bool("otelchi.enrichment.dropped", true))
+	close(release)
+	require.Eventually(t, func() bool { return len(sr.Ended()) == maxConcurrentEnrichments+1 }, time.Second, time.Millisecond)
+}
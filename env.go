@@ -0,0 +1,101 @@
+package otelchi
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+)
+
+// The environment variables configuring the middleware, so that operators
+// can change the options of prebuilt binaries. The options given to the
+// middleware take precedence over them.
+const (
+	// EnvMetadataOnly set to true or false is WithMetadataOnly, and takes
+	// precedence over HS_METADATA_ONLY.
+	EnvMetadataOnly = "OTELCHI_METADATA_ONLY"
+	// EnvCaptureMaxBody set to a number of bytes is WithMaxBodySize.
+	EnvCaptureMaxBody = "OTELCHI_CAPTURE_MAX_BODY"
+	// EnvCaptureRatio set to a fraction is WithBodyCaptureRatio.
+	EnvCaptureRatio = "OTELCHI_CAPTURE_RATIO"
+	// EnvCaptureRateLimit set to a number of requests per second is
+	// WithBodyCaptureRateLimit.
+	EnvCaptureRateLimit = "OTELCHI_CAPTURE_RATE_LIMIT"
+	// EnvHeaderAllowlist set to a comma-separated list of headers is
+	// WithHeaderAllowlist.
+	EnvHeaderAllowlist = "OTELCHI_HEADER_ALLOWLIST"
+	// EnvSkipPaths set to a comma-separated list of URL paths filters the
+	// requests to these paths out, see WithFilter.
+	EnvSkipPaths = "OTELCHI_SKIP_PATHS"
+)
+
+// applyEnvironment sets the options configured by the environment
+// variables in cfg. Invalid values are reported to the global OTel error
+// handler and ignored.
+func applyEnvironment(cfg *config) {
+	if value, ok := lookupEnv(EnvMetadataOnly); ok {
+		if metadataOnly, err := strconv.ParseBool(value); err != nil {
+			handleEnvError(EnvMetadataOnly, err)
+		} else {
+			cfg.MetadataOnly = &metadataOnly
+		}
+	}
+	if value, ok := lookupEnv(EnvCaptureMaxBody); ok {
+		if size, err := strconv.Atoi(value); err != nil {
+			handleEnvError(EnvCaptureMaxBody, err)
+		} else {
+			cfg.MaxBodySize = size
+		}
+	}
+	if value, ok := lookupEnv(EnvCaptureRatio); ok {
+		if ratio, err := strconv.ParseFloat(value, 64); err != nil {
+			handleEnvError(EnvCaptureRatio, err)
+		} else {
+			cfg.BodyCaptureRatio = &ratio
+		}
+	}
+	if value, ok := lookupEnv(EnvCaptureRateLimit); ok {
+		if limit, err := strconv.ParseFloat(value, 64); err != nil {
+			handleEnvError(EnvCaptureRateLimit, err)
+		} else {
+			cfg.CaptureRateLimit = limit
+		}
+	}
+	if value, ok := lookupEnv(EnvHeaderAllowlist); ok {
+		cfg.HeaderAllowlist = splitEnvList(value)
+	}
+	if value, ok := lookupEnv(EnvSkipPaths); ok {
+		skipped := map[string]bool{}
+		for _, path := range splitEnvList(value) {
+			skipped[path] = true
+		}
+		cfg.Filters = append(cfg.Filters, func(r *http.Request) bool {
+			return !skipped[r.URL.Path]
+		})
+	}
+}
+
+// lookupEnv returns the value of the environment variable key, unless it is
+// unset or empty.
+func lookupEnv(key string) (string, bool) {
+	value := strings.TrimSpace(os.Getenv(key))
+	return value, value != ""
+}
+
+// splitEnvList returns the elements of a comma-separated list.
+func splitEnvList(value string) []string {
+	var list []string
+	for _, element := range strings.Split(value, ",") {
+		if element = strings.TrimSpace(element); element != "" {
+			list = append(list, element)
+		}
+	}
+	return list
+}
+
+func handleEnvError(key string, err error) {
+	otel.Handle(fmt.Errorf("otelchi: invalid %s: %w", key, err))
+}
@@ -0,0 +1,61 @@
+package otelchi
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestEnvironmentConfig(t *testing.T) {
+	t.Setenv(EnvMetadataOnly, "false")
+	t.Setenv(EnvCaptureMaxBody, "4")
+	t.Setenv(EnvCaptureRatio, "0.25")
+	t.Setenv(EnvCaptureRateLimit, "invalid")
+	t.Setenv(EnvHeaderAllowlist, "Accept, X-Custom,")
+
+	cfg := newConfig(nil)
+	require.NotNil(t, cfg.MetadataOnly)
+	assert.False(t, *cfg.MetadataOnly)
+	assert.Equal(t, 4, cfg.MaxBodySize)
+	require.NotNil(t, cfg.BodyCaptureRatio)
+	assert.Equal(t, 0.25, *cfg.BodyCaptureRatio)
+	assert.Zero(t, cfg.CaptureRateLimit)
+	assert.Equal(t, []string{"Accept", "X-Custom"}, cfg.HeaderAllowlist)
+
+	// options take precedence over the environment
+	cfg = newConfig([]Option{WithMaxBodySize(8), WithMetadataOnly(true)})
+	assert.Equal(t, 8, cfg.MaxBodySize)
+	assert.True(t, *cfg.MetadataOnly)
+}
+
+func TestSDKIntegrationWithEnvironment(t *testing.T) {
+	t.Setenv(EnvCaptureMaxBody, "4")
+	t.Setenv(EnvSkipPaths, "/health,/ready")
+
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar", WithTracerProvider(provider)))
+	router.HandleFunc("/user/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+	})
+	router.HandleFunc("/health", ok)
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/health", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("PUT", "/user/123", strings.NewReader("valid")))
+
+	spans := sr.Ended()
+	require.Len(t, spans, 1)
+	assert.Contains(t, spans[0].Attributes(), attribute.String("http.request.body", "vali"))
+}
@@ -0,0 +1,115 @@
+package otelchi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+const errorTypeKey = attribute.Key("error.type")
+
+// RecordError reports err as the error the handler of the request of ctx
+// failed with. With WithErrorEvents, it is recorded as an exception event
+// on the server span once the request is served. It does nothing when ctx
+// is not the context of a request traced by the middleware.
+func RecordError(ctx context.Context, err error) {
+	if err == nil {
+		return
+	}
+	if owner, ok := ctx.Value(spanOwnerKey{}).(*spanOwner); ok {
+		owner.err = err
+		if owner.stackTraces != nil {
+			owner.stack = owner.stackTraces.capture(1)
+		}
+	}
+}
+
+// recordError sets the error.type attribute of the span of a request
+// answered with a server error to the status code, as per the semantic
+// conventions, and records the error reported by the handler, if any, as an
+// exception event when error events are enabled.
+func (tw traceware) recordError(span oteltrace.Span, rrw *recordingResponseWriter, err error, stack string) {
+	if rrw.status >= http.StatusInternalServerError {
+		span.SetAttributes(errorTypeKey.String(strconv.Itoa(rrw.status)))
+	}
+	if err != nil && tw.errorEvents {
+		span.RecordError(err, stackTraceAttributes(stack)...)
+	}
+}
+
+// stackTraceAttributes returns the exception event option setting the
+// stack trace, if captured.
+func stackTraceAttributes(stack string) []oteltrace.EventOption {
+	if stack == "" {
+		return nil
+	}
+	return []oteltrace.EventOption{oteltrace.WithAttributes(semconv.ExceptionStacktraceKey.String(stack))}
+}
+
+// panicStack returns the stack trace of the panic being recovered by the
+// deferred func calling it, if stack traces are captured.
+func (tw traceware) panicStack() string {
+	if !tw.errorEvents || tw.stackTraces == nil {
+		return ""
+	}
+	// skip panicStack and the deferred func calling it
+	return tw.stackTraces.capture(2)
+}
+
+// recordPanic marks the span of request r, whose handler panicked with p,
+// with the stack trace stack if captured. The error.type attribute is the
+// type of p. When the handler did not return, the span is named here if
+// the route was not known beforehand.
+func (tw traceware) recordPanic(span oteltrace.Span, r *http.Request, routePattern string, p interface{}, stack string) {
+	err, ok := p.(error)
+	if !ok {
+		err = fmt.Errorf("%v", p)
+	}
+	if errors.Is(err, http.ErrAbortHandler) {
+		// net/http aborts the response quietly
+		return
+	}
+	if routePattern == "" {
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			if pattern := rctx.RoutePattern(); pattern != "" {
+				span.SetAttributes(semconv.HTTPRouteKey.String(pattern))
+				span.SetName(tw.guardSpanName(r.Context(), r.Method, addPrefixToSpanName(tw.reqMethodInSpanName, r.Method, pattern)))
+			}
+		}
+	}
+	span.SetAttributes(errorTypeKey.String(fmt.Sprintf("%T", p)))
+	if tw.errorEvents {
+		span.RecordError(err, stackTraceAttributes(stack)...)
+	}
+	span.SetStatus(codes.Error, fmt.Sprintf("handler panicked: %v", p))
+}
+
+// serveRecovering serves r, recovering the panic of the handler, if any,
+// which is returned along with its stack trace when captured. The request
+// is then answered with a 500 Internal Server Error, unless the handler
+// already wrote the response header, so that the span and the metrics
+// record the response the client got. As with net/http,
+// http.ErrAbortHandler aborts the response.
+func (tw traceware) serveRecovering(rrw *recordingResponseWriter, r *http.Request) (p interface{}, stack string) {
+	defer func() {
+		if p = recover(); p != nil {
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+			stack = tw.panicStack()
+			if !rrw.written {
+				rrw.writer.WriteHeader(http.StatusInternalServerError)
+			}
+		}
+	}()
+	tw.handler.ServeHTTP(rrw.writer, r)
+	return nil, ""
+}
@@ -0,0 +1,84 @@
+package otelchi
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSDKIntegrationWithErrorEvents(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		opts   []Option
+		events int
+	}{
+		{name: "default"},
+		{name: "error events", opts: []Option{WithErrorEvents()}, events: 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			sr := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider()
+			provider.RegisterSpanProcessor(sr)
+
+			router := chi.NewRouter()
+			router.Use(func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					defer func() {
+						if recover() != nil {
+							w.WriteHeader(http.StatusInternalServerError)
+						}
+					}()
+					next.ServeHTTP(w, r)
+				})
+			})
+			router.Use(Middleware("foobar", append(tc.opts, WithTracerProvider(provider))...))
+			router.HandleFunc("/fail", func(w http.ResponseWriter, r *http.Request) {
+				RecordError(r.Context(), errors.New("database unavailable"))
+				w.WriteHeader(http.StatusServiceUnavailable)
+			})
+			router.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) {
+				panic(errors.New("nil map"))
+			})
+			router.HandleFunc("/ok", ok)
+
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/fail", nil))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("GET", "/panic", nil))
+			assert.Equal(t, http.StatusInternalServerError, w.Code)
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ok", nil))
+
+			spans := sr.Ended()
+			require.Len(t, spans, 3)
+			assertSpan(t, spans[0], "/fail", trace.SpanKindServer,
+				attribute.String("error.type", "503"),
+			)
+			assertSpan(t, spans[1], "/panic", trace.SpanKindServer,
+				attribute.String("error.type", "*errors.errorString"),
+			)
+			assert.Equal(t, codes.Error, spans[1].Status().Code)
+			assert.Equal(t, "handler panicked: nil map", spans[1].Status().Description)
+			for _, a := range spans[2].Attributes() {
+				assert.NotEqual(t, attribute.Key("error.type"), a.Key)
+			}
+
+			for i, message := range []string{"database unavailable", "nil map"} {
+				events := spans[i].Events()
+				require.Len(t, events, tc.events)
+				if tc.events > 0 {
+					assert.Equal(t, "exception", events[0].Name)
+					assert.Contains(t, events[0].Attributes, attribute.String("exception.message", message))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package otelchi
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+const droppedEventsKey = attribute.Key("otelchi.span.events.dropped")
+
+// eventLimits caps the number of events recorded on server spans.
+type eventLimits struct {
+	defaultLimit int
+	routeLimits  map[string]int
+	// maxLimit is the largest limit, used to size the reservoir when the
+	// route is not known yet.
+	maxLimit int
+}
+
+func newEventLimits(defaultLimit int, routeLimits map[string]int) *eventLimits {
+	l := &eventLimits{defaultLimit: defaultLimit, routeLimits: routeLimits, maxLimit: defaultLimit}
+	for _, limit := range routeLimits {
+		if limit < 0 || l.maxLimit < 0 {
+			l.maxLimit = -1
+		} else if limit > l.maxLimit {
+			l.maxLimit = limit
+		}
+	}
+	return l
+}
+
+// limit returns the event limit of routePattern, a negative limit meaning
+// there is none.
+func (l *eventLimits) limit(routePattern string) int {
+	if limit, ok := l.routeLimits[routePattern]; ok {
+		return limit
+	}
+	return l.defaultLimit
+}
+
+// bufferedEvent is an event, or recorded error, waiting to be added to the
+// span.
+type bufferedEvent struct {
+	seq   int
+	apply func(span oteltrace.Span)
+}
+
+// eventSamplingSpan is handed to handlers in place of the server span. It
+// keeps a uniform sample of the events added during the request using
+// reservoir sampling, and adds them to the server span, with their original
+// timestamps, when flushed.
+type eventSamplingSpan struct {
+	oteltrace.Span
+
+	clock    func() time.Time
+	mu       sync.Mutex
+	capacity int // negative for unbounded
+	seen     int
+	events   []bufferedEvent
+}
+
+func newEventSamplingSpan(span oteltrace.Span, capacity int, clock func() time.Time) *eventSamplingSpan {
+	return &eventSamplingSpan{Span: span, capacity: capacity, clock: clock}
+}
+
+func (s *eventSamplingSpan) AddEvent(name string, options ...oteltrace.EventOption) {
+	options = append([]oteltrace.EventOption{oteltrace.WithTimestamp(now(s.clock))}, options...)
+	s.buffer(func(span oteltrace.Span) {
+		span.AddEvent(name, options...)
+	})
+}
+
+func (s *eventSamplingSpan) RecordError(err error, options ...oteltrace.EventOption) {
+	if err == nil {
+		return
+	}
+	options = append([]oteltrace.EventOption{oteltrace.WithTimestamp(now(s.clock))}, options...)
+	s.buffer(func(span oteltrace.Span) {
+		span.RecordError(err, options...)
+	})
+}
+
+func (s *eventSamplingSpan) buffer(apply func(span oteltrace.Span)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	event := bufferedEvent{seq: s.seen, apply: apply}
+	s.seen++
+	switch {
+	case s.capacity < 0 || len(s.events) < s.capacity:
+		s.events = append(s.events, event)
+	case s.capacity > 0:
+		if i := rand.Intn(s.seen); i < s.capacity {
+			s.events[i] = event
+		}
+	}
+}
+
+// flush adds at most limit of the buffered events to the server span, in
+// the order they were added, and records how many events were dropped.
+func (s *eventSamplingSpan) flush(limit int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	events := s.events
+	if limit >= 0 && len(events) > limit {
+		// a random subset of a uniform sample is a uniform sample
+		rand.Shuffle(len(events), func(i, j int) {
+			events[i], events[j] = events[j], events[i]
+		})
+		events = events[:limit]
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].seq < events[j].seq
+	})
+	for _, event := range events {
+		event.apply(s.Span)
+	}
+	if dropped := s.seen - len(events); dropped > 0 {
+		s.Span.SetAttributes(droppedEventsKey.Int(dropped))
+	}
+	s.events, s.seen = nil, 0
+}
@@ -0,0 +1,61 @@
+package otelchi
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSDKIntegrationWithEventLimits(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	chatty := func(w http.ResponseWriter, r *http.Request) {
+		span := trace.SpanFromContext(r.Context())
+		for i := 0; i < 100; i++ {
+			span.AddEvent(fmt.Sprintf("event %d", i))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar",
+		WithTracerProvider(provider),
+		WithEventLimits(10, map[string]int{"/batch": 3, "/debug": -1}),
+	))
+	router.HandleFunc("/user/{id}", chatty)
+	router.HandleFunc("/batch", chatty)
+	router.HandleFunc("/debug", chatty)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/user/123", nil))
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/batch", nil))
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/debug", nil))
+
+	require.Len(t, sr.Ended(), 3)
+	assertEvents := func(span sdktrace.ReadOnlySpan, want int) {
+		events := span.Events()
+		require.Len(t, events, want)
+		for i := 1; i < len(events); i++ {
+			assert.False(t, events[i].Time.Before(events[i-1].Time), "events are out of order")
+		}
+	}
+	assertEvents(sr.Ended()[0], 10)
+	assertSpan(t, sr.Ended()[0], "/user/{id}", trace.SpanKindServer,
+		attribute.Int("otelchi.span.events.dropped", 90),
+	)
+	assertEvents(sr.Ended()[1], 3)
+	assertSpan(t, sr.Ended()[1], "/batch", trace.SpanKindServer,
+		attribute.Int("otelchi.span.events.dropped", 97),
+	)
+	assertEvents(sr.Ended()[2], 100)
+}
@@ -0,0 +1,105 @@
+package otelchi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/ohler55/ojg/jp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// bodyExtractor extracts the value of a JSONPath expression from a JSON
+// body into a span attribute.
+type bodyExtractor struct {
+	key  attribute.Key
+	expr jp.Expr
+}
+
+// parseBodyExtractors compiles the given attribute name to JSONPath
+// expression mapping. Invalid expressions are reported to the global OTel
+// error handler and ignored.
+func parseBodyExtractors(exprs map[string]string) []bodyExtractor {
+	if len(exprs) == 0 {
+		return nil
+	}
+	extractors := make([]bodyExtractor, 0, len(exprs))
+	for name, path := range exprs {
+		expr, err := jp.ParseString(path)
+		if err != nil {
+			otel.Handle(fmt.Errorf("otelchi: invalid JSONPath expression %q for attribute %q: %w", path, name, err))
+			continue
+		}
+		extractors = append(extractors, bodyExtractor{key: attribute.Key(name), expr: expr})
+	}
+	sort.Slice(extractors, func(i, j int) bool {
+		return extractors[i].key < extractors[j].key
+	})
+	return extractors
+}
+
+// extractBodyAttributes evaluates the extractors against the JSON request
+// and response bodies. The request body is looked at first, the response
+// body is only used for extractors that have no match in the request body.
+func extractBodyAttributes(extractors []bodyExtractor, requestBody, responseBody []byte) []attribute.KeyValue {
+	if len(extractors) == 0 {
+		return nil
+	}
+	bodies := make([]interface{}, 0, 2)
+	for _, body := range [][]byte{requestBody, responseBody} {
+		if data, ok := parseJSONBody(body); ok {
+			bodies = append(bodies, data)
+		}
+	}
+	if len(bodies) == 0 {
+		return nil
+	}
+
+	var attrs []attribute.KeyValue
+	for _, extractor := range extractors {
+		for _, data := range bodies {
+			results := extractor.expr.Get(data)
+			if len(results) == 0 {
+				continue
+			}
+			attrs = append(attrs, jsonAttribute(extractor.key, results[0]))
+			break
+		}
+	}
+	return attrs
+}
+
+func parseJSONBody(body []byte) (interface{}, bool) {
+	if len(body) == 0 {
+		return nil, false
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var data interface{}
+	if err := dec.Decode(&data); err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+func jsonAttribute(key attribute.Key, value interface{}) attribute.KeyValue {
+	switch v := value.(type) {
+	case string:
+		return key.String(v)
+	case bool:
+		return key.Bool(v)
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return key.Int64(i)
+		}
+		if f, err := v.Float64(); err == nil {
+			return key.Float64(f)
+		}
+		return key.String(v.String())
+	default:
+		b, _ := json.Marshal(v)
+		return key.String(string(b))
+	}
+}
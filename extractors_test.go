@@ -0,0 +1,56 @@
+package otelchi
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSDKIntegrationWithBodyAttributeExtractors(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar",
+		WithTracerProvider(provider),
+		WithBodyAttributeExtractors(map[string]string{
+			"app.order_id":    "$.order.id",
+			"app.order_total": "$.order.total",
+			"app.status":      "$.status",
+			"app.missing":     "$.nope",
+			"app.invalid":     "$[",
+		}),
+	))
+	router.HandleFunc("/orders", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"accepted"}`))
+	})
+
+	r := httptest.NewRequest("POST", "/orders", strings.NewReader(`{"order":{"id":"o-1","total":42}}`))
+	r.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	require.Len(t, sr.Ended(), 1)
+	span := sr.Ended()[0]
+	assertSpan(t, span, "/orders", trace.SpanKindServer,
+		attribute.String("app.order_id", "o-1"),
+		attribute.Int64("app.order_total", 42),
+		attribute.String("app.status", "accepted"),
+	)
+	for _, kv := range span.Attributes() {
+		assert.NotEqual(t, attribute.Key("app.missing"), kv.Key)
+		assert.NotEqual(t, attribute.Key("app.invalid"), kv.Key)
+	}
+}
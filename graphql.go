@@ -0,0 +1,136 @@
+package otelchi
+
+import (
+	"encoding/json"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	graphqlOperationNameKey = attribute.Key("graphql.operation.name")
+	graphqlOperationTypeKey = attribute.Key("graphql.operation.type")
+)
+
+type graphqlOperation struct {
+	Type string
+	Name string
+}
+
+// spanName returns the span name for the operation, e.g. "query GetUser".
+func (op graphqlOperation) spanName() string {
+	if op.Name == "" {
+		return op.Type
+	}
+	return op.Type + " " + op.Name
+}
+
+func (op graphqlOperation) attributes() []attribute.KeyValue {
+	attrs := []attribute.KeyValue{graphqlOperationTypeKey.String(op.Type)}
+	if op.Name != "" {
+		attrs = append(attrs, graphqlOperationNameKey.String(op.Name))
+	}
+	return attrs
+}
+
+// parseGraphQLRequest extracts the executed operation from a GraphQL over
+// HTTP request body. Batched requests are not supported.
+func parseGraphQLRequest(body []byte) (graphqlOperation, bool) {
+	var req struct {
+		Query         string `json:"query"`
+		OperationName string `json:"operationName"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil || req.Query == "" {
+		return graphqlOperation{}, false
+	}
+
+	ops := graphqlOperations(req.Query)
+	if req.OperationName != "" {
+		for _, op := range ops {
+			if op.Name == req.OperationName {
+				return op, true
+			}
+		}
+		return graphqlOperation{}, false
+	}
+	if len(ops) != 1 {
+		return graphqlOperation{}, false
+	}
+	return ops[0], true
+}
+
+// graphqlOperations lists the operations defined at the top level of a
+// GraphQL document. It is a minimal lexer that only understands enough of
+// the grammar to find operation types and names; fragments are skipped.
+func graphqlOperations(doc string) []graphqlOperation {
+	var (
+		ops     []graphqlOperation
+		depth   int
+		header  bool   // inside a definition header, before its selection set
+		pending string // operation type waiting for its name
+	)
+	for i := 0; i < len(doc); i++ {
+		c := doc[i]
+		switch {
+		case c == '#':
+			for i < len(doc) && doc[i] != '\n' {
+				i++
+			}
+		case c == '"':
+			i = skipGraphQLString(doc, i)
+		case c == '{' || c == '(':
+			if depth == 0 && c == '{' {
+				switch {
+				case pending != "":
+					ops = append(ops, graphqlOperation{Type: pending})
+				case !header:
+					// anonymous query shorthand
+					ops = append(ops, graphqlOperation{Type: "query"})
+				}
+				header, pending = false, ""
+			}
+			depth++
+		case c == '}' || c == ')':
+			depth--
+		case depth == 0 && isGraphQLNameStart(c):
+			j := i
+			for j < len(doc) && isGraphQLNameChar(doc[j]) {
+				j++
+			}
+			word := doc[i:j]
+			directive := i > 0 && doc[i-1] == '@'
+			i = j - 1
+			switch {
+			case directive:
+			case pending != "":
+				ops = append(ops, graphqlOperation{Type: pending, Name: word})
+				pending = ""
+			case header:
+			case word == "query" || word == "mutation" || word == "subscription":
+				header, pending = true, word
+			case word == "fragment":
+				header = true
+			}
+		}
+	}
+	return ops
+}
+
+func skipGraphQLString(doc string, i int) int {
+	for i++; i < len(doc); i++ {
+		switch doc[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return i
+}
+
+func isGraphQLNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isGraphQLNameChar(c byte) bool {
+	return isGraphQLNameStart(c) || (c >= '0' && c <= '9')
+}
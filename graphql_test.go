@@ -0,0 +1,93 @@
+package otelchi
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestParseGraphQLRequest(t *testing.T) {
+	testCases := []struct {
+		name string
+		body string
+		op   graphqlOperation
+		ok   bool
+	}{
+		{
+			name: "named query",
+			body: `{"query":"query GetUser($id: ID!) { user(id: $id) { name } }"}`,
+			op:   graphqlOperation{Type: "query", Name: "GetUser"},
+			ok:   true,
+		},
+		{
+			name: "anonymous shorthand",
+			body: `{"query":"{ user { name } }"}`,
+			op:   graphqlOperation{Type: "query"},
+			ok:   true,
+		},
+		{
+			name: "operation selected by name",
+			body: `{"query":"# comment\nquery A { a } mutation B @log { b } fragment F on X { c }","operationName":"B"}`,
+			op:   graphqlOperation{Type: "mutation", Name: "B"},
+			ok:   true,
+		},
+		{
+			name: "ambiguous document",
+			body: `{"query":"query A { a } query B { b }"}`,
+		},
+		{
+			name: "not graphql",
+			body: `hello`,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			op, ok := parseGraphQLRequest([]byte(testCase.body))
+			assert.Equal(t, testCase.ok, ok)
+			assert.Equal(t, testCase.op, op)
+		})
+	}
+}
+
+func TestSDKIntegrationWithGraphQLRoutes(t *testing.T) {
+	t.Setenv("HS_METADATA_ONLY", "true")
+
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar",
+		WithTracerProvider(provider),
+		WithGraphQLRoutes("/graphql"),
+	))
+	router.HandleFunc("/graphql", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	r := httptest.NewRequest("POST", "/graphql", strings.NewReader(`{"query":"mutation AddUser { addUser { id } }"}`))
+	r.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	require.Len(t, sr.Ended(), 1)
+	span := sr.Ended()[0]
+	assertSpan(t, span, "mutation AddUser", trace.SpanKindServer,
+		attribute.String("graphql.operation.type", "mutation"),
+		attribute.String("graphql.operation.name", "AddUser"),
+		attribute.String("http.route", "/graphql"),
+	)
+	for _, kv := range span.Attributes() {
+		assert.NotEqual(t, attribute.Key("http.request.body"), kv.Key)
+	}
+}
@@ -0,0 +1,208 @@
+package otelchi
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	requestHeadersKey      = attribute.Key("http.request.headers")
+	requestHeaderKeyPrefix = "http.request.header."
+	requestCookieNamesKey  = attribute.Key("http.request.cookie.names")
+	requestCookieKeyPrefix = "http.request.cookie."
+	trailerKeyPrefix       = "http.response.trailer."
+)
+
+// DefaultRedactedHeaders returns the headers whose values are redacted from
+// captured headers unless configured otherwise with WithRedactedHeaders or
+// WithoutHeaderRedaction. A new slice is returned on every call, so it can
+// be extended safely.
+func DefaultRedactedHeaders() []string {
+	return []string{
+		"Authorization",
+		"Proxy-Authorization",
+		"X-Api-Key",
+		"Cookie",
+		"Set-Cookie",
+	}
+}
+
+// headerCapture turns captured headers into span attributes.
+type headerCapture struct {
+	// structured records one attribute per header instead of a JSON blob
+	structured bool
+	// headersKey is the key of the JSON blob, see WithAttributeKeys
+	headersKey attribute.Key
+	// cookies records the names of the request cookies
+	cookies bool
+	// cookieAllowlist lists the cookies whose values are recorded
+	cookieAllowlist map[string]bool
+	// redacted lists the canonical names of the headers whose values are
+	// redacted
+	redacted []string
+	// allowlist lists the canonical names of the captured headers, all
+	// headers are captured when nil
+	allowlist map[string]bool
+}
+
+func newHeaderCapture(cfg config) headerCapture {
+	hc := headerCapture{
+		structured: cfg.HeaderAttributes,
+		headersKey: attribute.Key(newAttributeKeys(cfg).RequestHeaders),
+		cookies:    cfg.CookieCapture,
+	}
+	redacted := cfg.RedactedHeaders
+	if redacted == nil {
+		redacted = DefaultRedactedHeaders()
+	}
+	for _, name := range redacted {
+		hc.redacted = append(hc.redacted, http.CanonicalHeaderKey(name))
+	}
+	if len(cfg.HeaderAllowlist) > 0 {
+		hc.allowlist = make(map[string]bool, len(cfg.HeaderAllowlist))
+		for _, name := range cfg.HeaderAllowlist {
+			hc.allowlist[http.CanonicalHeaderKey(name)] = true
+		}
+	}
+	if len(cfg.CookieAllowlist) > 0 {
+		hc.cookieAllowlist = make(map[string]bool, len(cfg.CookieAllowlist))
+		for _, name := range cfg.CookieAllowlist {
+			hc.cookieAllowlist[name] = true
+		}
+	}
+	return hc
+}
+
+// redact returns header with the values of sensitive headers replaced. The
+// given header is not modified.
+func (hc headerCapture) redact(header http.Header) http.Header {
+	var redacted http.Header
+	for _, name := range hc.redacted {
+		values, ok := header[name]
+		if !ok {
+			continue
+		}
+		if redacted == nil {
+			redacted = header.Clone()
+		}
+		masked := make([]string, len(values))
+		for i := range values {
+			masked[i] = redactedValue
+		}
+		redacted[name] = masked
+	}
+	if redacted == nil {
+		return header
+	}
+	return redacted
+}
+
+// capture returns the allowlisted headers of header, redacted. The given
+// header is not modified.
+func (hc headerCapture) capture(header http.Header) http.Header {
+	if hc.allowlist != nil {
+		allowed := make(http.Header, len(hc.allowlist))
+		for name, values := range header {
+			if hc.allowlist[name] {
+				allowed[name] = values
+			}
+		}
+		header = allowed
+	}
+	return hc.redact(header)
+}
+
+func (hc headerCapture) requestAttributes(r *http.Request) []attribute.KeyValue {
+	attrs := hc.headerAttributes(hc.capture(r.Header))
+	if hc.cookies {
+		attrs = append(attrs, hc.cookieAttributes(r)...)
+	}
+	return attrs
+}
+
+func (hc headerCapture) headerAttributes(header http.Header) []attribute.KeyValue {
+	if !hc.structured {
+		headersStr, err := json.Marshal(header)
+		if err != nil {
+			return nil
+		}
+		key := hc.headersKey
+		if key == "" {
+			key = requestHeadersKey
+		}
+		return []attribute.KeyValue{key.String(string(headersStr))}
+	}
+
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	attrs := make([]attribute.KeyValue, 0, len(names))
+	for _, name := range names {
+		key := attribute.Key(requestHeaderKeyPrefix + strings.ToLower(name))
+		attrs = append(attrs, key.StringSlice(header[name]))
+	}
+	return attrs
+}
+
+// cookieAttributes records the names of the request cookies, and the values
+// of the allowlisted ones.
+func (hc headerCapture) cookieAttributes(r *http.Request) []attribute.KeyValue {
+	cookies := r.Cookies()
+	if len(cookies) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(cookies))
+	var attrs []attribute.KeyValue
+	for _, cookie := range cookies {
+		names = append(names, cookie.Name)
+		if hc.cookieAllowlist[cookie.Name] {
+			attrs = append(attrs, attribute.String(requestCookieKeyPrefix+cookie.Name, cookie.Value))
+		}
+	}
+	return append([]attribute.KeyValue{requestCookieNamesKey.StringSlice(names)}, attrs...)
+}
+
+// trailerAttributes records the response trailers, declared in the Trailer
+// header or set with the http.TrailerPrefix, as one attribute per trailer.
+// They are subject to the same allowlist and redaction as headers.
+func (hc headerCapture) trailerAttributes(header http.Header) []attribute.KeyValue {
+	trailers := make(http.Header)
+	for _, value := range header.Values("Trailer") {
+		for _, name := range strings.Split(value, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			if values, ok := header[name]; ok {
+				trailers[name] = values
+			}
+		}
+	}
+	for name, values := range header {
+		if strings.HasPrefix(name, http.TrailerPrefix) {
+			name = http.CanonicalHeaderKey(strings.TrimPrefix(name, http.TrailerPrefix))
+			trailers[name] = append(trailers[name], values...)
+		}
+	}
+	if len(trailers) == 0 {
+		return nil
+	}
+	trailers = hc.capture(trailers)
+
+	names := make([]string, 0, len(trailers))
+	for name := range trailers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	attrs := make([]attribute.KeyValue, 0, len(names))
+	for _, name := range names {
+		key := attribute.Key(trailerKeyPrefix + strings.ToLower(name))
+		attrs = append(attrs, key.StringSlice(trailers[name]))
+	}
+	return attrs
+}
@@ -0,0 +1,106 @@
+package otelchi
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestHeaderCaptureRequestAttributes(t *testing.T) {
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Add("Accept", "text/html")
+	header.Add("Accept", "application/json")
+
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String("http.request.headers", `{"Accept":["text/html","application/json"],"Content-Type":["application/json"]}`),
+	}, headerCapture{}.headerAttributes(header))
+
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.StringSlice("http.request.header.accept", []string{"text/html", "application/json"}),
+		attribute.StringSlice("http.request.header.content-type", []string{"application/json"}),
+	}, headerCapture{structured: true}.headerAttributes(header))
+}
+
+func TestHeaderCaptureCookies(t *testing.T) {
+	r := &http.Request{Header: http.Header{}}
+	r.Header.Set("Cookie", "session=s3cr3t; theme=dark")
+
+	hc := newHeaderCapture(config{HeaderAttributes: true, CookieCapture: true, CookieAllowlist: []string{"theme"}})
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.StringSlice("http.request.header.cookie", []string{"[REDACTED]"}),
+		attribute.StringSlice("http.request.cookie.names", []string{"session", "theme"}),
+		attribute.String("http.request.cookie.theme", "dark"),
+	}, hc.requestAttributes(r))
+
+	// the request itself is left untouched
+	assert.Equal(t, "session=s3cr3t; theme=dark", r.Header.Get("Cookie"))
+
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String("http.request.headers", `{"Cookie":["[REDACTED]"]}`),
+	}, newHeaderCapture(config{}).requestAttributes(r))
+}
+
+func TestHeaderCaptureRedaction(t *testing.T) {
+	r := &http.Request{Header: http.Header{}}
+	r.Header.Set("Authorization", "Bearer token")
+	r.Header.Set("X-Api-Key", "key")
+	r.Header.Set("X-Internal-Token", "internal")
+
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String("http.request.headers", `{"Authorization":["[REDACTED]"],"X-Api-Key":["[REDACTED]"],"X-Internal-Token":["internal"]}`),
+	}, newHeaderCapture(config{}).requestAttributes(r))
+
+	extended := append(DefaultRedactedHeaders(), "x-internal-token")
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String("http.request.headers", `{"Authorization":["[REDACTED]"],"X-Api-Key":["[REDACTED]"],"X-Internal-Token":["[REDACTED]"]}`),
+	}, newHeaderCapture(config{RedactedHeaders: extended}).requestAttributes(r))
+
+	cfg := config{}
+	WithRedactedHeaders().apply(&cfg)
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String("http.request.headers", `{"Authorization":["[REDACTED]"],"X-Api-Key":["[REDACTED]"],"X-Internal-Token":["internal"]}`),
+	}, newHeaderCapture(cfg).requestAttributes(r))
+
+	cfg = config{}
+	WithoutHeaderRedaction().apply(&cfg)
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String("http.request.headers", `{"Authorization":["Bearer token"],"X-Api-Key":["key"],"X-Internal-Token":["internal"]}`),
+	}, newHeaderCapture(cfg).requestAttributes(r))
+}
+
+func TestHeaderCaptureAllowlist(t *testing.T) {
+	r := &http.Request{Header: http.Header{}}
+	r.Header.Set("Authorization", "Bearer token")
+	r.Header.Set("Accept", "text/html")
+	r.Header.Set("X-Internal-Token", "internal")
+
+	hc := newHeaderCapture(config{HeaderAllowlist: []string{"accept", "Authorization"}})
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String("http.request.headers", `{"Accept":["text/html"],"Authorization":["[REDACTED]"]}`),
+	}, hc.requestAttributes(r))
+}
+
+func TestHeaderCaptureTrailers(t *testing.T) {
+	header := http.Header{}
+	header.Set("Content-Type", "application/grpc-web")
+	header.Set("Trailer", "Grpc-Status, Grpc-Message, X-Missing")
+	header.Set("Grpc-Status", "0")
+	header.Set("Grpc-Message", "OK")
+	header.Set(http.TrailerPrefix+"Authorization", "secret")
+
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.StringSlice("http.response.trailer.authorization", []string{"[REDACTED]"}),
+		attribute.StringSlice("http.response.trailer.grpc-message", []string{"OK"}),
+		attribute.StringSlice("http.response.trailer.grpc-status", []string{"0"}),
+	}, newHeaderCapture(config{}).trailerAttributes(header))
+
+	hc := newHeaderCapture(config{HeaderAllowlist: []string{"Grpc-Status"}})
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.StringSlice("http.response.trailer.grpc-status", []string{"0"}),
+	}, hc.trailerAttributes(header))
+
+	assert.Nil(t, newHeaderCapture(config{}).trailerAttributes(http.Header{"Content-Type": {"text/plain"}}))
+}
@@ -0,0 +1,42 @@
+package otelchi
+
+import (
+	"net/http"
+	"strings"
+)
+
+// healthCheckUserAgents are the user agent prefixes of well-known health
+// checkers.
+var healthCheckUserAgents = []string{
+	"kube-probe/",
+	"ELB-HealthChecker/",
+	"GoogleHC/",
+	"Consul Health Check",
+}
+
+// DefaultHealthCheckPaths returns the paths filtered out by
+// WithHealthCheckFilter when none is given.
+func DefaultHealthCheckPaths() []string {
+	return []string{"/healthz", "/livez", "/readyz", "/ping"}
+}
+
+// healthCheckFilter returns a filter rejecting the requests on the given
+// paths and the requests sent by well-known health checkers.
+func healthCheckFilter(paths []string) func(r *http.Request) bool {
+	pathSet := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		pathSet[path] = true
+	}
+	return func(r *http.Request) bool {
+		if pathSet[r.URL.Path] {
+			return false
+		}
+		userAgent := r.UserAgent()
+		for _, prefix := range healthCheckUserAgents {
+			if strings.HasPrefix(userAgent, prefix) {
+				return false
+			}
+		}
+		return true
+	}
+}
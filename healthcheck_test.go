@@ -0,0 +1,33 @@
+package otelchi
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthCheckFilter(t *testing.T) {
+	filter := healthCheckFilter(DefaultHealthCheckPaths())
+
+	testCases := []struct {
+		name      string
+		path      string
+		userAgent string
+		want      bool
+	}{
+		{name: "regular request", path: "/user/123", userAgent: "curl/7.79.1", want: true},
+		{name: "health path", path: "/healthz", want: false},
+		{name: "ping path", path: "/ping", want: false},
+		{name: "kube probe", path: "/", userAgent: "kube-probe/1.25", want: false},
+		{name: "ELB health checker", path: "/status", userAgent: "ELB-HealthChecker/2.0", want: false},
+		{name: "health path prefix", path: "/healthz/details", want: true},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", testCase.path, nil)
+			r.Header.Set("User-Agent", testCase.userAgent)
+			assert.Equal(t, testCase.want, filter(r))
+		})
+	}
+}
@@ -0,0 +1,14 @@
+package otelchi
+
+import (
+	"net/http"
+	"time"
+
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// spanHooks are the callbacks given to WithSpanHooks.
+type spanHooks struct {
+	onStart func(span oteltrace.Span, r *http.Request)
+	onEnd   func(span oteltrace.Span, r *http.Request, status int, duration time.Duration)
+}
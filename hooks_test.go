@@ -0,0 +1,56 @@
+package otelchi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSDKIntegrationWithSpanHooks(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	var calls []string
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar",
+		WithTracerProvider(provider),
+		WithSpanHooks(func(span trace.Span, r *http.Request) {
+			calls = append(calls, "start")
+			assert.Equal(t, span.SpanContext(), trace.SpanContextFromContext(r.Context()))
+			span.SetAttributes(attribute.String("quota.account", "acme"))
+		}, func(span trace.Span, r *http.Request, status int, duration time.Duration) {
+			calls = append(calls, "end")
+			assert.Equal(t, http.StatusCreated, status)
+			assert.Positive(t, duration)
+			span.AddEvent("quota.charged")
+		}),
+		WithSpanHooks(nil, func(trace.Span, *http.Request, int, time.Duration) {
+			calls = append(calls, "second end")
+		}),
+	))
+	router.HandleFunc("/user/{id}", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "handler")
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/user/123", nil))
+
+	assert.Equal(t, []string{"start", "handler", "end", "second end"}, calls)
+	spans := sr.Ended()
+	require.Len(t, spans, 1)
+	assertSpan(t, spans[0], "/user/{id}", trace.SpanKindServer,
+		attribute.String("quota.account", "acme"),
+	)
+	require.Len(t, spans[0].Events(), 1)
+	assert.Equal(t, "quota.charged", spans[0].Events()[0].Name)
+}
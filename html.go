@@ -0,0 +1,72 @@
+package otelchi
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net/http"
+
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// traceparentMetaTag returns the meta tag conveying the span context to
+// browser agents, in the W3C traceparent format.
+func traceparentMetaTag(spanCtx oteltrace.SpanContext) []byte {
+	return []byte(fmt.Sprintf(`<meta name="traceparent" content="00-%s-%s-%s">`,
+		spanCtx.TraceID(), spanCtx.SpanID(), spanCtx.TraceFlags()))
+}
+
+// serverTimingTraceparent returns the Server-Timing metric conveying the
+// span context to browser agents, in the form they conventionally expect.
+func serverTimingTraceparent(spanCtx oteltrace.SpanContext) string {
+	return fmt.Sprintf(`traceparent;desc="00-%s-%s-%s"`, spanCtx.TraceID(), spanCtx.SpanID(), spanCtx.TraceFlags())
+}
+
+func isHTMLContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "text/html"
+}
+
+// prepareHTMLInjection is called before the response headers are sent. It
+// gives up the injection if the response is not plain HTML, e.g. if it is
+// compressed, and otherwise drops the Content-Length header which the
+// injection would falsify.
+func (rrw *recordingResponseWriter) prepareHTMLInjection(header http.Header) {
+	if rrw.htmlTag == nil {
+		return
+	}
+	if encoding := header.Get("Content-Encoding"); encoding != "" && encoding != "identity" {
+		rrw.htmlTag = nil
+		return
+	}
+	if !isHTMLContentType(header.Get("Content-Type")) {
+		rrw.htmlTag = nil
+		return
+	}
+	header.Del("Content-Length")
+}
+
+// injectHTML inserts the meta tag right after the opening head tag, if b
+// holds it, and returns the resulting chunk. Once the tag is inserted, or
+// the body is reached, nothing more is injected.
+func (rrw *recordingResponseWriter) injectHTML(b []byte) []byte {
+	lower := bytes.ToLower(b)
+	head := bytes.Index(lower, []byte("<head"))
+	if head < 0 || (len(lower) > head+5 && lower[head+5] != '>' && lower[head+5] != ' ') {
+		if bytes.Contains(lower, []byte("<body")) {
+			rrw.htmlTag = nil
+		}
+		return b
+	}
+	end := bytes.IndexByte(b[head:], '>')
+	if end < 0 {
+		return b
+	}
+	end += head + 1
+	injected := make([]byte, 0, len(b)+len(rrw.htmlTag))
+	injected = append(injected, b[:end]...)
+	injected = append(injected, rrw.htmlTag...)
+	injected = append(injected, b[end:]...)
+	rrw.htmlTag = nil
+	return injected
+}
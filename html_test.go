@@ -0,0 +1,94 @@
+package otelchi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSDKIntegrationWithHTMLTraceContext(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	const page = `<!DOCTYPE html><html><HEAD lang="en"><title>Home</title></HEAD><body></body></html>`
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar", WithTracerProvider(provider), WithHTMLTraceContext()))
+	router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Length", "84")
+		n, err := w.Write([]byte(page))
+		assert.NoError(t, err)
+		assert.Equal(t, len(page), n)
+	})
+	router.HandleFunc("/gzip", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Set("Content-Length", "6")
+		_, _ = w.Write([]byte("<head>"))
+	})
+	router.HandleFunc("/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"head": "<head>"}`))
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	require.Len(t, sr.Ended(), 1)
+	spanCtx := sr.Ended()[0].SpanContext()
+	tag := `<meta name="traceparent" content="00-` + spanCtx.TraceID().String() + "-" + spanCtx.SpanID().String() + `-01">`
+	assert.Equal(t, `<!DOCTYPE html><html><HEAD lang="en">`+tag+`<title>Home</title></HEAD><body></body></html>`, w.Body.String())
+	assert.Empty(t, w.Header().Get("Content-Length"))
+	assert.Contains(t, sr.Ended()[0].Attributes(), responseBodySizeKey.Int(len(page)))
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/gzip", nil))
+	assert.Equal(t, "<head>", w.Body.String())
+	assert.Equal(t, "6", w.Header().Get("Content-Length"))
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/json", nil))
+	assert.Equal(t, `{"head": "<head>"}`, w.Body.String())
+}
+
+func TestSDKIntegrationWithServerTiming(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar", WithTracerProvider(provider), WithServerTiming()))
+	router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Server-Timing", "db;dur=53")
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	require.Len(t, sr.Ended(), 1)
+	spanCtx := sr.Ended()[0].SpanContext()
+	assert.Equal(t, []string{
+		`traceparent;desc="00-` + spanCtx.TraceID().String() + "-" + spanCtx.SpanID().String() + `-01"`,
+		"db;dur=53",
+	}, w.Header().Values("Server-Timing"))
+}
+
+func TestSDKIntegrationWithServerTimingUnsampled(t *testing.T) {
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSampler(sdktrace.NeverSample()))
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar", WithTracerProvider(provider), WithServerTiming()))
+	router.HandleFunc("/", ok)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	// the browser is not told the trace is sampled
+	assert.Regexp(t, `^traceparent;desc="00-[0-9a-f]{32}-[0-9a-f]{16}-00"$`, w.Header().Get("Server-Timing"))
+}
@@ -0,0 +1,70 @@
+package otelchi
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	http2StreamIDKey            = attribute.Key("http2.stream.id")
+	http2PriorityUrgencyKey     = attribute.Key("http2.priority.urgency")
+	http2PriorityIncrementalKey = attribute.Key("http2.priority.incremental")
+)
+
+// defaultPriorityUrgency is the urgency of requests without a priority, as
+// defined by RFC 9218.
+const defaultPriorityUrgency = 3
+
+// http2Attributes returns the stream and priority attributes of HTTP/2 and
+// later requests. net/http does not expose the stream of a request, so the
+// stream ID is only recorded when streamID is set and knows it.
+func http2Attributes(r *http.Request, streamID func(r *http.Request) (uint32, bool)) []attribute.KeyValue {
+	if r.ProtoMajor < 2 {
+		return nil
+	}
+	var attrs []attribute.KeyValue
+	if streamID != nil {
+		if id, ok := streamID(r); ok {
+			attrs = append(attrs, http2StreamIDKey.Int64(int64(id)))
+		}
+	}
+	urgency, incremental := parsePriority(r.Header.Get("Priority"))
+	return append(attrs,
+		http2PriorityUrgencyKey.Int(urgency),
+		http2PriorityIncrementalKey.Bool(incremental),
+	)
+}
+
+// parsePriority parses an RFC 9218 Priority header field value, e.g.
+// "u=5, i". Invalid or unknown parameters are ignored.
+func parsePriority(value string) (urgency int, incremental bool) {
+	urgency = defaultPriorityUrgency
+	for _, param := range strings.Split(value, ",") {
+		param = strings.TrimSpace(param)
+		name, val := param, ""
+		if i := strings.IndexByte(param, '='); i >= 0 {
+			name, val = param[:i], param[i+1:]
+		}
+		// drop structured field parameters, e.g. "u=1;x=y"
+		if i := strings.IndexByte(val, ';'); i >= 0 {
+			val = val[:i]
+		}
+		switch name {
+		case "u":
+			if u, err := strconv.Atoi(val); err == nil && u >= 0 && u <= 7 {
+				urgency = u
+			}
+		case "i":
+			switch val {
+			case "", "?1":
+				incremental = true
+			case "?0":
+				incremental = false
+			}
+		}
+	}
+	return urgency, incremental
+}
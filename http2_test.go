@@ -0,0 +1,41 @@
+package otelchi
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestParsePriority(t *testing.T) {
+	testCases := []struct {
+		value       string
+		urgency     int
+		incremental bool
+	}{
+		{value: "", urgency: 3},
+		{value: "u=5, i", urgency: 5, incremental: true},
+		{value: "i=?0, u=1", urgency: 1},
+		{value: "u=9, i=?1", urgency: 3, incremental: true},
+	}
+	for _, testCase := range testCases {
+		urgency, incremental := parsePriority(testCase.value)
+		assert.Equal(t, testCase.urgency, urgency, testCase.value)
+		assert.Equal(t, testCase.incremental, incremental, testCase.value)
+	}
+}
+
+func TestHTTP2Attributes(t *testing.T) {
+	r := &http.Request{ProtoMajor: 1, Header: http.Header{}}
+	assert.Empty(t, http2Attributes(r, nil))
+
+	r.ProtoMajor = 2
+	r.Header.Set("Priority", "u=0")
+	streamID := func(*http.Request) (uint32, bool) { return 7, true }
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.Int64("http2.stream.id", 7),
+		attribute.Int("http2.priority.urgency", 0),
+		attribute.Bool("http2.priority.incremental", false),
+	}, http2Attributes(r, streamID))
+}
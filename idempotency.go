@@ -0,0 +1,45 @@
+package otelchi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	idempotencyAttemptKey   = attribute.Key("http.request.idempotency.attempt")
+	idempotencyDuplicateKey = attribute.Key("http.request.idempotency.duplicate")
+)
+
+// idempotencyTTL is how long an idempotency key is remembered.
+const idempotencyTTL = 24 * time.Hour
+
+// idempotencyDetector counts the requests carrying the same idempotency
+// key, to detect retries.
+type idempotencyDetector struct {
+	header string
+	store  StateStore
+}
+
+// attributes counts the request as an attempt of its idempotency key, if
+// it has one. The key is scoped by the method and path of the request.
+// Store errors are reported to the global OTel error handler.
+func (d *idempotencyDetector) attributes(ctx context.Context, r *http.Request) []attribute.KeyValue {
+	key := r.Header.Get(d.header)
+	if key == "" {
+		return nil
+	}
+	attempt, err := d.store.Incr(ctx, "otelchi:idempotency:"+r.Method+" "+r.URL.Path+" "+key, idempotencyTTL)
+	if err != nil {
+		otel.Handle(fmt.Errorf("otelchi: idempotency detection: %w", err))
+		return nil
+	}
+	return []attribute.KeyValue{
+		idempotencyAttemptKey.Int64(attempt),
+		idempotencyDuplicateKey.Bool(attempt > 1),
+	}
+}
@@ -0,0 +1,44 @@
+package otelchi
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSDKIntegrationWithIdempotencyDetection(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar",
+		WithTracerProvider(provider),
+		WithStateStore(NewMemoryStateStore(10)),
+		WithIdempotencyDetection(""),
+	))
+	router.HandleFunc("/payments", ok)
+
+	for i := 0; i < 2; i++ {
+		r := httptest.NewRequest("POST", "/payments", nil)
+		r.Header.Set("Idempotency-Key", "8e03978e")
+		router.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	spans := sr.Ended()
+	require.Len(t, spans, 2)
+	assertSpan(t, spans[0], "/payments", trace.SpanKindServer,
+		attribute.Int64("http.request.idempotency.attempt", 1),
+		attribute.Bool("http.request.idempotency.duplicate", false),
+	)
+	assertSpan(t, spans[1], "/payments", trace.SpanKindServer,
+		attribute.Int64("http.request.idempotency.attempt", 2),
+		attribute.Bool("http.request.idempotency.duplicate", true),
+	)
+}
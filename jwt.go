@@ -0,0 +1,90 @@
+package otelchi
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+)
+
+// JWTVerifier verifies a JWT and returns its claims. It returns an error
+// when the token is not valid.
+type JWTVerifier func(token string) (map[string]interface{}, error)
+
+var errMalformedJWT = errors.New("otelchi: malformed JWT")
+
+// decodeJWTClaims decodes the claims of a JWT without verifying its
+// signature.
+func decodeJWTClaims(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errMalformedJWT
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, errMalformedJWT
+	}
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, errMalformedJWT
+	}
+	return claims, nil
+}
+
+// jwtEnduserAttributes returns the enduser attributes derived from the
+// claims of the request's Bearer token: enduser.id from "sub",
+// enduser.role from "role" or "roles" and enduser.scope from "scope" or
+// "scp". Nothing is returned when there is no token or verify rejects it.
+func jwtEnduserAttributes(r *http.Request, verify JWTVerifier) []attribute.KeyValue {
+	auth := r.Header.Get("Authorization")
+	if len(auth) < len("Bearer ") || !strings.EqualFold(auth[:len("Bearer ")], "Bearer ") {
+		return nil
+	}
+	if verify == nil {
+		verify = decodeJWTClaims
+	}
+	claims, err := verify(strings.TrimSpace(auth[len("Bearer "):]))
+	if err != nil {
+		return nil
+	}
+
+	var attrs []attribute.KeyValue
+	if sub, ok := claims["sub"].(string); ok && sub != "" {
+		attrs = append(attrs, semconv.EnduserIDKey.String(sub))
+	}
+	if role := claimValue(claims, ",", "role", "roles"); role != "" {
+		attrs = append(attrs, semconv.EnduserRoleKey.String(role))
+	}
+	if scope := claimValue(claims, " ", "scope", "scp"); scope != "" {
+		attrs = append(attrs, semconv.EnduserScopeKey.String(scope))
+	}
+	return attrs
+}
+
+// claimValue returns the first of the named claims that is set, joining
+// list claims with sep.
+func claimValue(claims map[string]interface{}, sep string, names ...string) string {
+	for _, name := range names {
+		switch v := claims[name].(type) {
+		case string:
+			if v != "" {
+				return v
+			}
+		case []interface{}:
+			values := make([]string, 0, len(v))
+			for _, item := range v {
+				if s, ok := item.(string); ok {
+					values = append(values, s)
+				}
+			}
+			if len(values) > 0 {
+				return strings.Join(values, sep)
+			}
+		}
+	}
+	return ""
+}
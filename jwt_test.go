@@ -0,0 +1,43 @@
+package otelchi
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func testJWT(claims string) string {
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString([]byte(`{"alg":"none"}`)) + "." + enc.EncodeToString([]byte(claims)) + ".sig"
+}
+
+func TestJWTEnduserAttributes(t *testing.T) {
+	r := &http.Request{Header: http.Header{}}
+	assert.Empty(t, jwtEnduserAttributes(r, nil))
+
+	r.Header.Set("Authorization", "Bearer "+testJWT(`{"sub":"user-1","roles":["admin","dev"],"scope":"read write"}`))
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String("enduser.id", "user-1"),
+		attribute.String("enduser.role", "admin,dev"),
+		attribute.String("enduser.scope", "read write"),
+	}, jwtEnduserAttributes(r, nil))
+
+	r.Header.Set("Authorization", "bearer "+testJWT(`{"sub":"user-2","scp":["orders:read"]}`))
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String("enduser.id", "user-2"),
+		attribute.String("enduser.scope", "orders:read"),
+	}, jwtEnduserAttributes(r, nil))
+
+	reject := func(string) (map[string]interface{}, error) { return nil, errors.New("bad signature") }
+	assert.Empty(t, jwtEnduserAttributes(r, reject))
+
+	r.Header.Set("Authorization", "Bearer not-a-jwt")
+	assert.Empty(t, jwtEnduserAttributes(r, nil))
+
+	r.Header.Set("Authorization", "Basic dXNlcjpwYXNz")
+	assert.Empty(t, jwtEnduserAttributes(r, nil))
+}
@@ -0,0 +1,52 @@
+package otelchi
+
+// AttributeKeys are the keys of the attributes recording the captured
+// payloads, for WithAttributeKeys. The keys derived from them, like the
+// <key>.sha256 and <key>.ref attributes of deduplicated bodies, follow
+// them.
+type AttributeKeys struct {
+	// RequestBody is the key of the request body, http.request.body by
+	// default.
+	RequestBody string
+	// ResponseBody is the key of the response body, http.response.body by
+	// default.
+	ResponseBody string
+	// RequestHeaders is the key of the JSON object holding the request
+	// headers, http.request.headers by default. It is not used with
+	// WithHeaderAttributes, which records the headers in the
+	// http.request.header.<name> attributes of the semantic conventions.
+	RequestHeaders string
+}
+
+// DefaultAttributeKeys returns the keys used when none are given to
+// WithAttributeKeys.
+func DefaultAttributeKeys() AttributeKeys {
+	return AttributeKeys{
+		RequestBody:    "http.request.body",
+		ResponseBody:   "http.response.body",
+		RequestHeaders: string(requestHeadersKey),
+	}
+}
+
+// newAttributeKeys returns the keys configured by cfg, the default ones
+// filling in the keys not set, prefixed by the namespace.
+func newAttributeKeys(cfg config) AttributeKeys {
+	keys := DefaultAttributeKeys()
+	if cfg.AttributeKeys != nil {
+		if cfg.AttributeKeys.RequestBody != "" {
+			keys.RequestBody = cfg.AttributeKeys.RequestBody
+		}
+		if cfg.AttributeKeys.ResponseBody != "" {
+			keys.ResponseBody = cfg.AttributeKeys.ResponseBody
+		}
+		if cfg.AttributeKeys.RequestHeaders != "" {
+			keys.RequestHeaders = cfg.AttributeKeys.RequestHeaders
+		}
+	}
+	if cfg.AttributeNamespace != "" {
+		keys.RequestBody = cfg.AttributeNamespace + keys.RequestBody
+		keys.ResponseBody = cfg.AttributeNamespace + keys.ResponseBody
+		keys.RequestHeaders = cfg.AttributeNamespace + keys.RequestHeaders
+	}
+	return keys
+}
@@ -0,0 +1,51 @@
+package otelchi
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSDKIntegrationWithAttributeKeys(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar",
+		WithTracerProvider(provider),
+		WithAttributeKeys(AttributeKeys{RequestBody: "payload.request", RequestHeaders: "payload.headers"}),
+		WithAttributeNamespace("acme."),
+	))
+	router.HandleFunc("/user/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		_, _ = w.Write([]byte("done"))
+	})
+
+	r := httptest.NewRequest("PUT", "/user/123", strings.NewReader("valid"))
+	r.Header.Set("Accept", "text/plain")
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	spans := sr.Ended()
+	require.Len(t, spans, 1)
+	assertSpan(t, spans[0], "/user/{id}", trace.SpanKindServer,
+		attribute.String("acme.payload.request", "valid"),
+		attribute.String("acme.http.response.body", "done"),
+		attribute.String("acme.payload.headers", `{"Accept":["text/plain"]}`),
+	)
+	for _, a := range spans[0].Attributes() {
+		assert.NotEqual(t, attribute.Key("http.request.body"), a.Key)
+		assert.NotEqual(t, attribute.Key("http.response.body"), a.Key)
+		assert.NotEqual(t, attribute.Key("http.request.headers"), a.Key)
+	}
+}
@@ -0,0 +1,36 @@
+package otelchi
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+const linkHeaderKey = attribute.Key("otelchi.link.header")
+
+// headerLink links the spans of the requests to the span context carried
+// by a correlation header.
+type headerLink struct {
+	header string
+	parser func(value string) oteltrace.SpanContext
+}
+
+// headerLinks returns the links to the valid span contexts of the values
+// of the correlation headers of r.
+func headerLinks(links []headerLink, r *http.Request) []oteltrace.Link {
+	var spanLinks []oteltrace.Link
+	for _, link := range links {
+		for _, value := range r.Header.Values(link.header) {
+			spanCtx := link.parser(value)
+			if !spanCtx.IsValid() {
+				continue
+			}
+			spanLinks = append(spanLinks, oteltrace.Link{
+				SpanContext: spanCtx,
+				Attributes:  []attribute.KeyValue{linkHeaderKey.String(link.header)},
+			})
+		}
+	}
+	return spanLinks
+}
@@ -0,0 +1,51 @@
+package otelchi
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// parseTraceparent parses a W3C traceparent header value.
+func parseTraceparent(value string) trace.SpanContext {
+	ctx := propagation.TraceContext{}.Extract(context.Background(), propagation.MapCarrier{"traceparent": value})
+	return trace.SpanContextFromContext(ctx)
+}
+
+func TestSDKIntegrationWithLinkFromHeader(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar",
+		WithTracerProvider(provider),
+		WithLinkFromHeader("X-Message-Traceparent", parseTraceparent),
+	))
+	router.HandleFunc("/user/{id}", ok)
+
+	r := httptest.NewRequest("GET", "/user/123", nil)
+	r.Header.Add("X-Message-Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.Header.Add("X-Message-Traceparent", "invalid")
+	r.Header.Add("X-Message-Traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-00")
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	spans := sr.Ended()
+	require.Len(t, spans, 1)
+	links := spans[0].Links()
+	require.Len(t, links, 2)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", links[0].SpanContext.TraceID().String())
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", links[1].SpanContext.TraceID().String())
+	assert.Equal(t, []attribute.KeyValue{attribute.String("otelchi.link.header", "X-Message-Traceparent")}, links[0].Attributes)
+	// the links do not change the parent of the span
+	assert.False(t, spans[0].Parent().IsValid())
+}
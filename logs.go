@@ -0,0 +1,24 @@
+package otelchi
+
+import (
+	"context"
+)
+
+// Keys of the fields correlating log records with the request being
+// served, used by LogHandler and the logger adapters.
+const (
+	LogTraceIDKey    = "trace_id"
+	LogSpanIDKey     = "span_id"
+	LogRouteKey      = "http.route"
+	LogServerNameKey = "http.server_name"
+)
+
+// ServerNameFromContext returns the server name of the middleware serving
+// the request, if any.
+func ServerNameFromContext(ctx context.Context) (string, bool) {
+	owner, ok := ctx.Value(spanOwnerKey{}).(*spanOwner)
+	if !ok {
+		return "", false
+	}
+	return owner.serverName, true
+}
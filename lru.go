@@ -0,0 +1,58 @@
+package otelchi
+
+import "container/list"
+
+// lru is a map bounded to its capacity, evicting the least recently used
+// keys beyond it. It is not safe for concurrent use.
+type lru struct {
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List // most recently used first
+}
+
+type lruEntry struct {
+	key   string
+	value interface{}
+}
+
+func newLRU(capacity int) *lru {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &lru{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// get returns the value of key, marking it as recently used.
+func (c *lru) get(key string) (interface{}, bool) {
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*lruEntry).value, true
+}
+
+// set sets the value of key, marking it as recently used, and evicts the
+// least recently used key beyond the capacity.
+func (c *lru) set(key string, value interface{}) {
+	if elem, ok := c.entries[key]; ok {
+		elem.Value.(*lruEntry).value = value
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&lruEntry{key: key, value: value})
+	for c.order.Len() > c.capacity {
+		c.remove(c.order.Back().Value.(*lruEntry).key)
+	}
+}
+
+func (c *lru) remove(key string) {
+	if elem, ok := c.entries[key]; ok {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+	}
+}
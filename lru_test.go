@@ -0,0 +1,34 @@
+package otelchi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRU(t *testing.T) {
+	c := newLRU(2)
+	c.set("a", 1)
+	c.set("b", 2)
+	_, _ = c.get("a")
+	c.set("c", 3)
+
+	value, ok := c.get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+	_, ok = c.get("b")
+	assert.False(t, ok)
+
+	// updating "c" makes "a" the least recently used key
+	c.set("c", 4)
+	c.set("d", 5)
+	_, ok = c.get("a")
+	assert.False(t, ok)
+	value, _ = c.get("c")
+	assert.Equal(t, 4, value)
+
+	c.remove("c")
+	_, ok = c.get("c")
+	assert.False(t, ok)
+	assert.Equal(t, 1, c.order.Len())
+}
@@ -0,0 +1,202 @@
+package otelchi
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/instrument"
+	"go.opentelemetry.io/otel/metric/instrument/syncfloat64"
+	"go.opentelemetry.io/otel/metric/instrument/syncint64"
+	"go.opentelemetry.io/otel/metric/unit"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+
+	otelcontrib "go.opentelemetry.io/contrib"
+)
+
+// metricNames are the names of the instruments of the middleware.
+type metricNames struct {
+	activeRequests string
+	duration       string
+	durationUnit   unit.Unit
+	slowRequests   string
+	collapsedNames string
+}
+
+var otelMetricNames = metricNames{
+	activeRequests: "http.server.active_requests",
+	duration:       "http.server.duration",
+	durationUnit:   unit.Milliseconds,
+	slowRequests:   "http.server.slow_requests",
+	collapsedNames: "otelchi.span_names.collapsed",
+}
+
+// prometheusMetricNames and the labels below follow the conventions of
+// promhttp.
+var prometheusMetricNames = metricNames{
+	activeRequests: "http_server_active_requests",
+	duration:       "http_server_request_duration_seconds",
+	durationUnit:   "s",
+	slowRequests:   "http_server_slow_requests_total",
+	collapsedNames: "otelchi_span_names_collapsed_total",
+}
+
+const (
+	prometheusMethodKey = attribute.Key("method")
+	prometheusRouteKey  = attribute.Key("route")
+	prometheusCodeKey   = attribute.Key("code")
+)
+
+// serverMetrics holds the instruments recording the requests served by the
+// middleware.
+type serverMetrics struct {
+	activeRequests syncint64.UpDownCounter
+	duration       syncfloat64.Histogram
+	slowRequests   syncint64.Counter
+	collapsedNames syncint64.Counter
+
+	// the Prometheus-style instruments, if enabled
+	prometheus *serverMetrics
+}
+
+func newServerMetrics(provider metric.MeterProvider, prometheusNames bool) *serverMetrics {
+	meter := provider.Meter(
+		tracerName,
+		metric.WithInstrumentationVersion(otelcontrib.SemVersion()),
+		metric.WithSchemaURL(semconv.SchemaURL),
+	)
+	m, err := newInstruments(meter, otelMetricNames)
+	if err != nil {
+		otel.Handle(err)
+		return nil
+	}
+	if prometheusNames {
+		if m.prometheus, err = newInstruments(meter, prometheusMetricNames); err != nil {
+			otel.Handle(err)
+		}
+	}
+	return m
+}
+
+func newInstruments(meter metric.Meter, names metricNames) (*serverMetrics, error) {
+	activeRequests, err := meter.SyncInt64().UpDownCounter(
+		names.activeRequests,
+		instrument.WithUnit(unit.Dimensionless),
+		instrument.WithDescription("Number of requests being served."),
+	)
+	if err != nil {
+		return nil, err
+	}
+	duration, err := meter.SyncFloat64().Histogram(
+		names.duration,
+		instrument.WithUnit(names.durationUnit),
+		instrument.WithDescription("Duration of the requests served."),
+	)
+	if err != nil {
+		return nil, err
+	}
+	slowRequests, err := meter.SyncInt64().Counter(
+		names.slowRequests,
+		instrument.WithUnit(unit.Dimensionless),
+		instrument.WithDescription("Number of requests served slower than the slow request threshold."),
+	)
+	if err != nil {
+		return nil, err
+	}
+	collapsedNames, err := meter.SyncInt64().Counter(
+		names.collapsedNames,
+		instrument.WithUnit(unit.Dimensionless),
+		instrument.WithDescription("Number of span names collapsed by the span name cardinality limit."),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &serverMetrics{
+		activeRequests: activeRequests,
+		duration:       duration,
+		slowRequests:   slowRequests,
+		collapsedNames: collapsedNames,
+	}, nil
+}
+
+// requestMetricAttributes returns the attributes the metrics of a request
+// are dimensioned by. Unknown methods are replaced by _OTHER, so that
+// clients cannot create unbounded series.
+func requestMetricAttributes(method, routePattern string) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{semconv.HTTPMethodKey.String(boundedMethod(method))}
+	if routePattern != "" {
+		attrs = append(attrs, semconv.HTTPRouteKey.String(routePattern))
+	}
+	return attrs
+}
+
+// prometheusMetricAttributes returns the labels of the Prometheus-style
+// metrics of a request. Unlike the attributes, labels are always set, so
+// that all the series of a metric have the same labels. Unknown methods are
+// replaced by _OTHER, as for the attributes.
+func prometheusMetricAttributes(method, routePattern string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		prometheusMethodKey.String(boundedMethod(method)),
+		prometheusRouteKey.String(routePattern),
+	}
+}
+
+// requestStarted counts the request as active, and returns the func to
+// call once it is served. The route is only known when the request was
+// matched before being served.
+func (m *serverMetrics) requestStarted(ctx context.Context, method, routePattern string) (done func()) {
+	attrs := requestMetricAttributes(method, routePattern)
+	m.activeRequests.Add(ctx, 1, attrs...)
+	var labels []attribute.KeyValue
+	if m.prometheus != nil {
+		labels = prometheusMetricAttributes(method, routePattern)
+		m.prometheus.activeRequests.Add(ctx, 1, labels...)
+	}
+	return func() {
+		m.activeRequests.Add(ctx, -1, attrs...)
+		if m.prometheus != nil {
+			m.prometheus.activeRequests.Add(ctx, -1, labels...)
+		}
+	}
+}
+
+// requestServed records the duration of a request served in elapsed, by
+// method, the route the request was routed to, and response status code.
+func (m *serverMetrics) requestServed(ctx context.Context, elapsed time.Duration, method, routePattern string, status int) {
+	attrs := requestMetricAttributes(method, routePattern)
+	if status != 0 {
+		attrs = append(attrs, semconv.HTTPStatusCodeKey.Int(status))
+	}
+	m.duration.Record(ctx, float64(elapsed)/float64(time.Millisecond), attrs...)
+	if m.prometheus != nil {
+		if status == 0 {
+			// like promhttp, count responses never written as sent by
+			// net/http
+			status = http.StatusOK
+		}
+		labels := append(prometheusMetricAttributes(method, routePattern), prometheusCodeKey.String(strconv.Itoa(status)))
+		m.prometheus.duration.Record(ctx, elapsed.Seconds(), labels...)
+	}
+}
+
+// slowRequest counts a request served slower than the slow request
+// threshold.
+func (m *serverMetrics) slowRequest(ctx context.Context, method, routePattern string) {
+	m.slowRequests.Add(ctx, 1, requestMetricAttributes(method, routePattern)...)
+	if m.prometheus != nil {
+		m.prometheus.slowRequests.Add(ctx, 1, prometheusMetricAttributes(method, routePattern)...)
+	}
+}
+
+// spanNameCollapsed counts a span name collapsed by the span name
+// cardinality limit.
+func (m *serverMetrics) spanNameCollapsed(ctx context.Context, method string) {
+	m.collapsedNames.Add(ctx, 1, semconv.HTTPMethodKey.String(method))
+	if m.prometheus != nil {
+		m.prometheus.collapsedNames.Add(ctx, 1, prometheusMethodKey.String(method))
+	}
+}
@@ -0,0 +1,185 @@
+//go:build go1.18
+// +build go1.18
+
+package otelchi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// collectMetric returns the data of the named metric collected by reader.
+func collectMetric(t *testing.T, reader sdkmetric.Reader, name string) metricdata.Aggregation {
+	rm, err := reader.Collect(context.Background())
+	require.NoError(t, err)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == name {
+				return m.Data
+			}
+		}
+	}
+	t.Fatalf("metric %s not collected", name)
+	return nil
+}
+
+func TestActiveRequests(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar", WithMeterProvider(provider), WithChiRoutes(router)))
+	router.HandleFunc("/user/{id}", func(w http.ResponseWriter, r *http.Request) {
+		sum := collectMetric(t, reader, "http.server.active_requests").(metricdata.Sum[int64])
+		require.Len(t, sum.DataPoints, 1)
+		assert.Equal(t, int64(1), sum.DataPoints[0].Value)
+		assert.Equal(t, attribute.NewSet(
+			attribute.String("http.method", "GET"),
+			attribute.String("http.route", "/user/{id}"),
+		), sum.DataPoints[0].Attributes)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/user/123", nil))
+
+	sum := collectMetric(t, reader, "http.server.active_requests").(metricdata.Sum[int64])
+	require.Len(t, sum.DataPoints, 1)
+	assert.Equal(t, int64(0), sum.DataPoints[0].Value)
+	assert.False(t, sum.IsMonotonic)
+}
+
+func TestDurationView(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(reader),
+		sdkmetric.WithView(DurationView(0.5, 1, 10)),
+	)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar", WithMeterProvider(provider)))
+	router.HandleFunc("/user/{id}", ok)
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/user/123", nil))
+
+	histogram := collectMetric(t, reader, "http.server.duration").(metricdata.Histogram)
+	require.Len(t, histogram.DataPoints, 1)
+	point := histogram.DataPoints[0]
+	assert.Equal(t, []float64{0.5, 1, 10}, point.Bounds)
+	assert.Equal(t, uint64(1), point.Count)
+	assert.Equal(t, attribute.NewSet(
+		attribute.String("http.method", "GET"),
+		attribute.String("http.route", "/user/{id}"),
+		attribute.Int("http.status_code", http.StatusOK),
+	), point.Attributes)
+}
+
+func TestPrometheusMetricNames(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar", WithMeterProvider(provider), WithPrometheusMetricNames()))
+	router.HandleFunc("/user/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/user/123", nil))
+
+	histogram := collectMetric(t, reader, "http_server_request_duration_seconds").(metricdata.Histogram)
+	require.Len(t, histogram.DataPoints, 1)
+	assert.Equal(t, uint64(1), histogram.DataPoints[0].Count)
+	assert.Equal(t, attribute.NewSet(
+		attribute.String("method", "GET"),
+		attribute.String("route", "/user/{id}"),
+		attribute.String("code", "404"),
+	), histogram.DataPoints[0].Attributes)
+
+	sum := collectMetric(t, reader, "http_server_active_requests").(metricdata.Sum[int64])
+	require.Len(t, sum.DataPoints, 1)
+	assert.Equal(t, int64(0), sum.DataPoints[0].Value)
+
+	// the OpenTelemetry names are kept
+	histogram = collectMetric(t, reader, "http.server.duration").(metricdata.Histogram)
+	assert.Len(t, histogram.DataPoints, 1)
+}
+
+func TestSlowRequestsCounter(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar",
+		WithMeterProvider(provider),
+		WithTracerProvider(trace.NewNoopTracerProvider()),
+		WithSlowRequestThreshold(time.Millisecond),
+	))
+	router.HandleFunc("/user/{id}", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/user/123", nil))
+
+	// counted for unsampled spans too
+	sum := collectMetric(t, reader, "http.server.slow_requests").(metricdata.Sum[int64])
+	require.Len(t, sum.DataPoints, 1)
+	assert.Equal(t, int64(1), sum.DataPoints[0].Value)
+	assert.Equal(t, attribute.NewSet(
+		attribute.String("http.method", "GET"),
+		attribute.String("http.route", "/user/{id}"),
+	), sum.DataPoints[0].Attributes)
+}
+
+func TestUnknownMethodMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar", WithMeterProvider(provider), WithPrometheusMetricNames()))
+	router.HandleFunc("/user/{id}", ok)
+
+	for _, method := range []string{"X-RANDOM-1", "X-RANDOM-2"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/user/123", nil))
+	}
+
+	histogram := collectMetric(t, reader, "http.server.duration").(metricdata.Histogram)
+	require.Len(t, histogram.DataPoints, 1)
+	assert.Equal(t, uint64(2), histogram.DataPoints[0].Count)
+	method, _ := histogram.DataPoints[0].Attributes.Value("http.method")
+	assert.Equal(t, "_OTHER", method.AsString())
+
+	histogram = collectMetric(t, reader, "http_server_request_duration_seconds").(metricdata.Histogram)
+	require.Len(t, histogram.DataPoints, 1)
+	method, _ = histogram.DataPoints[0].Attributes.Value("method")
+	assert.Equal(t, "_OTHER", method.AsString())
+}
+
+func TestRecoveredPanicMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	router := NewRouter("foobar", WithMeterProvider(provider), WithPanicRecovery())
+	router.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/panic", nil))
+
+	histogram := collectMetric(t, reader, "http.server.duration").(metricdata.Histogram)
+	require.Len(t, histogram.DataPoints, 1)
+	assert.Equal(t, attribute.NewSet(
+		attribute.String("http.method", "GET"),
+		attribute.String("http.route", "/panic"),
+		attribute.Int("http.status_code", http.StatusInternalServerError),
+	), histogram.DataPoints[0].Attributes)
+}
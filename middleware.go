@@ -1,18 +1,23 @@
 package otelchi
 
 import (
-	"encoding/json"
+	"bufio"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"os"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/felixge/httpsnoop"
 	"github.com/go-chi/chi/v5"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric/global"
 	"go.opentelemetry.io/otel/propagation"
 
 	datautils "github.com/helios/go-sdk/data-utils"
@@ -23,6 +28,14 @@ import (
 
 const (
 	tracerName = "github.com/helios/otelchi"
+
+	// CaptureSchemaVersion is the version of the format of the payload and
+	// header attributes recorded by the middleware. It is stamped on every
+	// span as the helios.capture.schema_version attribute and is bumped
+	// whenever that format changes.
+	CaptureSchemaVersion = "1"
+
+	captureSchemaVersionKey = attribute.Key("helios.capture.schema_version")
 )
 
 type bodyWrapper struct {
@@ -32,17 +45,27 @@ type bodyWrapper struct {
 	err          error
 	requestBody  []byte
 	metadataOnly bool
-	contentType string
+	contentType  string
+	cancel       *cancellationTracker
+	// ended is when the end of the body was reached
+	ended time.Time
+	clock func() time.Time
 }
 
 func (w *bodyWrapper) Read(b []byte) (int, error) {
 	n, err := w.ReadCloser.Read(b)
-	if n > 0 && !w.metadataOnly{
+	if err != io.EOF {
+		w.cancel.observe(stageReadingBody, err)
+	}
+	if n > 0 && !w.metadataOnly {
 		shouldSkipContentByType, _ := datautils.ShouldSkipContentCollectionByContentType(w.contentType)
 		if !shouldSkipContentByType {
 			w.requestBody = append(w.requestBody, b[0:n]...)
 		}
 	}
+	if err == io.EOF && w.err != io.EOF {
+		w.ended = now(w.clock)
+	}
 	n1 := int64(n)
 	w.read += n1
 	w.err = err
@@ -57,51 +80,339 @@ func (w *bodyWrapper) Close() error {
 // requests. The serverName parameter should describe the name of the
 // (virtual) server handling the request.
 func Middleware(serverName string, opts ...Option) func(next http.Handler) http.Handler {
-	cfg := config{}
-	for _, opt := range opts {
-		opt.apply(&cfg)
+	return newMiddleware(serverName, newConfig(opts), opts)
+}
+
+// newMiddleware returns the middleware configured by cfg, the configuration
+// set by opts.
+func newMiddleware(serverName string, cfg config, opts []Option) func(next http.Handler) http.Handler {
+	tw := newTraceware(serverName, cfg)
+	overrides := newRouteOverrides(serverName, opts, cfg.RouteOptions, tw)
+	return func(handler http.Handler) http.Handler {
+		tw := tw
+		tw.handler = handler
+		tw.routeOverrides = overrides.withHandler(handler)
+		return tw
 	}
+}
+
+// newTraceware returns the traceware configured by cfg, without handler.
+func newTraceware(serverName string, cfg config) traceware {
 	if cfg.TracerProvider == nil {
 		cfg.TracerProvider = otel.GetTracerProvider()
 	}
 	tracer := cfg.TracerProvider.Tracer(
 		tracerName,
 		oteltrace.WithInstrumentationVersion(otelcontrib.SemVersion()),
+		oteltrace.WithSchemaURL(semconv.SchemaURL),
 	)
+	var secondaryTracer oteltrace.Tracer
+	if cfg.SecondaryTracerProvider != nil {
+		secondaryTracer = cfg.SecondaryTracerProvider.Tracer(
+			tracerName,
+			oteltrace.WithInstrumentationVersion(otelcontrib.SemVersion()),
+			oteltrace.WithSchemaURL(semconv.SchemaURL),
+		)
+	}
 	if cfg.Propagators == nil {
 		cfg.Propagators = otel.GetTextMapPropagator()
 	}
-	return func(handler http.Handler) http.Handler {
-		return traceware{
-			serverName:          serverName,
-			tracer:              tracer,
-			propagators:         cfg.Propagators,
-			handler:             handler,
-			chiRoutes:           cfg.ChiRoutes,
-			reqMethodInSpanName: cfg.RequestMethodInSpanName,
-			metadataOnly:        os.Getenv("HS_METADATA_ONLY") == "true",
-			filter:              cfg.Filter,
+	if cfg.MeterProvider == nil {
+		cfg.MeterProvider = global.MeterProvider()
+	}
+	bodyExtractors := parseBodyExtractors(cfg.BodyAttributeExtractors)
+	var xmlCapture *xmlCapture
+	if cfg.XMLCapture {
+		xmlCapture = newXMLCapture(cfg.XMLExtractors, cfg.XMLRedactedElements)
+	}
+	var eventLimits *eventLimits
+	if cfg.EventLimits {
+		eventLimits = newEventLimits(cfg.DefaultEventLimit, cfg.RouteEventLimits)
+	}
+	var attributeLimit *valueLimit
+	if cfg.AttributeValueLimit > 0 {
+		attributeLimit = &valueLimit{limit: cfg.AttributeValueLimit, companion: cfg.TruncatedAttributes}
+	}
+	var offload *payloadOffload
+	if cfg.PayloadStore != nil {
+		offload = &payloadOffload{store: cfg.PayloadStore, threshold: cfg.PayloadInlineLimit}
+	}
+	var quarantine *quarantine
+	if cfg.QuarantineSink != nil {
+		quarantine = newQuarantine(cfg.QuarantineSink, cfg.QuarantineSampleEvery)
+	}
+	var clientAddress *clientAddressResolver
+	if len(cfg.TrustedProxies) > 0 || cfg.RealIP {
+		clientAddress = newClientAddressResolver(cfg.TrustedProxies, cfg.RealIP)
+	}
+	var graphqlRoutes map[string]bool
+	if len(cfg.GraphQLRoutes) > 0 {
+		graphqlRoutes = make(map[string]bool, len(cfg.GraphQLRoutes))
+		for _, pattern := range cfg.GraphQLRoutes {
+			graphqlRoutes[pattern] = true
+		}
+	}
+	var captureLimiter *captureLimiter
+	if cfg.CaptureRateLimit > 0 {
+		// only a store set with WithStateStore may be shared by replicas
+		captureLimiter = newCaptureLimiter(cfg.CaptureRateLimit, cfg.StateStore)
+	}
+	if cfg.StateStore == nil && (cfg.IdempotencyHeader != "" || cfg.BodyDedup) {
+		cfg.StateStore = NewMemoryStateStore(defaultStateStoreCapacity)
+	}
+	var dedup *bodyDedup
+	if cfg.BodyDedup {
+		dedup = &bodyDedup{store: cfg.StateStore, window: cfg.BodyDedupWindow}
+	}
+	var idempotency *idempotencyDetector
+	if cfg.IdempotencyHeader != "" {
+		idempotency = &idempotencyDetector{header: cfg.IdempotencyHeader, store: cfg.StateStore}
+	}
+	var enrichment *asyncEnrichment
+	if cfg.AsyncEnricher != nil {
+		deadline := cfg.AsyncEnrichmentDeadline
+		if deadline <= 0 {
+			deadline = defaultEnrichmentDeadline
 		}
+		enrichment = newAsyncEnrichment(cfg.AsyncEnricher, deadline)
+	}
+	var debug *debugHeader
+	if cfg.DebugHeader != "" {
+		debug = &debugHeader{header: cfg.DebugHeader, secret: cfg.DebugSecret}
 	}
+	var routeSampling *routeSampling
+	if len(cfg.RouteSampleRatios) > 0 {
+		routeSampling = newRouteSampling(cfg.RouteSampleRatios)
+	}
+	spanKind := oteltrace.SpanKindServer
+	if cfg.SpanKind != oteltrace.SpanKindUnspecified {
+		spanKind = cfg.SpanKind
+	}
+	chiRoutes := cfg.ChiRoutes
+	if cfg.LazyRouteNaming && !cfg.RouteSamplerHint {
+		// the span is named once the request is routed
+		chiRoutes = nil
+	}
+	unmatchedSpanNames := defaultUnmatchedSpanNames
+	if cfg.UnmatchedSpanNames != nil {
+		unmatchedSpanNames = cfg.UnmatchedSpanNames
+	}
+	var spanNameGuard *spanNameGuard
+	if cfg.SpanNameLimit > 0 {
+		spanNameGuard = newSpanNameGuard(cfg.SpanNameLimit)
+	}
+	var stackTraces *stackTraces
+	if cfg.StackTraces {
+		stackTraces = newStackTraces(cfg.StackTraceDepth, cfg.StackTraceSkips)
+	}
+	var routeCache *routeCache
+	if chiRoutes != nil && cfg.RouteCacheSize > 0 {
+		routeCache = newRouteCache(cfg.RouteCacheSize)
+	}
+	metadataOnly := os.Getenv("HS_METADATA_ONLY") == "true"
+	if cfg.MetadataOnly != nil {
+		metadataOnly = *cfg.MetadataOnly
+	}
+	captureRatio := 1.0
+	if cfg.BodyCaptureRatio != nil {
+		captureRatio = *cfg.BodyCaptureRatio
+	}
+	return traceware{
+		serverName:          serverName,
+		tracer:              tracer,
+		propagators:         cfg.Propagators,
+		chiRoutes:           chiRoutes,
+		reqMethodInSpanName: cfg.RequestMethodInSpanName,
+		metadataOnly:        cfg.MinimalAttributes || metadataOnly,
+		filters:             cfg.Filters,
+		priority:            cfg.PriorityPropagation,
+		priorityHeader:      cfg.PriorityHeader,
+		bodyExtractors:      bodyExtractors,
+		cachePolicies:       cfg.CacheControlPolicies,
+		graphqlRoutes:       graphqlRoutes,
+		xmlCapture:          xmlCapture,
+		enabledFunc:         cfg.EnabledFunc,
+		routeHeader:         cfg.RouteHeader,
+		headerCapture:       newHeaderCapture(cfg),
+		eventLimits:         eventLimits,
+		pathNormalizer:      cfg.PathNormalizer,
+		minimalAttributes:   cfg.MinimalAttributes,
+		http2Attributes:     cfg.HTTP2Attributes,
+		http2StreamID:       cfg.HTTP2StreamID,
+		jwtEnduser:          cfg.JWTEnduser,
+		jwtVerifier:         cfg.JWTVerifier,
+		clientAddress:       clientAddress,
+		captureBuffer:       cfg.CaptureBuffer,
+		operationNames:      cfg.OperationNames,
+		quarantine:          quarantine,
+		htmlTraceContext:    cfg.HTMLTraceContext,
+		secondaryTracer:     secondaryTracer,
+		secondaryCapture:    cfg.SecondaryFullCapture,
+		zeroStatusPolicy:    cfg.ZeroStatusPolicy,
+		multipartFileEvents: cfg.MultipartFileEvents,
+		routeSampling:       routeSampling,
+		debugHeader:         debug,
+		contentLengthCheck:  cfg.ContentLengthCheck,
+		idempotency:         idempotency,
+		spanStartOptions:    cfg.SpanStartOptions,
+		asyncEnrichment:     enrichment,
+		spanKind:            spanKind,
+		spanKindOption:      oteltrace.WithSpanKind(spanKind),
+		webSocketPolicy:     cfg.WebSocketPolicy,
+		filteredPropagation: cfg.FilteredPropagation,
+		streamChunkEvents:   cfg.StreamChunkEvents,
+		streamChunkInterval: cfg.StreamChunkInterval,
+		bodyDedup:           dedup,
+		serverTiming:        cfg.ServerTiming,
+		baggageLimits:       cfg.BaggageLimits,
+		metrics:             newServerMetrics(cfg.MeterProvider, cfg.PrometheusMetricNames),
+		logEmitter:          cfg.LogEmitter,
+		bodiesAsEvents:      cfg.BodiesAsEvents,
+		capturePredicates:   cfg.CapturePredicates,
+		slowThreshold:       cfg.SlowRequestThreshold,
+		routeMatchTiming:    cfg.RouteMatchTiming,
+		serverNameFn:        cfg.ServerNameFn,
+		staticAttributes:    staticAttributes(serverName, cfg.ServerNameFn == nil),
+		routeCache:          routeCache,
+		unmatchedSpanNames:  unmatchedSpanNames,
+		spanNameGuard:       spanNameGuard,
+		detectPropagation:   cfg.PropagatorAutodetection,
+		queryTraceContext:   cfg.QueryTraceContext,
+		headerLinks:         cfg.HeaderLinks,
+		syntheticDetection:  cfg.SyntheticDetection,
+		userAgentParser:     cfg.UserAgentParser,
+		requestID:           cfg.RequestID,
+		spanStatusMapper:    cfg.SpanStatusMapper,
+		errorEvents:         cfg.ErrorEvents || cfg.StackTraces,
+		stackTraces:         stackTraces,
+		spanHooks:           cfg.SpanHooks,
+		attributesFns:       cfg.RequestAttributesFns,
+		keys:                newAttributeKeys(cfg),
+		valueLimit:          attributeLimit,
+		bodyDigests:         cfg.BodyDigests,
+		payloadOffload:      offload,
+		captureRatio:        captureRatio,
+		captureLimiter:      captureLimiter,
+		captureConfig:       cfg.CaptureConfig,
+		maxBodySize:         cfg.MaxBodySize,
+		clock:               cfg.Clock,
+		detectRoutes:        cfg.ChiRoutes == nil && (!cfg.LazyRouteNaming || cfg.RouteSamplerHint),
+		stateStore:          cfg.StateStore,
+		panicRecovery:       cfg.PanicRecovery,
+	}
+}
+
+// staticAttributes returns the start attributes which only depend on the
+// middleware configuration, so they are built once instead of per request.
+func staticAttributes(serverName string, staticServerName bool) []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, 2)
+	if staticServerName && serverName != "" {
+		attrs = append(attrs, semconv.HTTPServerNameKey.String(serverName))
+	}
+	return append(attrs, captureSchemaVersionKey.String(CaptureSchemaVersion))
 }
 
 type traceware struct {
 	serverName          string
+	serverNameFn        func(r *http.Request) string
 	tracer              oteltrace.Tracer
 	propagators         propagation.TextMapPropagator
 	handler             http.Handler
 	chiRoutes           chi.Routes
 	reqMethodInSpanName bool
 	metadataOnly        bool
-	filter              func(r *http.Request) bool
+	filters             []func(r *http.Request) bool
+	priority            bool
+	priorityHeader      string
+	bodyExtractors      []bodyExtractor
+	cachePolicies       map[string]string
+	graphqlRoutes       map[string]bool
+	xmlCapture          *xmlCapture
+	enabledFunc         func() bool
+	routeHeader         string
+	headerCapture       headerCapture
+	eventLimits         *eventLimits
+	pathNormalizer      func(path string) string
+	minimalAttributes   bool
+	http2Attributes     bool
+	http2StreamID       func(r *http.Request) (uint32, bool)
+	jwtEnduser          bool
+	jwtVerifier         JWTVerifier
+	clientAddress       *clientAddressResolver
+	captureBuffer       *CaptureBuffer
+	operationNames      map[string]OperationNameFunc
+	quarantine          *quarantine
+	htmlTraceContext    bool
+	secondaryTracer     oteltrace.Tracer
+	secondaryCapture    bool
+	zeroStatusPolicy    ZeroStatusPolicy
+	routeOverrides      *routeOverrides
+	multipartFileEvents bool
+	routeSampling       *routeSampling
+	debugHeader         *debugHeader
+	contentLengthCheck  bool
+	idempotency         *idempotencyDetector
+	spanStartOptions    []oteltrace.SpanStartOption
+	asyncEnrichment     *asyncEnrichment
+	spanKind            oteltrace.SpanKind
+	spanKindOption      oteltrace.SpanStartOption
+	webSocketPolicy     WebSocketPolicy
+	filteredPropagation bool
+	streamChunkEvents   bool
+	streamChunkInterval time.Duration
+	bodyDedup           *bodyDedup
+	serverTiming        bool
+	baggageLimits       *baggageLimits
+	metrics             *serverMetrics
+	logEmitter          LogEmitter
+	bodiesAsEvents      bool
+	capturePredicates   []CapturePredicate
+	slowThreshold       time.Duration
+	routeMatchTiming    bool
+	staticAttributes    []attribute.KeyValue
+	routeCache          *routeCache
+	unmatchedSpanNames  *unmatchedSpanNames
+	spanNameGuard       *spanNameGuard
+	detectPropagation   bool
+	queryTraceContext   *queryTraceContext
+	headerLinks         []headerLink
+	syntheticDetection  bool
+	userAgentParser     UserAgentParser
+	requestID           *requestIDCorrelation
+	spanStatusMapper    func(status int) (codes.Code, string)
+	errorEvents         bool
+	stackTraces         *stackTraces
+	spanHooks           []spanHooks
+	attributesFns       []func(r *http.Request) []attribute.KeyValue
+	keys                AttributeKeys
+	valueLimit          *valueLimit
+	bodyDigests         bool
+	payloadOffload      *payloadOffload
+	captureRatio        float64
+	captureLimiter      *captureLimiter
+	captureConfig       *CaptureConfig
+	maxBodySize         int
+	clock               func() time.Time
+	detectRoutes        bool
+	stateStore          StateStore
+	panicRecovery       bool
 }
 
 type recordingResponseWriter struct {
 	writer       http.ResponseWriter
 	written      bool
 	status       int
+	bytesWritten int64
 	responseBody []byte
 	metadataOnly bool
+	cancel       *cancellationTracker
+	htmlTag      []byte
+	onHijack     func()
+	streaming    bool
+	chunks       *streamChunks
+	bodyEvents   bool
+	bodyEnd      time.Time
+	clock        func() time.Time
 }
 
 var rrwPool = &sync.Pool{
@@ -114,24 +425,45 @@ func getRRW(writer http.ResponseWriter) *recordingResponseWriter {
 	rrw := rrwPool.Get().(*recordingResponseWriter)
 	rrw.written = false
 	rrw.status = 0
+	rrw.bytesWritten = 0
 	rrw.responseBody = []byte{}
+	rrw.streaming = false
+	rrw.bodyEvents = false
+	rrw.bodyEnd = time.Time{}
 	rrw.writer = httpsnoop.Wrap(writer, httpsnoop.Hooks{
 		Write: func(next httpsnoop.WriteFunc) httpsnoop.WriteFunc {
 			return func(b []byte) (int, error) {
 				if !rrw.written {
 					rrw.written = true
 					rrw.status = http.StatusOK
+					rrw.beforeHeaders(writer.Header())
 				}
 
-				if !rrw.metadataOnly && len(b) > 0 {
+				if !rrw.metadataOnly && !rrw.streaming && len(b) > 0 {
 					respContentType := writer.Header().Get("Content-Type")
 					shouldSkipContentByType, _ := datautils.ShouldSkipContentCollectionByContentType(respContentType)
 					if !shouldSkipContentByType {
 						rrw.responseBody = append(rrw.responseBody, b...)
+						if rrw.bodyEvents {
+							rrw.bodyEnd = now(rrw.clock)
+						}
 					}
 				}
 
-				return next(b)
+				written := b
+				if rrw.htmlTag != nil {
+					written = rrw.injectHTML(b)
+				}
+				n, err := next(written)
+				rrw.cancel.observe(stageWritingResponse, err)
+				if extra := len(written) - len(b); extra > 0 {
+					// report the bytes of b that were written
+					if n -= extra; n < 0 {
+						n = 0
+					}
+				}
+				rrw.bytesWritten += int64(n)
+				return n, err
 			}
 		},
 		WriteHeader: func(next httpsnoop.WriteHeaderFunc) httpsnoop.WriteHeaderFunc {
@@ -139,39 +471,113 @@ func getRRW(writer http.ResponseWriter) *recordingResponseWriter {
 				if !rrw.written {
 					rrw.written = true
 					rrw.status = statusCode
+					rrw.beforeHeaders(writer.Header())
 				}
 				next(statusCode)
 			}
 		},
+		Flush: func(next httpsnoop.FlushFunc) httpsnoop.FlushFunc {
+			return func() {
+				if !rrw.written {
+					rrw.written = true
+					rrw.status = http.StatusOK
+					rrw.beforeHeaders(writer.Header())
+				}
+				// flushing handlers stream their response
+				rrw.startStreaming()
+				next()
+				if rrw.chunks != nil {
+					rrw.chunks.flushed(rrw.bytesWritten)
+				}
+			}
+		},
+		Hijack: func(next httpsnoop.HijackFunc) httpsnoop.HijackFunc {
+			return func() (net.Conn, *bufio.ReadWriter, error) {
+				conn, rw, err := next()
+				if err == nil {
+					if !rrw.written {
+						rrw.written = true
+						rrw.status = http.StatusSwitchingProtocols
+					}
+					if rrw.onHijack != nil {
+						rrw.onHijack()
+					}
+				}
+				return conn, rw, err
+			}
+		},
 	})
 	return rrw
 }
 
 func putRRW(rrw *recordingResponseWriter) {
 	rrw.writer = nil
+	rrw.cancel = nil
+	rrw.htmlTag = nil
+	rrw.onHijack = nil
+	rrw.chunks = nil
+	rrw.clock = nil
 	rrwPool.Put(rrw)
 }
 
-func collectRequestHeaders(r *http.Request, span oteltrace.Span) {
-	headersStr, err := json.Marshal(r.Header)
-	if err == nil {
-		span.SetAttributes(attribute.KeyValue{Key: "http.request.headers", Value: attribute.StringValue(string(headersStr))})
-	}
-}
-
 // ServeHTTP implements the http.Handler interface. It does the actual
 // tracing of the request.
 func (tw traceware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// skip if filter returns false
-	if tw.filter != nil && !tw.filter(r) {
+	if override, ok := tw.routeOverrides.match(r); ok {
+		override.ServeHTTP(w, r)
+		return
+	}
+
+	// skip if disabled or a filter returns false
+	if !tw.enabled() {
+		tw.handler.ServeHTTP(w, r)
+		return
+	}
+	if !tw.traced(r) {
+		if tw.filteredPropagation {
+			// keep the trace going downstream, without span
+			ctx := tw.propagators.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			if tw.detectPropagation {
+				ctx, _ = extractDetected(ctx, propagation.HeaderCarrier(r.Header))
+			}
+			if tw.queryTraceContext != nil {
+				ctx, _ = tw.queryTraceContext.extract(ctx, r, false)
+			}
+			if tw.baggageLimits != nil {
+				ctx, _ = tw.baggageLimits.enforce(ctx, r.Header)
+			}
+			r = r.WithContext(ctx)
+		}
 		tw.handler.ServeHTTP(w, r)
 		return
 	}
 
-	metadataOnly := tw.metadataOnly
+	start := now(tw.clock)
+	var debugAttrs []attribute.KeyValue
+	if tw.debugHeader != nil {
+		if token, ok := tw.debugHeader.token(r); ok {
+			debugAttrs = debugAttributes(token)
+		}
+	}
 
 	// extract tracing header using propagator
 	ctx := tw.propagators.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	var formatAttrs []attribute.KeyValue
+	if tw.detectPropagation {
+		ctx, formatAttrs = extractDetected(ctx, propagation.HeaderCarrier(r.Header))
+	}
+	var queryAttrs []attribute.KeyValue
+	if tw.queryTraceContext != nil {
+		ctx, queryAttrs = tw.queryTraceContext.extract(ctx, r, !tw.minimalAttributes)
+	}
+	var baggageAttrs []attribute.KeyValue
+	if tw.baggageLimits != nil {
+		ctx, baggageAttrs = tw.baggageLimits.enforce(ctx, r.Header)
+	}
+	var priority string
+	if tw.priority {
+		ctx, priority = resolvePriority(ctx, r, tw.priorityHeader)
+	}
 	// create span, based on specification, we need to set already known attributes
 	// when creating the span, the only thing missing here is HTTP route pattern since
 	// in go-chi/chi route pattern could only be extracted once the request is executed
@@ -182,72 +588,512 @@ func (tw traceware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// if we have access to chi routes, we could extract the route pattern beforehand.
 	spanName := ""
 	routePattern := ""
-	if tw.chiRoutes != nil {
-		rctx := chi.NewRouteContext()
-		if tw.chiRoutes.Match(rctx, r.Method, r.URL.Path) {
-			routePattern = rctx.RoutePattern()
+	var matchAttrs []attribute.KeyValue
+	chiRoutes := tw.chiRoutes
+	if chiRoutes == nil && tw.detectRoutes {
+		chiRoutes = detectRoutes(r)
+	}
+	if chiRoutes != nil {
+		matchStart := now(tw.clock)
+		if routePattern = tw.matchRoute(r, chiRoutes); routePattern != "" {
 			spanName = addPrefixToSpanName(tw.reqMethodInSpanName, r.Method, routePattern)
 		}
+		if tw.routeMatchTiming {
+			matchAttrs = routeMatchAttributes(now(tw.clock).Sub(matchStart), routePattern != "")
+		}
 	}
 
+	if tw.routeSampling != nil && debugAttrs == nil {
+		if unsampledCtx, drop := tw.routeSampling.drop(ctx, r, routePattern); drop {
+			tw.handler.ServeHTTP(w, r.WithContext(unsampledCtx))
+			return
+		}
+	}
+
+	settings := tw.captureConfig.load()
+	metadataOnly := settings.metadataOnly(tw.metadataOnly, routePattern)
+	if debugAttrs != nil {
+		// debug requests are captured with full fidelity
+		metadataOnly = false
+	}
+	// payloads may be captured for the secondary span only
+	capture := !metadataOnly || (tw.secondaryTracer != nil && tw.secondaryCapture)
+	if capture && debugAttrs == nil && !sampleCapture(settings.captureRatio(tw.captureRatio)) {
+		capture = false
+	}
+	// once the route is known, bursts of requests to it are not all captured
+	var captureDropped bool
+	if capture && debugAttrs == nil && tw.captureLimiter != nil && !tw.captureLimiter.allow(r.Context(), routePattern, start) {
+		capture = false
+		captureDropped = true
+	}
+
+	cancel := newCancellationTracker(r.Context())
+	serverName := tw.requestServerName(r)
+
 	var bw bodyWrapper
-	bw.metadataOnly = metadataOnly
+	bw.metadataOnly = !capture
+	bw.cancel = cancel
+	bw.clock = tw.clock
+	if tw.mayNeedRequestBody(r, routePattern) {
+		// GraphQL and RPC operations are parsed from the body even when
+		// payloads are not exported
+		bw.metadataOnly = false
+	}
+	websocket := isWebSocketUpgrade(r)
+	if websocket {
+		// the connection is the body of upgraded requests
+		bw.metadataOnly = true
+	}
 	if r.Body != nil && r.Body != http.NoBody {
 		bw.contentType = r.Header.Get("Content-type")
 		bw.ReadCloser = r.Body
 		r.Body = &bw
 	}
 
-	ctx, span := tw.tracer.Start(
-		ctx, spanName,
-		oteltrace.WithAttributes(semconv.NetAttributesFromHTTPRequest("tcp", r)...),
-		oteltrace.WithAttributes(semconv.EndUserAttributesFromHTTPRequest(r)...),
-		oteltrace.WithAttributes(semconv.HTTPServerAttributesFromHTTPRequest(tw.serverName, routePattern, r)...),
-		oteltrace.WithSpanKind(oteltrace.SpanKindServer),
-	)
-	defer span.End()
+	startOpts := make([]oteltrace.SpanStartOption, 0, 9+len(tw.spanStartOptions))
+	startOpts = append(startOpts, tw.spanKindOption)
+	startOpts = tw.appendAttributesOption(startOpts, tw.startAttributes(r, routePattern, serverName))
+	startOpts = tw.appendAttributesOption(startOpts, tw.optionalStartAttributes(r))
+	startOpts = tw.appendAttributesOption(startOpts, debugAttrs)
+	startOpts = tw.appendAttributesOption(startOpts, baggageAttrs)
+	startOpts = tw.appendAttributesOption(startOpts, formatAttrs)
+	startOpts = tw.appendAttributesOption(startOpts, queryAttrs)
+	if links := headerLinks(tw.headerLinks, r); len(links) > 0 {
+		startOpts = append(startOpts, oteltrace.WithLinks(links...))
+	}
+	startOpts = tw.appendAttributesOption(startOpts, matchAttrs)
+	startOpts = append(startOpts, tw.spanStartOptions...)
+	if tw.clock != nil {
+		startOpts = append(startOpts, oteltrace.WithTimestamp(start))
+	}
+	parentCtx := ctx
+	ctx, span := tw.tracer.Start(ctx, spanName, startOpts...)
+	payloadSpan := span
+	if tw.secondaryTracer != nil {
+		_, secondary := tw.secondaryTracer.Start(parentCtx, spanName, startOpts...)
+		span = &mirrorSpan{Span: span, secondary: secondary}
+		ctx = oteltrace.ContextWithSpan(ctx, span)
+		payloadSpan = span
+		if metadataOnly {
+			payloadSpan = secondary
+		}
+	}
+	if tw.valueLimit != nil {
+		samePayloadSpan := payloadSpan == span
+		span = &truncatingSpan{Span: span, limit: tw.valueLimit}
+		ctx = oteltrace.ContextWithSpan(ctx, span)
+		if samePayloadSpan {
+			payloadSpan = span
+		} else {
+			payloadSpan = &truncatingSpan{Span: payloadSpan, limit: tw.valueLimit}
+		}
+	}
+	if tw.clock != nil {
+		samePayloadSpan := payloadSpan == span
+		span = &clockSpan{Span: span, clock: tw.clock}
+		ctx = oteltrace.ContextWithSpan(ctx, span)
+		if samePayloadSpan {
+			payloadSpan = span
+		} else {
+			payloadSpan = &clockSpan{Span: payloadSpan, clock: tw.clock}
+		}
+	}
+	var snapshot *RequestSnapshot
+	defer func() {
+		if tw.asyncEnrichment == nil || snapshot == nil {
+			if tw.clock != nil {
+				span.End(oteltrace.WithTimestamp(now(tw.clock)))
+				return
+			}
+			span.End()
+			return
+		}
+		tw.asyncEnrichment.enrich(span, *snapshot)
+	}()
+	if tw.metrics != nil {
+		defer tw.metrics.requestStarted(ctx, r.Method, routePattern)()
+	}
+
+	if captureDropped {
+		span.SetAttributes(captureDroppedKey.Bool(true))
+	}
+	if priority != "" {
+		span.SetAttributes(priorityKey.String(priority))
+	}
+	if tw.idempotency != nil {
+		span.SetAttributes(tw.idempotency.attributes(ctx, r)...)
+	}
+	if tw.http2Attributes {
+		span.SetAttributes(http2Attributes(r, tw.http2StreamID)...)
+	}
+	if websocket {
+		span.SetAttributes(networkProtocolNameKey.String("websocket"))
+	}
 
 	// get recording response writer
 	rrw := getRRW(w)
-	rrw.metadataOnly = metadataOnly
+	rrw.metadataOnly = !capture || websocket
+	rrw.bodyEvents = tw.bodiesAsEvents
+	rrw.cancel = cancel
+	if websocket && tw.webSocketPolicy == WebSocketSpanUntilUpgrade {
+		rrw.onHijack = tw.endAtUpgrade(span, r, routePattern, rrw)
+	}
+	if tw.htmlTraceContext && span.SpanContext().IsValid() {
+		rrw.htmlTag = traceparentMetaTag(span.SpanContext())
+	}
+	if tw.serverTiming && span.SpanContext().IsValid() {
+		rrw.writer.Header().Add("Server-Timing", serverTimingTraceparent(span.SpanContext()))
+	}
 	defer putRRW(rrw)
 
+	// hand a span sampling the events added by the handler
+	var eventSpan *eventSamplingSpan
+	if tw.eventLimits != nil {
+		capacity := tw.eventLimits.maxLimit
+		if routePattern != "" {
+			capacity = tw.eventLimits.limit(routePattern)
+		}
+		eventSpan = newEventSamplingSpan(span, capacity, tw.clock)
+		ctx = oteltrace.ContextWithSpan(ctx, eventSpan)
+	}
+
+	if tw.streamChunkEvents {
+		rrw.chunks = &streamChunks{span: oteltrace.SpanFromContext(ctx), interval: tw.streamChunkInterval, clock: tw.clock}
+	}
+
+	if tw.requestID != nil {
+		ctx = tw.requestID.correlate(ctx, span, rrw.writer.Header())
+	}
+
 	// execute next http handler
+	ctx = contextWithRecorder(ctx, rrw)
+	owner := &spanOwner{span: oteltrace.SpanFromContext(ctx), serverName: serverName, stackTraces: tw.stackTraces, clock: tw.clock}
+	ctx = contextWithSpanOwner(ctx, owner)
 	r = r.WithContext(ctx)
-	tw.handler.ServeHTTP(rrw.writer, r)
+	for _, hooks := range tw.spanHooks {
+		if hooks.onStart != nil {
+			hooks.onStart(span, r)
+		}
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tw.recordPanic(span, r, routePattern, p, tw.panicStack())
+			panic(p)
+		}
+	}()
+	handlerStart := now(tw.clock)
+	var recovered interface{}
+	var recoveredStack string
+	if tw.panicRecovery {
+		recovered, recoveredStack = tw.serveRecovering(rrw, r)
+	} else {
+		tw.handler.ServeHTTP(rrw.writer, r)
+	}
+	handlerElapsed := now(tw.clock).Sub(handlerStart)
 
 	// set span name & http route attribute if necessary
-	if len(routePattern) == 0 {
+	resolved := len(routePattern) > 0
+	// the span is renamed once its final name is known
+	renamed := false
+	if !resolved {
 		routePattern = chi.RouteContext(r.Context()).RoutePattern()
-		span.SetAttributes(semconv.HTTPRouteKey.String(routePattern))
-
-		spanName = addPrefixToSpanName(tw.reqMethodInSpanName, r.Method, routePattern)
-		span.SetName(spanName)
+	}
+	if tw.routeHeader != "" && isCatchAllPattern(routePattern) {
+		if gatewayRoute := r.Header.Get(tw.routeHeader); gatewayRoute != "" {
+			routePattern = gatewayRoute
+			resolved = false
+		}
+	}
+	if !resolved {
+		if routePattern != "" {
+			span.SetAttributes(semconv.HTTPRouteKey.String(routePattern))
+			spanName = addPrefixToSpanName(tw.reqMethodInSpanName, r.Method, routePattern)
+		} else {
+			// naming the span after the raw path would make its cardinality unbounded
+			span.SetAttributes(urlPathKey.String(r.URL.Path))
+			spanName = tw.unmatchedSpanNames.spanName(r.Method, rrw.status)
+		}
+		renamed = true
+	}
+	if tw.pathNormalizer != nil && isCatchAllPattern(routePattern) {
+		spanName = addPrefixToSpanName(tw.reqMethodInSpanName, r.Method, tw.pathNormalizer(r.URL.Path))
+		renamed = true
 	}
 
 	// Add traceresponse header
 	if span.IsRecording() {
 		spanCtx := span.SpanContext()
-		rrw.writer.Header().Add("traceresponse", fmt.Sprintf("00-%s-%s-01", spanCtx.TraceID().String(), spanCtx.SpanID().String()))
+		rrw.writer.Header().Add(traceresponseHeader, "00-"+spanCtx.TraceID().String()+"-"+spanCtx.SpanID().String()+"-01")
 	}
 
-	// set status code attribute
-	span.SetAttributes(semconv.HTTPStatusCodeKey.Int(rrw.status))
+	if r.Method == http.MethodPost && tw.graphqlRoutes[routePattern] {
+		if op, ok := parseGraphQLRequest(bw.requestBody); ok {
+			spanName, renamed = op.spanName(), true
+			span.SetAttributes(op.attributes()...)
+		}
+	}
+	if namer := tw.operationNamer(r.Method, routePattern); namer != nil {
+		if name := namer(r, bw.requestBody); name != "" {
+			spanName, renamed = name, true
+		}
+	}
+	if renamed {
+		span.SetName(tw.guardSpanName(ctx, r.Method, spanName))
+	}
+
+	if len(tw.cachePolicies) > 0 {
+		span.SetAttributes(cacheControlAttributes(tw.cachePolicies, routePattern, rrw.writer.Header())...)
+	}
+
+	if tw.slowThreshold > 0 && handlerElapsed > tw.slowThreshold {
+		tw.slowRequest(ctx, span, r, routePattern, handlerElapsed)
+	}
+
+	span.SetAttributes(cancel.attributes()...)
+	if rrw.streaming {
+		span.SetAttributes(responseStreamingKey.Bool(true))
+	}
+	if tw.contentLengthCheck {
+		span.SetAttributes(contentLengthAttributes(r, &bw)...)
+	}
+	if !tw.minimalAttributes {
+		span.SetAttributes(bodySizeAttributes(&bw, rrw)...)
+	}
+
+	if eventSpan != nil {
+		eventSpan.flush(tw.eventLimits.limit(routePattern))
+	}
 
-	// set span status
-	spanStatus, spanMessage := semconv.SpanStatusFromHTTPStatusCode(rrw.status)
-	span.SetStatus(spanStatus, spanMessage)
+	if cancel.aborted(rrw) {
+		cancel.recordAborted(span)
+	} else {
+		tw.recordStatus(span, rrw)
+	}
+	tw.recordError(span, rrw, owner.err, owner.stack)
+	if owner.timeout > 0 {
+		recordTimeout(span, owner.timeout)
+	}
+	if recovered != nil {
+		tw.recordPanic(span, r, routePattern, recovered, recoveredStack)
+	}
 
-	if !metadataOnly {
-		collectRequestHeaders(r, span)
-		if len(bw.requestBody) > 0 {
-			span.SetAttributes(attribute.KeyValue{Key: "http.request.body", Value: attribute.StringValue(string(bw.requestBody))})
+	// the payloads of debug requests are always attached
+	attach := capture && (debugAttrs != nil || (!settings.skipsCapture(routePattern) && tw.attachPayloads(r, routePattern, rrw, start, span)))
+	var requestBody, responseBody []byte
+	headerCapture := settings.headerCapture(tw.headerCapture)
+	if attach {
+		payloadSpan.SetAttributes(headerCapture.requestAttributes(r)...)
+		payloadSpan.SetAttributes(headerCapture.trailerAttributes(rrw.writer.Header())...)
+		collectMultipartMetadata(r, payloadSpan)
+		if tw.multipartFileEvents {
+			addMultipartFileEvents(r, payloadSpan)
+		}
+	}
+	if attach && tw.bodyDigests {
+		// the content of the bodies is never recorded
+		payloadSpan.SetAttributes(bodyDigestAttributes(tw.keys.RequestBody, bw.requestBody)...)
+		payloadSpan.SetAttributes(bodyDigestAttributes(tw.keys.ResponseBody, rrw.responseBody)...)
+	} else if attach {
+		// the payload store gets the whole bodies, only the recorded ones
+		// are limited
+		storedRequest, storedResponse := bw.requestBody, rrw.responseBody
+		requestBody, responseBody = settings.limitBody(bw.requestBody, tw.maxBodySize), settings.limitBody(rrw.responseBody, tw.maxBodySize)
+		if tw.xmlCapture != nil {
+			var (
+				attrs []attribute.KeyValue
+				err   error
+			)
+			if len(requestBody) > 0 && isXMLContentType(bw.contentType) {
+				attrs, requestBody, err = tw.xmlCapture.analyze(tw.keys.RequestBody+".xml", requestBody)
+				// the redacted elements are not stored either
+				storedRequest = requestBody
+				payloadSpan.SetAttributes(attrs...)
+				if err != nil {
+					payloadSpan.SetAttributes(tw.quarantine.scrubFailed(ctx, payloadSpan, tw.keys.RequestBody, bw.requestBody, err)...)
+				}
+			}
+			if len(responseBody) > 0 && isXMLContentType(rrw.writer.Header().Get("Content-Type")) {
+				attrs, responseBody, err = tw.xmlCapture.analyze(tw.keys.ResponseBody+".xml", responseBody)
+				storedResponse = responseBody
+				payloadSpan.SetAttributes(attrs...)
+				if err != nil {
+					payloadSpan.SetAttributes(tw.quarantine.scrubFailed(ctx, payloadSpan, tw.keys.ResponseBody, rrw.responseBody, err)...)
+				}
+			}
 		}
 
-		if len(rrw.responseBody) > 0 {
-			span.SetAttributes(attribute.KeyValue{Key: "http.response.body", Value: attribute.StringValue(string(rrw.responseBody))})
+		if tw.logEmitter != nil {
+			emitPayloadLogs(ctx, tw.logEmitter, tw.clock, tw.keys, payloadSpan.SpanContext(), r, routePattern, rrw, requestBody, responseBody)
+		} else if tw.bodiesAsEvents {
+			addBodyEvent(payloadSpan, tw.clock, tw.keys.RequestBody, bw.ended, tw.bodyAttributes(ctx, payloadSpan, tw.keys.RequestBody, bw.contentType, storedRequest, requestBody))
+			addBodyEvent(payloadSpan, tw.clock, tw.keys.ResponseBody, rrw.bodyEnd, tw.bodyAttributes(ctx, payloadSpan, tw.keys.ResponseBody, rrw.writer.Header().Get("Content-Type"), storedResponse, responseBody))
+		} else {
+			payloadSpan.SetAttributes(tw.bodyAttributes(ctx, payloadSpan, tw.keys.RequestBody, bw.contentType, storedRequest, requestBody)...)
+			payloadSpan.SetAttributes(tw.bodyAttributes(ctx, payloadSpan, tw.keys.ResponseBody, rrw.writer.Header().Get("Content-Type"), storedResponse, responseBody)...)
 		}
+
+		payloadSpan.SetAttributes(extractBodyAttributes(tw.bodyExtractors, bw.requestBody, rrw.responseBody)...)
+	}
+
+	if tw.captureBuffer != nil {
+		spanCtx := span.SpanContext()
+		captured := CapturedRequest{
+			Time:           start,
+			TraceID:        spanCtx.TraceID().String(),
+			SpanID:         spanCtx.SpanID().String(),
+			Method:         r.Method,
+			Path:           r.URL.Path,
+			Route:          routePattern,
+			Status:         rrw.status,
+			DurationMillis: float64(now(tw.clock).Sub(start)) / float64(time.Millisecond),
+			RequestBody:    string(requestBody),
+			ResponseBody:   string(responseBody),
+		}
+		if attach {
+			captured.RequestHeaders = headerCapture.capture(r.Header)
+		}
+		tw.captureBuffer.add(captured)
+	}
+
+	if tw.metrics != nil {
+		tw.metrics.requestServed(ctx, now(tw.clock).Sub(start), r.Method, routePattern, rrw.status)
+	}
+
+	for _, hooks := range tw.spanHooks {
+		if hooks.onEnd != nil {
+			hooks.onEnd(span, r, rrw.status, now(tw.clock).Sub(start))
+		}
+	}
+
+	if tw.asyncEnrichment != nil {
+		snapshot = &RequestSnapshot{
+			SpanContext:    span.SpanContext(),
+			Method:         r.Method,
+			Path:           r.URL.Path,
+			Route:          routePattern,
+			RequestHeader:  r.Header.Clone(),
+			Status:         rrw.status,
+			ResponseHeader: rrw.writer.Header().Clone(),
+			Start:          start,
+			End:            now(tw.clock),
+		}
+	}
+}
+
+// traceresponseHeader is the canonical form of the traceresponse header,
+// which spares canonicalizing it on every request.
+const traceresponseHeader = "Traceresponse"
+
+// appendAttributesOption appends a span start option setting attrs, unless
+// there are none.
+func (tw traceware) appendAttributesOption(opts []oteltrace.SpanStartOption, attrs []attribute.KeyValue) []oteltrace.SpanStartOption {
+	if len(attrs) == 0 {
+		return opts
+	}
+	if tw.valueLimit != nil {
+		attrs = tw.valueLimit.truncate(attrs)
+	}
+	return append(opts, oteltrace.WithAttributes(attrs...))
+}
+
+// startAttributesCapacity covers the start attributes of a typical
+// request, so building them needs a single allocation.
+const startAttributesCapacity = 24
+
+// startAttributes returns the attributes known when the span starts.
+func (tw traceware) startAttributes(r *http.Request, routePattern, serverName string) []attribute.KeyValue {
+	if tw.minimalAttributes {
+		attrs := []attribute.KeyValue{semconv.HTTPMethodKey.String(r.Method)}
+		if routePattern != "" {
+			attrs = append(attrs, semconv.HTTPRouteKey.String(routePattern))
+		}
+		return attrs
 	}
+	attrs := make([]attribute.KeyValue, 0, startAttributesCapacity)
+	attrs = append(attrs, semconv.NetAttributesFromHTTPRequest("tcp", r)...)
+	attrs = append(attrs, semconv.EndUserAttributesFromHTTPRequest(r)...)
+	// a static server name is part of the static attributes
+	attrs = append(attrs, semconv.HTTPServerAttributesFromHTTPRequest("", routePattern, r)...)
+	if tw.serverNameFn != nil && serverName != "" {
+		attrs = append(attrs, semconv.HTTPServerNameKey.String(serverName))
+	}
+	attrs = append(attrs, protocolAttributes(r)...)
+	attrs = append(attrs, tlsAttributes(r)...)
+	return append(attrs, tw.staticAttributes...)
+}
+
+// optionalStartAttributes returns the start attributes of the explicitly
+// enabled options. Since they come after the default ones, they take
+// precedence over them.
+func (tw traceware) optionalStartAttributes(r *http.Request) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	if tw.jwtEnduser {
+		attrs = append(attrs, jwtEnduserAttributes(r, tw.jwtVerifier)...)
+	}
+	if tw.clientAddress != nil {
+		attrs = append(attrs, tw.clientAddress.attributes(r)...)
+	}
+	if tw.syntheticDetection {
+		attrs = append(attrs, syntheticAttributes(r)...)
+	}
+	if tw.userAgentParser != nil {
+		attrs = append(attrs, userAgentAttributes(tw.userAgentParser, r)...)
+	}
+	for _, fn := range tw.attributesFns {
+		attrs = append(attrs, fn(r)...)
+	}
+	return attrs
+}
+
+// enabled reports whether the request should be traced according to the
+// enabled func. A panicking enabled func is treated as returning true.
+func (tw traceware) enabled() (enabled bool) {
+	if tw.enabledFunc == nil {
+		return true
+	}
+	defer func() {
+		if err := recover(); err != nil {
+			otel.Handle(fmt.Errorf("otelchi: enabled func panicked: %v", err))
+			enabled = true
+		}
+	}()
+	return tw.enabledFunc()
+}
+
+// traced reports whether the filters let the request be traced.
+func (tw traceware) traced(r *http.Request) bool {
+	for _, filter := range tw.filters {
+		if !filter(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// isPreflight reports whether r is a CORS preflight request.
+func isPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// mayNeedRequestBody reports whether the request body has to be buffered to
+// find a GraphQL or RPC operation. When the route pattern is not known
+// before the handler is executed, every request that may be on a GraphQL or
+// RPC route is considered.
+func (tw traceware) mayNeedRequestBody(r *http.Request, routePattern string) bool {
+	if len(tw.operationNames) > 0 && (routePattern == "" || tw.operationNamer(r.Method, routePattern) != nil) {
+		return true
+	}
+	if len(tw.graphqlRoutes) == 0 || r.Method != http.MethodPost {
+		return false
+	}
+	return routePattern == "" || tw.graphqlRoutes[routePattern]
+}
+
+// isCatchAllPattern reports whether routePattern does not identify a route,
+// being either empty or ending with a wildcard.
+func isCatchAllPattern(routePattern string) bool {
+	return routePattern == "" || strings.HasSuffix(routePattern, "*")
 }
 
 func addPrefixToSpanName(shouldAdd bool, prefix, spanName string) string {
@@ -2,15 +2,18 @@ package otelchi
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/otel"
@@ -18,6 +21,7 @@ import (
 	"go.opentelemetry.io/otel/propagation"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
 	"go.opentelemetry.io/otel/trace"
 )
 
@@ -395,3 +399,528 @@ func assertSpan(t *testing.T, span sdktrace.ReadOnlySpan, name string, kind trac
 		assert.Equal(t, got[want.Key], want.Value)
 	}
 }
+
+func TestSDKIntegrationWithMultipartForm(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar", WithTracerProvider(provider)))
+	router.HandleFunc("/upload", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseMultipartForm(1<<20))
+		w.WriteHeader(http.StatusOK)
+	})
+
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	require.NoError(t, mw.WriteField("description", "avatar"))
+	fw, err := mw.CreateFormFile("avatar", "me.png")
+	require.NoError(t, err)
+	_, err = fw.Write([]byte("not really a png"))
+	require.NoError(t, err)
+	require.NoError(t, mw.Close())
+
+	r := httptest.NewRequest("POST", "/upload", body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+
+	require.Len(t, sr.Ended(), 1)
+	span := sr.Ended()[0]
+	assertSpan(t, span,
+		"/upload",
+		trace.SpanKindServer,
+		attribute.StringSlice("http.request.multipart.field_names", []string{"description"}),
+		attribute.StringSlice("http.request.multipart.file.field_names", []string{"avatar"}),
+		attribute.StringSlice("http.request.multipart.file.names", []string{"me.png"}),
+		attribute.Int64Slice("http.request.multipart.file.sizes", []int64{16}),
+		attribute.StringSlice("http.request.multipart.file.content_types", []string{"application/octet-stream"}),
+	)
+	for _, kv := range span.Attributes() {
+		assert.NotEqual(t, attribute.Key("http.request.body"), kv.Key)
+	}
+}
+
+func TestSDKIntegrationWithMultipartFileEvents(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar", WithTracerProvider(provider), WithMultipartFileEvents()))
+	router.HandleFunc("/upload", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseMultipartForm(1<<20))
+		w.WriteHeader(http.StatusOK)
+	})
+
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	for _, name := range []string{"a.txt", "b.txt"} {
+		fw, err := mw.CreateFormFile("docs", name)
+		require.NoError(t, err)
+		_, err = fw.Write([]byte("hello"))
+		require.NoError(t, err)
+	}
+	require.NoError(t, mw.Close())
+
+	r := httptest.NewRequest("POST", "/upload", body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	require.Len(t, sr.Ended(), 1)
+	events := sr.Ended()[0].Events()
+	require.Len(t, events, 2)
+	assert.Equal(t, "http.request.multipart.file", events[0].Name)
+	assert.ElementsMatch(t, []attribute.KeyValue{
+		attribute.String("http.request.multipart.file.field_name", "docs"),
+		attribute.String("http.request.multipart.file.name", "a.txt"),
+		attribute.Int64("http.request.multipart.file.size", 5),
+		attribute.String("http.request.multipart.file.content_type", "application/octet-stream"),
+		attribute.String("http.request.multipart.file.sha256", "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"),
+	}, events[0].Attributes)
+	assert.Contains(t, events[1].Attributes, attribute.String("http.request.multipart.file.name", "b.txt"))
+}
+
+func TestSDKIntegrationWithEnabledFunc(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	enabled := false
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar", WithTracerProvider(provider), WithEnabledFunc(func() bool {
+		if enabled {
+			panic("flag client unavailable")
+		}
+		return false
+	})))
+	router.HandleFunc("/user/{id:[0-9]+}", ok)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/user/123", nil))
+	require.Len(t, sr.Ended(), 0)
+
+	// fail open when the enabled func panics
+	enabled = true
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/user/123", nil))
+	require.Len(t, sr.Ended(), 1)
+	assertSpan(t, sr.Ended()[0], "/user/{id:[0-9]+}", trace.SpanKindServer)
+}
+
+func TestSDKIntegrationWithRouteHeader(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar",
+		WithTracerProvider(provider),
+		WithChiRoutes(router),
+		WithRouteHeader("X-Route-Name"),
+	))
+	router.HandleFunc("/user/{id:[0-9]+}", ok)
+	router.HandleFunc("/*", ok)
+
+	r0 := httptest.NewRequest("GET", "/proxy/users/123", nil)
+	r0.Header.Set("X-Route-Name", "/users/{id}")
+	r1 := httptest.NewRequest("GET", "/user/123", nil)
+	r1.Header.Set("X-Route-Name", "/users/{id}")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r0)
+	router.ServeHTTP(w, r1)
+
+	require.Len(t, sr.Ended(), 2)
+	assertSpan(t, sr.Ended()[0], "/users/{id}", trace.SpanKindServer,
+		attribute.String("http.route", "/users/{id}"),
+	)
+	assertSpan(t, sr.Ended()[1], "/user/{id:[0-9]+}", trace.SpanKindServer,
+		attribute.String("http.route", "/user/{id:[0-9]+}"),
+	)
+}
+
+func TestSDKIntegrationSchema(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar", WithTracerProvider(provider)))
+	router.HandleFunc("/user/{id:[0-9]+}", ok)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/user/123", nil))
+
+	require.Len(t, sr.Ended(), 1)
+	span := sr.Ended()[0]
+	assert.Equal(t, semconv.SchemaURL, span.InstrumentationLibrary().SchemaURL)
+	assertSpan(t, span, "/user/{id:[0-9]+}", trace.SpanKindServer,
+		attribute.String("helios.capture.schema_version", CaptureSchemaVersion),
+	)
+}
+
+func TestSDKIntegrationWithPathNormalizer(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar",
+		WithTracerProvider(provider),
+		WithPathNormalizer(NormalizePath),
+		WithRequestMethodInSpanName(true),
+	))
+	router.HandleFunc("/book/{title}", ok)
+	router.Handle("/proxy/*", http.HandlerFunc(ok))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/proxy/users/42", nil))
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/book/foo", nil))
+
+	require.Len(t, sr.Ended(), 2)
+	assertSpan(t, sr.Ended()[0], "GET /proxy/users/{id}", trace.SpanKindServer,
+		attribute.String("http.route", "/proxy/*"),
+	)
+	assertSpan(t, sr.Ended()[1], "GET /book/{title}", trace.SpanKindServer)
+}
+
+func TestSDKIntegrationWithMinimalAttributes(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar",
+		WithTracerProvider(provider),
+		WithMinimalAttributes(),
+	))
+	router.HandleFunc("/user/{id:[0-9]+}", ok)
+
+	r := httptest.NewRequest("GET", "/user/123", nil)
+	r.Header.Set("User-Agent", "test")
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	require.Len(t, sr.Ended(), 1)
+	assert.ElementsMatch(t, []attribute.KeyValue{
+		attribute.String("http.method", "GET"),
+		attribute.String("http.route", "/user/{id:[0-9]+}"),
+		attribute.Int("http.status_code", http.StatusOK),
+	}, sr.Ended()[0].Attributes())
+}
+
+func TestSDKIntegrationWithTrustedProxies(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar",
+		WithTracerProvider(provider),
+		WithTrustedProxies("192.0.2.0/24"),
+	))
+	router.HandleFunc("/user/{id}", ok)
+
+	r := httptest.NewRequest("GET", "/user/123", nil)
+	r.Header.Set("X-Forwarded-For", "1.2.3.4, 203.0.113.7, 192.0.2.10")
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	require.Len(t, sr.Ended(), 1)
+	assertSpan(t, sr.Ended()[0], "/user/{id}", trace.SpanKindServer,
+		attribute.String("client.address", "203.0.113.7"),
+		attribute.String("http.client_ip", "203.0.113.7"),
+	)
+}
+
+func TestSDKIntegrationWithRealIP(t *testing.T) {
+	testCases := []struct {
+		name       string
+		realIPLast bool
+		wantSource string
+	}{
+		{name: "after RealIP", wantSource: "real_ip"},
+		{name: "before RealIP", realIPLast: true, wantSource: "header"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			sr := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider()
+			provider.RegisterSpanProcessor(sr)
+
+			router := chi.NewRouter()
+			tracing := Middleware("foobar", WithTracerProvider(provider), WithRealIP())
+			if testCase.realIPLast {
+				router.Use(tracing, middleware.RealIP)
+			} else {
+				router.Use(middleware.RealIP, tracing)
+			}
+			router.HandleFunc("/user/{id}", ok)
+
+			r := httptest.NewRequest("GET", "/user/123", nil)
+			r.Header.Set("X-Real-IP", "203.0.113.7")
+			router.ServeHTTP(httptest.NewRecorder(), r)
+
+			require.Len(t, sr.Ended(), 1)
+			assertSpan(t, sr.Ended()[0], "/user/{id}", trace.SpanKindServer,
+				attribute.String("client.address", "203.0.113.7"),
+				attribute.String("client.address.source", testCase.wantSource),
+			)
+		})
+	}
+}
+
+func TestSDKIntegrationWithSkipPreflight(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar", WithTracerProvider(provider), WithSkipPreflight()))
+	router.HandleFunc("/user/{id}", ok)
+
+	r := httptest.NewRequest("OPTIONS", "/user/123", nil)
+	r.Header.Set("Access-Control-Request-Method", "PUT")
+	router.ServeHTTP(httptest.NewRecorder(), r)
+	require.Len(t, sr.Ended(), 0)
+
+	r = httptest.NewRequest("OPTIONS", "/user/123", nil)
+	router.ServeHTTP(httptest.NewRecorder(), r)
+	require.Len(t, sr.Ended(), 1)
+}
+
+func TestSDKIntegrationWithSpanStartOptions(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	link := trace.Link{SpanContext: trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{0x01},
+		SpanID:  trace.SpanID{0x01},
+	})}
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar",
+		WithTracerProvider(provider),
+		WithSpanStartOptions(
+			trace.WithAttributes(attribute.String("team", "payments")),
+			trace.WithLinks(link),
+		),
+	))
+	router.HandleFunc("/user/{id}", ok)
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/user/123", nil))
+
+	require.Len(t, sr.Ended(), 1)
+	span := sr.Ended()[0]
+	assertSpan(t, span, "/user/{id}", trace.SpanKindServer, attribute.String("team", "payments"))
+	require.Len(t, span.Links(), 1)
+	assert.Equal(t, link.SpanContext.TraceID(), span.Links()[0].SpanContext.TraceID())
+}
+
+func TestSDKIntegrationWithSpanKind(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar", WithTracerProvider(provider)))
+	router.Route("/internal", func(r chi.Router) {
+		r.Use(Middleware("foobar", WithTracerProvider(provider), WithSpanKind(trace.SpanKindInternal)))
+		r.HandleFunc("/stats", ok)
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/internal/stats", nil))
+
+	spans := sr.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, trace.SpanKindInternal, spans[0].SpanKind())
+	assert.Equal(t, trace.SpanKindServer, spans[1].SpanKind())
+	assert.Equal(t, spans[1].SpanContext().SpanID(), spans[0].Parent().SpanID())
+}
+
+func TestSDKIntegrationWithFilteredPropagation(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar",
+		WithTracerProvider(provider),
+		WithPropagators(propagation.TraceContext{}),
+		WithHealthCheckFilter(),
+		WithFilteredPropagation(),
+	))
+	var handlerCtx trace.SpanContext
+	router.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		handlerCtx = trace.SpanContextFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})
+
+	r := httptest.NewRequest("GET", "/healthz", nil)
+	r.Header.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	assert.Len(t, sr.Ended(), 0)
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", handlerCtx.TraceID().String())
+	assert.Equal(t, "b7ad6b7169203331", handlerCtx.SpanID().String())
+	assert.True(t, handlerCtx.IsRemote())
+}
+
+// nameRecordingSampler samples every span, recording the names the spans
+// start with.
+type nameRecordingSampler struct {
+	names []string
+}
+
+func (s *nameRecordingSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	s.names = append(s.names, p.Name)
+	return sdktrace.AlwaysSample().ShouldSample(p)
+}
+
+func (s *nameRecordingSampler) Description() string {
+	return "nameRecordingSampler"
+}
+
+func TestSDKIntegrationWithLazyRouteNaming(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		opts      func(router chi.Routes) []Option
+		startName string
+	}{
+		{
+			name: "lazy",
+			opts: func(router chi.Routes) []Option {
+				return []Option{WithChiRoutes(router), WithLazyRouteNaming()}
+			},
+			startName: "",
+		},
+		{
+			name: "sampler hint",
+			opts: func(router chi.Routes) []Option {
+				return []Option{WithRouteSamplerHint(router), WithLazyRouteNaming()}
+			},
+			startName: "/user/{id:[0-9]+}",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			sampler := &nameRecordingSampler{}
+			sr := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSampler(sampler))
+			provider.RegisterSpanProcessor(sr)
+
+			router := chi.NewRouter()
+			router.Use(Middleware("foobar", append(tc.opts(router), WithTracerProvider(provider))...))
+			router.HandleFunc("/user/{id:[0-9]+}", ok)
+
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/user/123", nil))
+
+			assert.Equal(t, []string{tc.startName}, sampler.names)
+			require.Len(t, sr.Ended(), 1)
+			assertSpan(t, sr.Ended()[0], "/user/{id:[0-9]+}", trace.SpanKindServer,
+				attribute.String("http.route", "/user/{id:[0-9]+}"),
+			)
+		})
+	}
+}
+
+func TestSDKIntegrationWithRequestAttributesFn(t *testing.T) {
+	sampler := &attributesRecordingSampler{}
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSampler(sampler))
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar",
+		WithTracerProvider(provider),
+		WithRequestAttributesFn(func(r *http.Request) []attribute.KeyValue {
+			return []attribute.KeyValue{attribute.String("tenant.id", r.Header.Get("X-Tenant"))}
+		}),
+		WithRequestAttributesFn(func(r *http.Request) []attribute.KeyValue {
+			return []attribute.KeyValue{attribute.String("http.target", "/redacted")}
+		}),
+	))
+	router.HandleFunc("/user/{id}", ok)
+
+	r := httptest.NewRequest("GET", "/user/123?token=secret", nil)
+	r.Header.Set("X-Tenant", "acme")
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	assert.Contains(t, sampler.attributes, attribute.String("tenant.id", "acme"))
+	spans := sr.Ended()
+	require.Len(t, spans, 1)
+	assertSpan(t, spans[0], "/user/{id}", trace.SpanKindServer,
+		attribute.String("tenant.id", "acme"),
+		attribute.String("http.target", "/redacted"),
+	)
+}
+
+// attributesRecordingSampler samples every span, recording the attributes
+// the last span started with.
+type attributesRecordingSampler struct {
+	attributes []attribute.KeyValue
+}
+
+func (s *attributesRecordingSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	s.attributes = p.Attributes
+	return sdktrace.AlwaysSample().ShouldSample(p)
+}
+
+func (s *attributesRecordingSampler) Description() string {
+	return "attributesRecordingSampler"
+}
+
+func BenchmarkMiddleware(b *testing.B) {
+	for _, bc := range []struct {
+		name string
+		opts func(router chi.Routes) []Option
+	}{
+		{
+			name: "metadata only",
+			opts: func(chi.Routes) []Option { return []Option{WithMetadataOnly(true)} },
+		},
+		{
+			name: "metadata only with chi routes",
+			opts: func(router chi.Routes) []Option {
+				return []Option{WithMetadataOnly(true), WithChiRoutes(router)}
+			},
+		},
+		{
+			name: "minimal attributes",
+			opts: func(router chi.Routes) []Option {
+				return []Option{WithMinimalAttributes(), WithChiRoutes(router)}
+			},
+		},
+		{
+			name: "capture",
+			opts: func(router chi.Routes) []Option {
+				return []Option{WithMetadataOnly(false), WithChiRoutes(router)}
+			},
+		},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			provider := sdktrace.NewTracerProvider()
+			router := chi.NewRouter()
+			router.Use(Middleware("foobar", append(bc.opts(router), WithTracerProvider(provider))...))
+			router.HandleFunc("/user/{id}", func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("ok"))
+			})
+			r := httptest.NewRequest("GET", "/user/123", nil)
+			w := &discardResponseWriter{header: http.Header{}}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for k := range w.header {
+					delete(w.header, k)
+				}
+				router.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// discardResponseWriter is a reusable http.ResponseWriter, so benchmarks
+// only measure the allocations of the middleware.
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func (w *discardResponseWriter) Header() http.Header         { return w.header }
+func (w *discardResponseWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *discardResponseWriter) WriteHeader(int)             {}
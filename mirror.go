@@ -0,0 +1,45 @@
+package otelchi
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// mirrorSpan is the span of a request traced by both the primary and the
+// secondary tracer providers. It behaves as the primary span, and mirrors
+// every change to the secondary span.
+type mirrorSpan struct {
+	oteltrace.Span
+	secondary oteltrace.Span
+}
+
+func (s *mirrorSpan) End(options ...oteltrace.SpanEndOption) {
+	s.Span.End(options...)
+	s.secondary.End(options...)
+}
+
+func (s *mirrorSpan) AddEvent(name string, options ...oteltrace.EventOption) {
+	s.Span.AddEvent(name, options...)
+	s.secondary.AddEvent(name, options...)
+}
+
+func (s *mirrorSpan) RecordError(err error, options ...oteltrace.EventOption) {
+	s.Span.RecordError(err, options...)
+	s.secondary.RecordError(err, options...)
+}
+
+func (s *mirrorSpan) SetStatus(code codes.Code, description string) {
+	s.Span.SetStatus(code, description)
+	s.secondary.SetStatus(code, description)
+}
+
+func (s *mirrorSpan) SetName(name string) {
+	s.Span.SetName(name)
+	s.secondary.SetName(name)
+}
+
+func (s *mirrorSpan) SetAttributes(kv ...attribute.KeyValue) {
+	s.Span.SetAttributes(kv...)
+	s.secondary.SetAttributes(kv...)
+}
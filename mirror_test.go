@@ -0,0 +1,62 @@
+package otelchi
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSDKIntegrationWithSecondaryTracerProvider(t *testing.T) {
+	primaryRecorder := tracetest.NewSpanRecorder()
+	primary := sdktrace.NewTracerProvider()
+	primary.RegisterSpanProcessor(primaryRecorder)
+	secondaryRecorder := tracetest.NewSpanRecorder()
+	secondary := sdktrace.NewTracerProvider()
+	secondary.RegisterSpanProcessor(secondaryRecorder)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar",
+		WithTracerProvider(primary),
+		WithMinimalAttributes(),
+		WithSecondaryTracerProvider(secondary, true),
+	))
+	router.HandleFunc("/user/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		trace.SpanFromContext(r.Context()).AddEvent("handled")
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	r := httptest.NewRequest("POST", "/user/123", strings.NewReader(`{"name": "foo"}`))
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	require.Len(t, primaryRecorder.Ended(), 1)
+	require.Len(t, secondaryRecorder.Ended(), 1)
+	primarySpan, secondarySpan := primaryRecorder.Ended()[0], secondaryRecorder.Ended()[0]
+
+	for _, span := range []sdktrace.ReadOnlySpan{primarySpan, secondarySpan} {
+		assertSpan(t, span, "/user/{id}", trace.SpanKindServer,
+			attribute.String("http.route", "/user/{id}"),
+			attribute.Int("http.status_code", http.StatusInternalServerError),
+		)
+		assert.Equal(t, codes.Error, span.Status().Code)
+		require.Len(t, span.Events(), 1)
+		assert.Equal(t, "handled", span.Events()[0].Name)
+	}
+	assertSpan(t, secondarySpan, "/user/{id}", trace.SpanKindServer,
+		attribute.String("http.request.body", `{"name": "foo"}`),
+	)
+	for _, kv := range primarySpan.Attributes() {
+		assert.NotEqual(t, attribute.Key("http.request.body"), kv.Key)
+	}
+}
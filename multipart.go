@@ -0,0 +1,126 @@
+package otelchi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"sort"
+
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+const (
+	multipartFieldNamesKey       = attribute.Key("http.request.multipart.field_names")
+	multipartFileFieldNamesKey   = attribute.Key("http.request.multipart.file.field_names")
+	multipartFileNamesKey        = attribute.Key("http.request.multipart.file.names")
+	multipartFileSizesKey        = attribute.Key("http.request.multipart.file.sizes")
+	multipartFileContentTypesKey = attribute.Key("http.request.multipart.file.content_types")
+)
+
+const (
+	multipartFileEventName = "http.request.multipart.file"
+
+	multipartFileFieldKey       = attribute.Key("http.request.multipart.file.field_name")
+	multipartFileNameKey        = attribute.Key("http.request.multipart.file.name")
+	multipartFileSizeKey        = attribute.Key("http.request.multipart.file.size")
+	multipartFileContentTypeKey = attribute.Key("http.request.multipart.file.content_type")
+	multipartFileSHA256Key      = attribute.Key("http.request.multipart.file.sha256")
+)
+
+// collectMultipartMetadata records the structure of a multipart/form-data
+// request body instead of its content. The request body itself is never
+// buffered for multipart requests, so the metadata is taken from the form
+// parsed by the handler; nothing is recorded if the handler did not parse it.
+func collectMultipartMetadata(r *http.Request, span oteltrace.Span) {
+	form := r.MultipartForm
+	if form == nil {
+		return
+	}
+
+	if len(form.Value) > 0 {
+		names := make([]string, 0, len(form.Value))
+		for name := range form.Value {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		span.SetAttributes(multipartFieldNamesKey.StringSlice(names))
+	}
+
+	if len(form.File) == 0 {
+		return
+	}
+	fields := make([]string, 0, len(form.File))
+	for field := range form.File {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	var (
+		fieldNames   []string
+		fileNames    []string
+		sizes        []int64
+		contentTypes []string
+	)
+	for _, field := range fields {
+		for _, fh := range form.File[field] {
+			fieldNames = append(fieldNames, field)
+			fileNames = append(fileNames, fh.Filename)
+			sizes = append(sizes, fh.Size)
+			contentTypes = append(contentTypes, fh.Header.Get("Content-Type"))
+		}
+	}
+	span.SetAttributes(
+		multipartFileFieldNamesKey.StringSlice(fieldNames),
+		multipartFileNamesKey.StringSlice(fileNames),
+		multipartFileSizesKey.Int64Slice(sizes),
+		multipartFileContentTypesKey.StringSlice(contentTypes),
+	)
+}
+
+// addMultipartFileEvents adds a span event per file uploaded in the
+// multipart form parsed by the handler, with its SHA-256 digest. The files
+// are streamed from the parsed form, from memory or from the temporary
+// files of the form, and are never buffered in the span.
+func addMultipartFileEvents(r *http.Request, span oteltrace.Span) {
+	form := r.MultipartForm
+	if form == nil || len(form.File) == 0 {
+		return
+	}
+	fields := make([]string, 0, len(form.File))
+	for field := range form.File {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		for _, fh := range form.File[field] {
+			attrs := []attribute.KeyValue{
+				multipartFileFieldKey.String(field),
+				multipartFileNameKey.String(fh.Filename),
+				multipartFileSizeKey.Int64(fh.Size),
+				multipartFileContentTypeKey.String(fh.Header.Get("Content-Type")),
+			}
+			if digest, err := fileSHA256(fh); err == nil {
+				attrs = append(attrs, multipartFileSHA256Key.String(digest))
+			}
+			span.AddEvent(multipartFileEventName, oteltrace.WithAttributes(attrs...))
+		}
+	}
+}
+
+func fileSHA256(fh *multipart.FileHeader) (string, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,52 @@
+package otelchi
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/ohler55/ojg/jp"
+	"go.opentelemetry.io/otel"
+)
+
+// OperationNameFunc returns the logical operation served by a request to an
+// RPC-style route, given its request body, or "" if it can't tell. The body
+// holds what the handler read from the request.
+type OperationNameFunc func(r *http.Request, body []byte) string
+
+// OperationNameFromJSON returns an OperationNameFunc naming operations after
+// the value of a JSONPath expression in the JSON request body, with the
+// given prefix, e.g. OperationNameFromJSON("rpc ", "$.method") names a
+// request whose body is {"method": "UserService/GetUser"} as
+// "rpc UserService/GetUser". An invalid expression is reported to the
+// global OTel error handler and names no operation.
+func OperationNameFromJSON(prefix, path string) OperationNameFunc {
+	expr, err := jp.ParseString(path)
+	if err != nil {
+		otel.Handle(fmt.Errorf("otelchi: invalid JSONPath expression %q for operation name: %w", path, err))
+		return func(*http.Request, []byte) string { return "" }
+	}
+	return func(_ *http.Request, body []byte) string {
+		data, ok := parseJSONBody(body)
+		if !ok {
+			return ""
+		}
+		results := expr.Get(data)
+		if len(results) == 0 {
+			return ""
+		}
+		name, ok := results[0].(string)
+		if !ok || name == "" {
+			return ""
+		}
+		return prefix + name
+	}
+}
+
+// operationNamer returns the OperationNameFunc of a route, matched either
+// as "METHOD pattern" or as the bare pattern.
+func (tw traceware) operationNamer(method, routePattern string) OperationNameFunc {
+	if fn, ok := tw.operationNames[method+" "+routePattern]; ok {
+		return fn
+	}
+	return tw.operationNames[routePattern]
+}
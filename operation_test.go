@@ -0,0 +1,53 @@
+package otelchi
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestOperationNameFromJSON(t *testing.T) {
+	namer := OperationNameFromJSON("rpc ", "$.method")
+
+	assert.Equal(t, "rpc UserService/GetUser", namer(nil, []byte(`{"method": "UserService/GetUser"}`)))
+	assert.Equal(t, "", namer(nil, []byte(`{"method": 42}`)))
+	assert.Equal(t, "", namer(nil, []byte(`{"id": 1}`)))
+	assert.Equal(t, "", namer(nil, []byte(`not json`)))
+}
+
+func TestSDKIntegrationWithOperationNames(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar",
+		WithTracerProvider(provider),
+		WithOperationNames(map[string]OperationNameFunc{
+			"POST /internal/rpc": OperationNameFromJSON("rpc ", "$.method"),
+		}),
+	))
+	router.HandleFunc("/internal/rpc", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for _, body := range []string{`{"method": "UserService/GetUser"}`, `{"method": "UserService/ListUsers"}`, `{}`} {
+		r := httptest.NewRequest("POST", "/internal/rpc", strings.NewReader(body))
+		router.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	spans := sr.Ended()
+	require.Len(t, spans, 3)
+	assert.Equal(t, "rpc UserService/GetUser", spans[0].Name())
+	assert.Equal(t, "rpc UserService/ListUsers", spans[1].Name())
+	assert.Equal(t, "/internal/rpc", spans[2].Name())
+}
@@ -0,0 +1,44 @@
+// Package otelchilogrus correlates logrus logs with the requests traced by
+// the otelchi middleware.
+package otelchilogrus
+
+import (
+	"context"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/helios/otelchi"
+	"github.com/sirupsen/logrus"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// Fields returns the fields joining the records logged while serving a
+// request to its trace: the trace and span IDs, the chi route and the
+// server name of the middleware. No fields are returned when ctx holds no
+// span.
+//
+//	logger.WithFields(otelchilogrus.Fields(r.Context())).Info("user created")
+func Fields(ctx context.Context) logrus.Fields {
+	spanCtx := oteltrace.SpanContextFromContext(ctx)
+	if !spanCtx.IsValid() {
+		return nil
+	}
+	fields := logrus.Fields{
+		otelchi.LogTraceIDKey: spanCtx.TraceID().String(),
+		otelchi.LogSpanIDKey:  spanCtx.SpanID().String(),
+	}
+	if rctx := chi.RouteContext(ctx); rctx != nil {
+		if pattern := rctx.RoutePattern(); pattern != "" {
+			fields[otelchi.LogRouteKey] = pattern
+		}
+	}
+	if serverName, ok := otelchi.ServerNameFromContext(ctx); ok {
+		fields[otelchi.LogServerNameKey] = serverName
+	}
+	return fields
+}
+
+// Entry returns an entry of logger with the fields of ctx added, and ctx
+// set as its context.
+func Entry(ctx context.Context, logger logrus.FieldLogger) *logrus.Entry {
+	return logger.WithFields(Fields(ctx)).WithContext(ctx)
+}
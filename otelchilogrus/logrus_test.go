@@ -0,0 +1,47 @@
+package otelchilogrus
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/helios/otelchi"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestEntry(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	logger, hook := test.NewNullLogger()
+
+	router := chi.NewRouter()
+	router.Use(otelchi.Middleware("foobar", otelchi.WithTracerProvider(provider)))
+	router.HandleFunc("/user/{id}", func(w http.ResponseWriter, r *http.Request) {
+		Entry(r.Context(), logger).Info("user fetched")
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/user/123", nil))
+	require.Len(t, sr.Ended(), 1)
+	spanCtx := sr.Ended()[0].SpanContext()
+
+	require.Len(t, hook.Entries, 1)
+	assert.Equal(t, logrus.Fields{
+		"trace_id":         spanCtx.TraceID().String(),
+		"span_id":          spanCtx.SpanID().String(),
+		"http.route":       "/user/{id}",
+		"http.server_name": "foobar",
+	}, hook.LastEntry().Data)
+}
+
+func TestFieldsWithoutSpan(t *testing.T) {
+	assert.Empty(t, Fields(context.Background()))
+}
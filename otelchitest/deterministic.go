@@ -0,0 +1,62 @@
+package otelchitest
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SequentialIDs returns an ID generator numbering the traces and the spans
+// from 1, so that tests of the emitted spans, and of the traceresponse
+// header, are reproducible:
+//
+//	rec := otelchitest.NewRecorder(sdktrace.WithIDGenerator(otelchitest.SequentialIDs()))
+func SequentialIDs() sdktrace.IDGenerator {
+	return &sequentialIDs{}
+}
+
+type sequentialIDs struct {
+	mu     sync.Mutex
+	traces uint64
+	spans  uint64
+}
+
+func (g *sequentialIDs) NewIDs(ctx context.Context) (trace.TraceID, trace.SpanID) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.traces++
+	var traceID trace.TraceID
+	binary.BigEndian.PutUint64(traceID[8:], g.traces)
+	return traceID, g.newSpanID()
+}
+
+func (g *sequentialIDs) NewSpanID(ctx context.Context, traceID trace.TraceID) trace.SpanID {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.newSpanID()
+}
+
+func (g *sequentialIDs) newSpanID() trace.SpanID {
+	g.spans++
+	var spanID trace.SpanID
+	binary.BigEndian.PutUint64(spanID[:], g.spans)
+	return spanID
+}
+
+// SteppingClock returns a clock telling start the first time it is called,
+// and advancing by step on every call, for otelchi.WithClock.
+func SteppingClock(start time.Time, step time.Duration) func() time.Time {
+	var mu sync.Mutex
+	next := start
+	return func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		now := next
+		next = next.Add(step)
+		return now
+	}
+}
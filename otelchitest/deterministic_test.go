@@ -0,0 +1,45 @@
+package otelchitest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/helios/otelchi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestDeterministicSpans(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	rec := NewRecorder(sdktrace.WithIDGenerator(SequentialIDs()))
+	router := chi.NewRouter()
+	router.Use(rec.Middleware("foobar", otelchi.WithClock(SteppingClock(start, time.Millisecond))))
+	router.HandleFunc("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		trace.SpanFromContext(r.Context()).AddEvent("handled")
+	})
+
+	var headers []string
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/users/123", nil))
+		headers = append(headers, w.Header().Get("Traceresponse"))
+	}
+
+	assert.Equal(t, []string{
+		"00-00000000000000000000000000000001-0000000000000001-01",
+		"00-00000000000000000000000000000002-0000000000000002-01",
+	}, headers)
+	spans := rec.Spans()
+	require.Len(t, spans, 2)
+	assert.Equal(t, start, spans[0].StartTime())
+	assert.True(t, spans[0].EndTime().After(spans[0].StartTime()))
+	require.Len(t, spans[0].Events(), 1)
+	assert.True(t, spans[0].Events()[0].Time.After(spans[0].StartTime()))
+	assert.True(t, spans[0].Events()[0].Time.Before(spans[0].EndTime()))
+	assert.Equal(t, spans[0].EndTime().Sub(spans[0].StartTime()), spans[1].EndTime().Sub(spans[1].StartTime()))
+}
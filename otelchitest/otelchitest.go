@@ -0,0 +1,198 @@
+// Package otelchitest helps testing the spans of the requests traced by the
+// otelchi middleware, recording them in memory.
+//
+//	rec := otelchitest.NewRecorder()
+//	router := chi.NewRouter()
+//	router.Use(rec.Middleware("my-server"))
+//	...
+//	rec.RequireSpan(t, "/users/{id}",
+//		otelchitest.HasAttribute(attribute.Int("http.status_code", 200)),
+//		otelchitest.RequestBody(`{"name":"alice"}`),
+//	)
+package otelchitest
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/helios/otelchi"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// The attributes recording the captured bodies, unless renamed with
+// otelchi.WithAttributeKeys.
+const (
+	requestBodyKey  = attribute.Key("http.request.body")
+	responseBodyKey = attribute.Key("http.response.body")
+)
+
+// Recorder records the spans of the requests traced by its middleware.
+type Recorder struct {
+	spans    *tracetest.SpanRecorder
+	provider *sdktrace.TracerProvider
+}
+
+// NewRecorder returns a Recorder with no spans recorded yet, whose tracer
+// provider is configured by opts, e.g. to generate deterministic IDs, see
+// SequentialIDs.
+func NewRecorder(opts ...sdktrace.TracerProviderOption) *Recorder {
+	spans := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(opts...)
+	provider.RegisterSpanProcessor(spans)
+	return &Recorder{spans: spans, provider: provider}
+}
+
+// TracerProvider returns the tracer provider recording the spans.
+func (rec *Recorder) TracerProvider() *sdktrace.TracerProvider {
+	return rec.provider
+}
+
+// Middleware returns the otelchi middleware configured by opts, recording
+// its spans in rec.
+func (rec *Recorder) Middleware(serverName string, opts ...otelchi.Option) func(http.Handler) http.Handler {
+	return otelchi.Middleware(serverName, append(opts[:len(opts):len(opts)], otelchi.WithTracerProvider(rec.provider))...)
+}
+
+// Spans returns the spans ended so far, in the order they ended.
+func (rec *Recorder) Spans() []sdktrace.ReadOnlySpan {
+	return rec.spans.Ended()
+}
+
+// RequireSpan returns the first span named name, and fails the test now
+// unless there is one and it matches all the matchers.
+func (rec *Recorder) RequireSpan(t testing.TB, name string, matchers ...Matcher) sdktrace.ReadOnlySpan {
+	t.Helper()
+	spans := rec.Spans()
+	names := make([]string, 0, len(spans))
+	for _, span := range spans {
+		if span.Name() != name {
+			names = append(names, span.Name())
+			continue
+		}
+		var mismatches []string
+		for _, matcher := range matchers {
+			if err := matcher(span); err != nil {
+				mismatches = append(mismatches, err.Error())
+			}
+		}
+		if len(mismatches) > 0 {
+			t.Fatalf("span %q does not match:\n\t%s", name, strings.Join(mismatches, "\n\t"))
+		}
+		return span
+	}
+	t.Fatalf("no span %q among the spans ended: %q", name, names)
+	return nil
+}
+
+// Matcher checks a span, returning an error describing how it does not
+// match.
+type Matcher func(span sdktrace.ReadOnlySpan) error
+
+// HasAttribute matches the spans with the attribute kv.
+func HasAttribute(kv attribute.KeyValue) Matcher {
+	return func(span sdktrace.ReadOnlySpan) error {
+		value, ok := attributeValue(span.Attributes(), kv.Key)
+		if !ok {
+			return fmt.Errorf("no attribute %s", kv.Key)
+		}
+		if value != kv.Value {
+			return fmt.Errorf("attribute %s is %s, not %s", kv.Key, value.Emit(), kv.Value.Emit())
+		}
+		return nil
+	}
+}
+
+// HasAttributeKey matches the spans with an attribute of the given key,
+// whatever its value.
+func HasAttributeKey(key attribute.Key) Matcher {
+	return func(span sdktrace.ReadOnlySpan) error {
+		if _, ok := attributeValue(span.Attributes(), key); !ok {
+			return fmt.Errorf("no attribute %s", key)
+		}
+		return nil
+	}
+}
+
+// LacksAttribute matches the spans without an attribute of the given key.
+func LacksAttribute(key attribute.Key) Matcher {
+	return func(span sdktrace.ReadOnlySpan) error {
+		if value, ok := attributeValue(span.Attributes(), key); ok {
+			return fmt.Errorf("unexpected attribute %s: %s", key, value.Emit())
+		}
+		return nil
+	}
+}
+
+// RequestBody matches the spans with the captured request body body,
+// recorded as an attribute or as an event, see otelchi.WithBodiesAsEvents.
+func RequestBody(body string) Matcher {
+	return bodyMatcher(requestBodyKey, body)
+}
+
+// ResponseBody matches the spans with the captured response body body,
+// recorded as an attribute or as an event, see otelchi.WithBodiesAsEvents.
+func ResponseBody(body string) Matcher {
+	return bodyMatcher(responseBodyKey, body)
+}
+
+// NoBodyCapture matches the spans without captured request or response
+// body.
+func NoBodyCapture() Matcher {
+	return func(span sdktrace.ReadOnlySpan) error {
+		for _, key := range []attribute.Key{requestBodyKey, responseBodyKey} {
+			if body, ok := capturedBody(span, key); ok {
+				return fmt.Errorf("unexpected %s %q", key, body)
+			}
+		}
+		return nil
+	}
+}
+
+func bodyMatcher(key attribute.Key, body string) Matcher {
+	return func(span sdktrace.ReadOnlySpan) error {
+		captured, ok := capturedBody(span, key)
+		if !ok {
+			return fmt.Errorf("no %s captured", key)
+		}
+		if captured != body {
+			return fmt.Errorf("%s is %q, not %q", key, captured, body)
+		}
+		return nil
+	}
+}
+
+// capturedBody returns the body recorded under key in the attributes or
+// the events of span.
+func capturedBody(span sdktrace.ReadOnlySpan, key attribute.Key) (string, bool) {
+	if value, ok := attributeValue(span.Attributes(), key); ok {
+		return value.AsString(), true
+	}
+	for _, event := range span.Events() {
+		if event.Name != string(key) {
+			continue
+		}
+		if value, ok := attributeValue(event.Attributes, key); ok {
+			return value.AsString(), true
+		}
+	}
+	return "", false
+}
+
+// attributeValue returns the last value of key in attrs, which is the one
+// kept by the SDK.
+func attributeValue(attrs []attribute.KeyValue, key attribute.Key) (attribute.Value, bool) {
+	var (
+		value attribute.Value
+		found bool
+	)
+	for _, kv := range attrs {
+		if kv.Key == key {
+			value, found = kv.Value, true
+		}
+	}
+	return value, found
+}
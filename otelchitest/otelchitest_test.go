@@ -0,0 +1,83 @@
+package otelchitest
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/helios/otelchi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// fatalRecorder records the failures of a test instead of failing it.
+type fatalRecorder struct {
+	testing.TB
+	failures []string
+}
+
+func (t *fatalRecorder) Helper() {}
+
+func (t *fatalRecorder) Fatalf(format string, args ...interface{}) {
+	t.failures = append(t.failures, fmt.Sprintf(format, args...))
+}
+
+func TestRecorder(t *testing.T) {
+	rec := NewRecorder()
+	router := chi.NewRouter()
+	router.Use(rec.Middleware("foobar", otelchi.WithRequestMethodInSpanName(true)))
+	router.HandleFunc("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		_, _ = w.Write([]byte("done"))
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("PUT", "/users/123", strings.NewReader(`{"name":"alice"}`)))
+
+	require.Len(t, rec.Spans(), 1)
+	span := rec.RequireSpan(t, "PUT /users/{id}",
+		HasAttribute(attribute.Int("http.status_code", http.StatusOK)),
+		HasAttributeKey("http.target"),
+		LacksAttribute("enduser.id"),
+		RequestBody(`{"name":"alice"}`),
+		ResponseBody("done"),
+	)
+	assert.Equal(t, rec.Spans()[0], span)
+
+	ft := &fatalRecorder{TB: t}
+	rec.RequireSpan(ft, "GET /users/{id}")
+	rec.RequireSpan(ft, "PUT /users/{id}",
+		HasAttribute(attribute.Int("http.status_code", http.StatusCreated)),
+		LacksAttribute("http.target"),
+		RequestBody("{}"),
+		NoBodyCapture(),
+	)
+	assert.Equal(t, []string{
+		`no span "GET /users/{id}" among the spans ended: ["PUT /users/{id}"]`,
+		`span "PUT /users/{id}" does not match:` +
+			"\n\tattribute http.status_code is 200, not 201" +
+			"\n\tunexpected attribute http.target: /users/123" +
+			"\n\thttp.request.body is \"{\\\"name\\\":\\\"alice\\\"}\", not \"{}\"" +
+			"\n\tunexpected http.request.body \"{\\\"name\\\":\\\"alice\\\"}\"",
+	}, ft.failures)
+}
+
+func TestRecorderBodiesAsEvents(t *testing.T) {
+	rec := NewRecorder()
+	router := chi.NewRouter()
+	router.Use(rec.Middleware("foobar", otelchi.WithBodiesAsEvents()))
+	router.HandleFunc("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("done"))
+	})
+	router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/123", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/health", nil))
+
+	rec.RequireSpan(t, "/users/{id}", ResponseBody("done"))
+	rec.RequireSpan(t, "/health", NoBodyCapture())
+}
@@ -0,0 +1,48 @@
+// Package otelchizap correlates zap logs with the requests traced by the
+// otelchi middleware.
+package otelchizap
+
+import (
+	"context"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/helios/otelchi"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// Fields returns the fields joining the records logged while serving a
+// request to its trace: the trace and span IDs, the chi route and the
+// server name of the middleware. No fields are returned when ctx holds no
+// span.
+//
+//	logger.Info("user created", otelchizap.Fields(r.Context())...)
+func Fields(ctx context.Context) []zap.Field {
+	spanCtx := oteltrace.SpanContextFromContext(ctx)
+	if !spanCtx.IsValid() {
+		return nil
+	}
+	fields := make([]zap.Field, 0, 4)
+	fields = append(fields,
+		zap.String(otelchi.LogTraceIDKey, spanCtx.TraceID().String()),
+		zap.String(otelchi.LogSpanIDKey, spanCtx.SpanID().String()),
+	)
+	if rctx := chi.RouteContext(ctx); rctx != nil {
+		if pattern := rctx.RoutePattern(); pattern != "" {
+			fields = append(fields, zap.String(otelchi.LogRouteKey, pattern))
+		}
+	}
+	if serverName, ok := otelchi.ServerNameFromContext(ctx); ok {
+		fields = append(fields, zap.String(otelchi.LogServerNameKey, serverName))
+	}
+	return fields
+}
+
+// Logger returns logger with the fields of ctx added.
+func Logger(ctx context.Context, logger *zap.Logger) *zap.Logger {
+	fields := Fields(ctx)
+	if len(fields) == 0 {
+		return logger
+	}
+	return logger.With(fields...)
+}
@@ -0,0 +1,48 @@
+package otelchizap
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/helios/otelchi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLogger(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	core, logs := observer.New(zap.InfoLevel)
+	logger := zap.New(core)
+
+	router := chi.NewRouter()
+	router.Use(otelchi.Middleware("foobar", otelchi.WithTracerProvider(provider)))
+	router.HandleFunc("/user/{id}", func(w http.ResponseWriter, r *http.Request) {
+		Logger(r.Context(), logger).Info("user fetched")
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/user/123", nil))
+	require.Len(t, sr.Ended(), 1)
+	spanCtx := sr.Ended()[0].SpanContext()
+
+	require.Equal(t, 1, logs.Len())
+	assert.Equal(t, map[string]interface{}{
+		"trace_id":         spanCtx.TraceID().String(),
+		"span_id":          spanCtx.SpanID().String(),
+		"http.route":       "/user/{id}",
+		"http.server_name": "foobar",
+	}, logs.All()[0].ContextMap())
+}
+
+func TestFieldsWithoutSpan(t *testing.T) {
+	assert.Empty(t, Fields(context.Background()))
+}
@@ -0,0 +1,91 @@
+package otelchi
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+const (
+	stageEventName   = "otelchi.stage"
+	stageNameKey     = attribute.Key("otelchi.stage.name")
+	stageDurationKey = attribute.Key("otelchi.stage.duration_ms")
+)
+
+type spanOwnerKey struct{}
+
+// SpanOwner gives other middlewares access to the server span started by
+// otelchi for the request being served. Middlewares such as auth, rate
+// limiting or tenant resolution can use it to attach their attributes and
+// timings to the server span instead of starting spans of their own.
+//
+// Unlike trace.SpanFromContext, a SpanOwner always refers to the server
+// span, even when a middleware in between has put a child span into the
+// request context.
+//
+// A SpanOwner is only valid until the otelchi middleware returns. It must
+// not be retained after the request is complete.
+type SpanOwner interface {
+	// SpanContext returns the span context of the server span.
+	SpanContext() oteltrace.SpanContext
+	// SetAttributes sets attributes on the server span.
+	SetAttributes(kv ...attribute.KeyValue)
+	// AddEvent adds an event to the server span.
+	AddEvent(name string, opts ...oteltrace.EventOption)
+	// StartStage marks the start of a named processing stage, e.g.
+	// "auth". Calling the returned function ends the stage and records an
+	// otelchi.stage event carrying the stage name and its duration on the
+	// server span. Extra attributes given to the returned function are
+	// added to the event.
+	StartStage(name string) (end func(kv ...attribute.KeyValue))
+}
+
+// SpanOwnerFromContext returns the SpanOwner of the request being traced
+// by the middleware, if any.
+func SpanOwnerFromContext(ctx context.Context) (SpanOwner, bool) {
+	owner, ok := ctx.Value(spanOwnerKey{}).(*spanOwner)
+	return owner, ok
+}
+
+func contextWithSpanOwner(ctx context.Context, owner *spanOwner) context.Context {
+	return context.WithValue(ctx, spanOwnerKey{}, owner)
+}
+
+type spanOwner struct {
+	span       oteltrace.Span
+	serverName string
+	// timeout is set by Timeout when it cut off the handler
+	timeout time.Duration
+	// err is the error reported by RecordError, and stack its stack
+	// trace, if captured
+	err         error
+	stack       string
+	stackTraces *stackTraces
+	clock       func() time.Time
+}
+
+func (o *spanOwner) SpanContext() oteltrace.SpanContext {
+	return o.span.SpanContext()
+}
+
+func (o *spanOwner) SetAttributes(kv ...attribute.KeyValue) {
+	o.span.SetAttributes(kv...)
+}
+
+func (o *spanOwner) AddEvent(name string, opts ...oteltrace.EventOption) {
+	o.span.AddEvent(name, opts...)
+}
+
+func (o *spanOwner) StartStage(name string) func(kv ...attribute.KeyValue) {
+	start := now(o.clock)
+	return func(kv ...attribute.KeyValue) {
+		end := now(o.clock)
+		attrs := append([]attribute.KeyValue{
+			stageNameKey.String(name),
+			stageDurationKey.Float64(float64(end.Sub(start)) / float64(time.Millisecond)),
+		}, kv...)
+		o.span.AddEvent(stageEventName, oteltrace.WithTimestamp(end), oteltrace.WithAttributes(attrs...))
+	}
+}
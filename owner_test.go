@@ -0,0 +1,63 @@
+package otelchi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSDKIntegrationWithSpanOwner(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar", WithTracerProvider(provider)))
+	// an auth middleware which puts a child span into the context before
+	// reporting onto the server span
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			owner, ok := SpanOwnerFromContext(r.Context())
+			require.True(t, ok)
+			end := owner.StartStage("auth")
+			ctx, child := provider.Tracer("auth").Start(r.Context(), "lookup")
+			child.End()
+			owner.SetAttributes(attribute.String("enduser.id", "123"))
+			end(attribute.Bool("auth.cached", false))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	})
+	router.HandleFunc("/user/{id}", func(w http.ResponseWriter, r *http.Request) {
+		owner, ok := SpanOwnerFromContext(r.Context())
+		require.True(t, ok)
+		assert.NotEqual(t, trace.SpanFromContext(r.Context()).SpanContext(), owner.SpanContext())
+		owner.AddEvent("handled")
+	})
+
+	r := httptest.NewRequest("GET", "/user/123", nil)
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	spans := sr.Ended()
+	require.Len(t, spans, 2)
+	server := spans[1]
+	assertSpan(t, server, "/user/{id}", trace.SpanKindServer,
+		attribute.String("enduser.id", "123"),
+	)
+	events := server.Events()
+	require.Len(t, events, 2)
+	assert.Equal(t, "otelchi.stage", events[0].Name)
+	assert.Contains(t, events[0].Attributes, attribute.String("otelchi.stage.name", "auth"))
+	assert.Contains(t, events[0].Attributes, attribute.Bool("auth.cached", false))
+	assert.Equal(t, "handled", events[1].Name)
+
+	_, ok := SpanOwnerFromContext(r.Context())
+	assert.False(t, ok)
+}
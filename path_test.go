@@ -0,0 +1,21 @@
+package otelchi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizePath(t *testing.T) {
+	testCases := map[string]string{
+		"":                    "",
+		"/":                   "/",
+		"/users/42":           "/users/{id}",
+		"/users/42/orders/7/": "/users/{id}/orders/{id}/",
+		"/v2/files/0f8fad5b-d9cb-469f-a165-70867728950e": "/v2/files/{id}",
+		"/v2/files/latest": "/v2/files/latest",
+	}
+	for path, want := range testCases {
+		assert.Equal(t, want, NormalizePath(path), path)
+	}
+}
@@ -0,0 +1,72 @@
+package otelchi
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// PayloadLogRecord is a captured body emitted as a log record correlated
+// with the span of the request.
+type PayloadLogRecord struct {
+	Timestamp   time.Time
+	SpanContext oteltrace.SpanContext
+	// Name is the attribute the body would otherwise have been recorded
+	// in, i.e. "http.request.body" or "http.response.body".
+	Name string
+	Body string
+	// Attributes describe the request the body belongs to: its method,
+	// route, response status code and the content type of the body.
+	Attributes []attribute.KeyValue
+}
+
+// LogEmitter emits the payload log records, typically through an
+// OpenTelemetry Logs SDK bridge. It is called synchronously once the
+// handler returned, before the span ends, and must therefore not block.
+type LogEmitter interface {
+	Emit(ctx context.Context, record PayloadLogRecord)
+}
+
+// LogEmitterFunc is an adapter allowing the use of ordinary functions as
+// LogEmitter.
+type LogEmitterFunc func(ctx context.Context, record PayloadLogRecord)
+
+// Emit calls f(ctx, record).
+func (f LogEmitterFunc) Emit(ctx context.Context, record PayloadLogRecord) {
+	f(ctx, record)
+}
+
+// emitPayloadLogs emits the captured bodies of a request, if any, as log
+// records correlated with spanCtx.
+func emitPayloadLogs(ctx context.Context, emitter LogEmitter, clock func() time.Time, keys AttributeKeys, spanCtx oteltrace.SpanContext, r *http.Request, routePattern string, rrw *recordingResponseWriter, requestBody, responseBody []byte) {
+	emitted := now(clock)
+	attrs := []attribute.KeyValue{semconv.HTTPMethodKey.String(r.Method)}
+	if routePattern != "" {
+		attrs = append(attrs, semconv.HTTPRouteKey.String(routePattern))
+	}
+	if rrw.status != 0 {
+		attrs = append(attrs, semconv.HTTPStatusCodeKey.Int(rrw.status))
+	}
+	emit := func(name string, body []byte, contentType string) {
+		if len(body) == 0 {
+			return
+		}
+		recordAttrs := attrs
+		if contentType != "" {
+			recordAttrs = append(attrs[:len(attrs):len(attrs)], attribute.String(name+".content_type", contentType))
+		}
+		emitter.Emit(ctx, PayloadLogRecord{
+			Timestamp:   emitted,
+			SpanContext: spanCtx,
+			Name:        name,
+			Body:        string(body),
+			Attributes:  recordAttrs,
+		})
+	}
+	emit(keys.RequestBody, requestBody, r.Header.Get("Content-Type"))
+	emit(keys.ResponseBody, responseBody, rrw.writer.Header().Get("Content-Type"))
+}
@@ -0,0 +1,62 @@
+package otelchi
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSDKIntegrationWithPayloadLogs(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	var records []PayloadLogRecord
+	emitter := LogEmitterFunc(func(ctx context.Context, record PayloadLogRecord) {
+		records = append(records, record)
+	})
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar", WithTracerProvider(provider), WithPayloadLogs(emitter)))
+	router.HandleFunc("/user/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id":"123"}`))
+	})
+
+	r := httptest.NewRequest("PUT", "/user/123", strings.NewReader(`{"name":"foo"}`))
+	r.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	require.Len(t, sr.Ended(), 1)
+	span := sr.Ended()[0]
+	for _, a := range span.Attributes() {
+		assert.NotEqual(t, attribute.Key("http.request.body"), a.Key)
+		assert.NotEqual(t, attribute.Key("http.response.body"), a.Key)
+	}
+
+	require.Len(t, records, 2)
+	assert.Equal(t, "http.request.body", records[0].Name)
+	assert.Equal(t, `{"name":"foo"}`, records[0].Body)
+	assert.Equal(t, span.SpanContext(), records[0].SpanContext)
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String("http.method", "PUT"),
+		attribute.String("http.route", "/user/{id}"),
+		attribute.Int("http.status_code", http.StatusCreated),
+		attribute.String("http.request.body.content_type", "application/json"),
+	}, records[0].Attributes)
+	assert.Equal(t, "http.response.body", records[1].Name)
+	assert.Equal(t, `{"id":"123"}`, records[1].Body)
+	assert.Equal(t, records[0].Timestamp, records[1].Timestamp)
+}
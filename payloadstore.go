@@ -0,0 +1,93 @@
+package otelchi
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// StoredPayload is a captured body too large to be recorded inline, handed
+// to a PayloadStore.
+type StoredPayload struct {
+	TraceID string
+	SpanID  string
+	// Attribute is the attribute the body would have been recorded in,
+	// e.g. "http.request.body".
+	Attribute   string
+	ContentType string
+	Body        []byte
+}
+
+// PayloadStore persists the payloads too large to be recorded inline, e.g.
+// in a bucket or a local directory. It is called synchronously once the
+// handler returned, before the span ends.
+type PayloadStore interface {
+	// Store persists payload and returns the reference recorded on the
+	// span, like a storage key or URL.
+	Store(ctx context.Context, payload StoredPayload) (string, error)
+}
+
+// PayloadStoreFunc is an adapter allowing the use of ordinary functions as
+// PayloadStore.
+type PayloadStoreFunc func(ctx context.Context, payload StoredPayload) (string, error)
+
+// Store calls f(ctx, payload).
+func (f PayloadStoreFunc) Store(ctx context.Context, payload StoredPayload) (string, error) {
+	return f(ctx, payload)
+}
+
+// NewDirPayloadStore returns a PayloadStore writing each payload to a file
+// of dir, named after the trace, the span and the attribute of the payload.
+// The reference of a payload is the path of its file.
+func NewDirPayloadStore(dir string) PayloadStore {
+	return PayloadStoreFunc(func(ctx context.Context, payload StoredPayload) (string, error) {
+		path := filepath.Join(dir, payload.TraceID+"-"+payload.SpanID+"-"+payload.Attribute)
+		if err := os.WriteFile(path, payload.Body, 0o600); err != nil {
+			return "", err
+		}
+		return path, nil
+	})
+}
+
+// payloadOffload hands the bodies larger than the inline threshold to its
+// store.
+type payloadOffload struct {
+	store     PayloadStore
+	threshold int
+}
+
+// offload stores body, meant for the attribute key, and returns the
+// <key>.location attribute referencing it. Store errors are reported to the
+// global OTel error handler, and reported as not offloaded.
+func (o *payloadOffload) offload(ctx context.Context, spanCtx oteltrace.SpanContext, key, contentType string, body []byte) ([]attribute.KeyValue, bool) {
+	ref, err := o.store.Store(ctx, StoredPayload{
+		TraceID:     spanCtx.TraceID().String(),
+		SpanID:      spanCtx.SpanID().String(),
+		Attribute:   key,
+		ContentType: contentType,
+		Body:        body,
+	})
+	if err != nil {
+		otel.Handle(fmt.Errorf("otelchi: payload store: %w", err))
+		return nil, false
+	}
+	return []attribute.KeyValue{attribute.String(key+".location", ref)}, true
+}
+
+// bodyAttributes returns the attributes recording body under key: a
+// reference to the whole body offloaded to the payload store when it is
+// larger than the inline threshold, or inline, the body limited to the
+// maximum body size, deduplicated if enabled.
+func (tw traceware) bodyAttributes(ctx context.Context, span oteltrace.Span, key, contentType string, body, inline []byte) []attribute.KeyValue {
+	if tw.payloadOffload != nil && len(body) > tw.payloadOffload.threshold {
+		if attrs, ok := tw.payloadOffload.offload(ctx, span.SpanContext(), key, contentType, body); ok {
+			return attrs
+		}
+	}
+	return tw.bodyDedup.bodyAttributes(ctx, key, inline)
+}
@@ -0,0 +1,104 @@
+package otelchi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSDKIntegrationWithPayloadStore(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	dir := t.TempDir()
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar", WithTracerProvider(provider), WithPayloadStore(NewDirPayloadStore(dir), 8)))
+	router.HandleFunc("/user/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte("a response larger than the limit"))
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("PUT", "/user/123", strings.NewReader("small")))
+
+	spans := sr.Ended()
+	require.Len(t, spans, 1)
+	spanCtx := spans[0].SpanContext()
+	path := filepath.Join(dir, spanCtx.TraceID().String()+"-"+spanCtx.SpanID().String()+"-http.response.body")
+	assertSpan(t, spans[0], "/user/{id}", trace.SpanKindServer,
+		attribute.String("http.request.body", "small"),
+		attribute.String("http.response.body.location", path),
+	)
+	for _, a := range spans[0].Attributes() {
+		assert.NotEqual(t, attribute.Key("http.response.body"), a.Key)
+	}
+	stored, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "a response larger than the limit", string(stored))
+}
+
+func TestSDKIntegrationWithPayloadStoreMaxBodySize(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	var stored []StoredPayload
+	store := PayloadStoreFunc(func(ctx context.Context, payload StoredPayload) (string, error) {
+		stored = append(stored, payload)
+		return "ref", nil
+	})
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar", WithTracerProvider(provider), WithPayloadStore(store, 8), WithMaxBodySize(4)))
+	router.HandleFunc("/user/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		_, _ = w.Write([]byte("a response larger than the limit"))
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("PUT", "/user/123", strings.NewReader("small")))
+
+	// the stored body is whole, the inline one is limited
+	spans := sr.Ended()
+	require.Len(t, spans, 1)
+	assertSpan(t, spans[0], "/user/{id}", trace.SpanKindServer,
+		attribute.String("http.request.body", "smal"),
+		attribute.String("http.response.body.location", "ref"),
+	)
+	require.Len(t, stored, 1)
+	assert.Equal(t, "a response larger than the limit", string(stored[0].Body))
+}
+
+func TestPayloadOffloadStoreError(t *testing.T) {
+	var stored []StoredPayload
+	tw := traceware{payloadOffload: &payloadOffload{
+		store: PayloadStoreFunc(func(ctx context.Context, payload StoredPayload) (string, error) {
+			stored = append(stored, payload)
+			return "", errors.New("unavailable")
+		}),
+		threshold: 2,
+	}}
+	span := trace.SpanFromContext(context.Background())
+
+	// bodies the store fails to persist are recorded inline
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String("http.request.body", "large"),
+	}, tw.bodyAttributes(context.Background(), span, "http.request.body", "text/plain", []byte("larger"), []byte("large")))
+	require.Len(t, stored, 1)
+	assert.Equal(t, "larger", string(stored[0].Body))
+	assert.Equal(t, "text/plain", stored[0].ContentType)
+	assert.Equal(t, "http.request.body", stored[0].Attribute)
+}
@@ -0,0 +1,58 @@
+package otelchi
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+)
+
+// PriorityBaggageKey is the baggage member used to carry the request
+// priority hint across services.
+const PriorityBaggageKey = "priority"
+
+const priorityKey = attribute.Key("request.priority")
+
+type priorityCtxKey struct{}
+
+// Priority returns the priority hint of the request being served, as
+// resolved by the middleware configured with WithPriorityPropagation. When
+// the middleware did not resolve a priority, the value of the priority
+// baggage member is returned, or an empty string if there is none.
+func Priority(ctx context.Context) string {
+	if p, ok := ctx.Value(priorityCtxKey{}).(string); ok {
+		return p
+	}
+	return baggage.FromContext(ctx).Member(PriorityBaggageKey).Value()
+}
+
+// resolvePriority reads the priority hint from the incoming baggage, falling
+// back to the given header. A priority read from the header is added to the
+// baggage so it is propagated to downstream services together with the
+// trace context.
+func resolvePriority(ctx context.Context, r *http.Request, header string) (context.Context, string) {
+	bag := baggage.FromContext(ctx)
+	priority := bag.Member(PriorityBaggageKey).Value()
+	if priority == "" && header != "" {
+		priority = r.Header.Get(header)
+		if priority == "" {
+			return ctx, ""
+		}
+		member, err := baggage.NewMember(PriorityBaggageKey, priority)
+		if err != nil {
+			otel.Handle(err)
+			return ctx, ""
+		}
+		if bag, err = bag.SetMember(member); err != nil {
+			otel.Handle(err)
+			return ctx, ""
+		}
+		ctx = baggage.ContextWithBaggage(ctx, bag)
+	}
+	if priority == "" {
+		return ctx, ""
+	}
+	return context.WithValue(ctx, priorityCtxKey{}, priority), priority
+}
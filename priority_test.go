@@ -0,0 +1,70 @@
+package otelchi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestPriorityPropagation(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+	prop := propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+	var (
+		priority   string
+		downstream http.Header
+	)
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar",
+		WithTracerProvider(provider),
+		WithPropagators(prop),
+		WithPriorityPropagation("X-Priority"),
+	))
+	router.HandleFunc("/user/{id}", func(w http.ResponseWriter, r *http.Request) {
+		priority = Priority(r.Context())
+		downstream = http.Header{}
+		prop.Inject(r.Context(), propagation.HeaderCarrier(downstream))
+		w.WriteHeader(http.StatusOK)
+	})
+
+	r := httptest.NewRequest("GET", "/user/123", nil)
+	r.Header.Set("X-Priority", "high")
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	assert.Equal(t, "high", priority)
+	assert.Equal(t, "priority=high", downstream.Get("baggage"))
+	require.Len(t, sr.Ended(), 1)
+	assertSpan(t, sr.Ended()[0], "/user/{id}", trace.SpanKindServer,
+		attribute.String("request.priority", "high"),
+	)
+
+	// baggage member wins over the header
+	r = httptest.NewRequest("GET", "/user/123", nil)
+	r.Header.Set("X-Priority", "high")
+	r.Header.Set("baggage", "priority=low")
+	router.ServeHTTP(httptest.NewRecorder(), r)
+	assert.Equal(t, "low", priority)
+}
+
+func TestPriorityFromBaggage(t *testing.T) {
+	member, err := baggage.NewMember(PriorityBaggageKey, "low")
+	require.NoError(t, err)
+	bag, err := baggage.New(member)
+	require.NoError(t, err)
+
+	assert.Equal(t, "low", Priority(baggage.ContextWithBaggage(context.Background(), bag)))
+	assert.Equal(t, "", Priority(context.Background()))
+}
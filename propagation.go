@@ -0,0 +1,46 @@
+package otelchi
+
+import (
+	"context"
+
+	"go.opentelemetry.io/contrib/propagators/b3"
+	"go.opentelemetry.io/contrib/propagators/jaeger"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+const propagationFormatKey = attribute.Key("otelchi.propagation.format")
+
+// propagationFormat is a trace context format detected by
+// WithPropagatorAutodetection.
+type propagationFormat struct {
+	name       string
+	propagator propagation.TextMapPropagator
+}
+
+// autodetectedFormats are the formats tried in order until one carries a
+// valid trace context.
+var autodetectedFormats = []propagationFormat{
+	{name: "tracecontext", propagator: propagation.TraceContext{}},
+	// B3 extracts both the single and multiple headers encodings
+	{name: "b3", propagator: b3.New()},
+	{name: "jaeger", propagator: jaeger.Jaeger{}},
+}
+
+// extractDetected extracts the trace context of the first format carried by
+// carrier into ctx, which already holds what the configured propagators
+// extracted, and returns the attribute naming the format. The context is
+// returned unchanged when no format matched.
+func extractDetected(ctx context.Context, carrier propagation.TextMapCarrier) (context.Context, []attribute.KeyValue) {
+	// ignore the span context extracted by the configured propagators, so a
+	// format only matches with its own headers
+	base := oteltrace.ContextWithSpanContext(ctx, oteltrace.SpanContext{})
+	for _, format := range autodetectedFormats {
+		detected := format.propagator.Extract(base, carrier)
+		if oteltrace.SpanContextFromContext(detected).IsValid() {
+			return detected, []attribute.KeyValue{propagationFormatKey.String(format.name)}
+		}
+	}
+	return ctx, nil
+}
@@ -0,0 +1,104 @@
+package otelchi
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSDKIntegrationWithPropagatorAutodetection(t *testing.T) {
+	const (
+		traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		spanID  = "00f067aa0ba902b7"
+	)
+	for _, tc := range []struct {
+		name    string
+		headers map[string]string
+		format  string
+	}{
+		{
+			name:    "tracecontext",
+			headers: map[string]string{"traceparent": "00-" + traceID + "-" + spanID + "-01"},
+			format:  "tracecontext",
+		},
+		{
+			name:    "b3 single header",
+			headers: map[string]string{"b3": traceID + "-" + spanID + "-1"},
+			format:  "b3",
+		},
+		{
+			name: "b3 multiple headers",
+			headers: map[string]string{
+				"X-B3-TraceId": traceID,
+				"X-B3-SpanId":  spanID,
+				"X-B3-Sampled": "1",
+			},
+			format: "b3",
+		},
+		{
+			name:    "jaeger",
+			headers: map[string]string{"uber-trace-id": traceID + ":" + spanID + ":0:1"},
+			format:  "jaeger",
+		},
+		{
+			name: "tracecontext first",
+			headers: map[string]string{
+				"traceparent":   "00-" + traceID + "-" + spanID + "-01",
+				"uber-trace-id": "0af7651916cd43dd8448eb211c80319c:b7ad6b7169203331:0:1",
+			},
+			format: "tracecontext",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			sr := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider()
+			provider.RegisterSpanProcessor(sr)
+
+			router := chi.NewRouter()
+			router.Use(Middleware("foobar",
+				WithTracerProvider(provider),
+				WithPropagators(propagation.Baggage{}),
+				WithPropagatorAutodetection(),
+			))
+			router.HandleFunc("/user/{id}", ok)
+
+			r := httptest.NewRequest("GET", "/user/123", nil)
+			for name, value := range tc.headers {
+				r.Header.Set(name, value)
+			}
+			router.ServeHTTP(httptest.NewRecorder(), r)
+
+			spans := sr.Ended()
+			require.Len(t, spans, 1)
+			assert.Equal(t, traceID, spans[0].Parent().TraceID().String())
+			assert.Equal(t, spanID, spans[0].Parent().SpanID().String())
+			assert.Contains(t, spans[0].Attributes(), attribute.String("otelchi.propagation.format", tc.format))
+		})
+	}
+}
+
+func TestSDKIntegrationWithPropagatorAutodetectionWithoutContext(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar", WithTracerProvider(provider), WithPropagatorAutodetection()))
+	router.HandleFunc("/user/{id}", ok)
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/user/123", nil))
+
+	spans := sr.Ended()
+	require.Len(t, spans, 1)
+	assert.False(t, spans[0].Parent().IsValid())
+	for _, a := range spans[0].Attributes() {
+		assert.NotEqual(t, attribute.Key("otelchi.propagation.format"), a.Key)
+	}
+}
@@ -0,0 +1,43 @@
+package otelchi
+
+import (
+	"net/http"
+	"strconv"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	networkProtocolNameKey    = attribute.Key("network.protocol.name")
+	networkProtocolVersionKey = attribute.Key("network.protocol.version")
+	networkProtocolH2CKey     = attribute.Key("network.protocol.h2c")
+	networkTransportKey       = attribute.Key("network.transport")
+)
+
+// protocolAttributes returns the application protocol attributes of r.
+// Unlike http.flavor, they tell HTTP/3 requests and HTTP/2 requests over
+// cleartext (h2c) apart, which net/http only exposes through the protocol
+// version and the TLS state of the request.
+func protocolAttributes(r *http.Request) []attribute.KeyValue {
+	var version string
+	switch r.ProtoMajor {
+	case 0:
+		return nil
+	case 1:
+		version = "1." + strconv.Itoa(r.ProtoMinor)
+	default:
+		version = strconv.Itoa(r.ProtoMajor)
+	}
+	attrs := []attribute.KeyValue{
+		networkProtocolNameKey.String("http"),
+		networkProtocolVersionKey.String(version),
+	}
+	switch {
+	case r.ProtoMajor == 2 && r.TLS == nil:
+		attrs = append(attrs, networkProtocolH2CKey.Bool(true))
+	case r.ProtoMajor == 3:
+		// HTTP/3 runs over QUIC
+		attrs = append(attrs, networkTransportKey.String("udp"))
+	}
+	return attrs
+}
@@ -0,0 +1,101 @@
+package otelchi
+
+import (
+	"crypto/tls"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestProtocolAttributes(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		major int
+		minor int
+		tls   bool
+		want  []attribute.KeyValue
+	}{
+		{
+			name:  "HTTP/1.0",
+			major: 1,
+			want: []attribute.KeyValue{
+				attribute.String("network.protocol.name", "http"),
+				attribute.String("network.protocol.version", "1.0"),
+			},
+		},
+		{
+			name:  "HTTP/1.1",
+			major: 1,
+			minor: 1,
+			want: []attribute.KeyValue{
+				attribute.String("network.protocol.name", "http"),
+				attribute.String("network.protocol.version", "1.1"),
+			},
+		},
+		{
+			name:  "HTTP/2",
+			major: 2,
+			tls:   true,
+			want: []attribute.KeyValue{
+				attribute.String("network.protocol.name", "http"),
+				attribute.String("network.protocol.version", "2"),
+			},
+		},
+		{
+			name:  "h2c",
+			major: 2,
+			want: []attribute.KeyValue{
+				attribute.String("network.protocol.name", "http"),
+				attribute.String("network.protocol.version", "2"),
+				attribute.Bool("network.protocol.h2c", true),
+			},
+		},
+		{
+			name:  "HTTP/3",
+			major: 3,
+			tls:   true,
+			want: []attribute.KeyValue{
+				attribute.String("network.protocol.name", "http"),
+				attribute.String("network.protocol.version", "3"),
+				attribute.String("network.transport", "udp"),
+			},
+		},
+		{
+			name: "unknown",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.ProtoMajor, r.ProtoMinor = tc.major, tc.minor
+			r.TLS = nil
+			if tc.tls {
+				r.TLS = &tls.ConnectionState{}
+			}
+			assert.Equal(t, tc.want, protocolAttributes(r))
+		})
+	}
+}
+
+func TestSDKIntegrationWithProtocolAttributes(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar", WithTracerProvider(provider)))
+	router.HandleFunc("/", ok)
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Proto, r.ProtoMajor, r.ProtoMinor = "HTTP/2.0", 2, 0
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	require.Len(t, sr.Ended(), 1)
+	assert.Contains(t, sr.Ended()[0].Attributes(), attribute.Bool("network.protocol.h2c", true))
+	assert.Contains(t, sr.Ended()[0].Attributes(), attribute.String("network.protocol.version", "2"))
+}
@@ -0,0 +1,71 @@
+package otelchi
+
+import (
+	"context"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// QuarantinedPayload is a raw copy of a captured body that could not be
+// scrubbed, kept to debug the scrubbing rules.
+type QuarantinedPayload struct {
+	TraceID string
+	SpanID  string
+	// Attribute is the attribute the body would have been recorded in,
+	// e.g. "http.request.body".
+	Attribute string
+	// Reason tells why the body could not be scrubbed.
+	Reason string
+	Body   []byte
+}
+
+// QuarantineSink receives the quarantined payloads. It is called
+// synchronously once the handler returned, before the span ends, and must
+// therefore not block.
+type QuarantineSink interface {
+	Quarantine(ctx context.Context, payload QuarantinedPayload)
+}
+
+// QuarantineSinkFunc is an adapter allowing the use of ordinary functions
+// as QuarantineSink.
+type QuarantineSinkFunc func(ctx context.Context, payload QuarantinedPayload)
+
+// Quarantine calls f(ctx, payload).
+func (f QuarantineSinkFunc) Quarantine(ctx context.Context, payload QuarantinedPayload) {
+	f(ctx, payload)
+}
+
+// quarantine forwards one in every scrub failures to its sink.
+type quarantine struct {
+	sink     QuarantineSink
+	every    uint64
+	failures uint64
+}
+
+func newQuarantine(sink QuarantineSink, every int) *quarantine {
+	if every < 1 {
+		every = 1
+	}
+	return &quarantine{sink: sink, every: uint64(every)}
+}
+
+// scrubFailed records the failure to scrub body, meant for the attribute
+// key, in span attributes and, if sampled, quarantines the raw body. The
+// quarantine may be nil, in which case only the attributes are returned.
+func (q *quarantine) scrubFailed(ctx context.Context, span oteltrace.Span, key string, body []byte, err error) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{attribute.String(key+".scrub_error", err.Error())}
+	if q == nil || (atomic.AddUint64(&q.failures, 1)-1)%q.every != 0 {
+		return attrs
+	}
+	spanCtx := span.SpanContext()
+	q.sink.Quarantine(ctx, QuarantinedPayload{
+		TraceID:   spanCtx.TraceID().String(),
+		SpanID:    spanCtx.SpanID().String(),
+		Attribute: key,
+		Reason:    err.Error(),
+		Body:      append([]byte(nil), body...),
+	})
+	return append(attrs, attribute.Bool(key+".quarantined", true))
+}
@@ -0,0 +1,62 @@
+package otelchi
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSDKIntegrationWithScrubFailureQuarantine(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	var quarantined []QuarantinedPayload
+	sink := QuarantineSinkFunc(func(_ context.Context, payload QuarantinedPayload) {
+		quarantined = append(quarantined, payload)
+	})
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar",
+		WithTracerProvider(provider),
+		WithXMLCapture(nil, "Token"),
+		WithScrubFailureQuarantine(sink, 2),
+	))
+	router.HandleFunc("/soap", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for i := 0; i < 3; i++ {
+		r := httptest.NewRequest("POST", "/soap", strings.NewReader(`<Request><Token>abc</Request>`))
+		r.Header.Set("Content-Type", "text/xml")
+		router.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	spans := sr.Ended()
+	require.Len(t, spans, 3)
+	for _, span := range spans {
+		assertSpan(t, span, "/soap", trace.SpanKindServer,
+			attribute.String("http.request.body", "[REDACTED]"),
+		)
+	}
+	assertSpan(t, spans[0], "/soap", trace.SpanKindServer, attribute.Bool("http.request.body.quarantined", true))
+	assert.NotContains(t, spans[1].Attributes(), attribute.Bool("http.request.body.quarantined", true))
+
+	require.Len(t, quarantined, 2)
+	assert.Equal(t, "http.request.body", quarantined[0].Attribute)
+	assert.Equal(t, `<Request><Token>abc</Request>`, string(quarantined[0].Body))
+	assert.Equal(t, spans[0].SpanContext().TraceID().String(), quarantined[0].TraceID)
+	assert.NotEmpty(t, quarantined[0].Reason)
+}
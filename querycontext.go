@@ -0,0 +1,50 @@
+package otelchi
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// queryTraceContext extracts the W3C trace context from query parameters,
+// for the clients unable to set headers, like EventSource and WebSocket
+// clients in browsers.
+type queryTraceContext struct {
+	traceparent string
+	tracestate  string
+}
+
+// extract extracts the trace context of the query of r into ctx, unless
+// ctx already holds a remote span context extracted from the headers. The
+// returned attributes set http.target without the trace context
+// parameters, when target is true.
+func (q *queryTraceContext) extract(ctx context.Context, r *http.Request, target bool) (context.Context, []attribute.KeyValue) {
+	if r.URL.RawQuery == "" {
+		return ctx, nil
+	}
+	query := r.URL.Query()
+	traceparent := query.Get(q.traceparent)
+	if traceparent == "" {
+		return ctx, nil
+	}
+	if !oteltrace.SpanContextFromContext(ctx).IsRemote() {
+		ctx = propagation.TraceContext{}.Extract(ctx, propagation.MapCarrier{
+			"traceparent": traceparent,
+			"tracestate":  query.Get(q.tracestate),
+		})
+	}
+	if !target {
+		return ctx, nil
+	}
+	query.Del(q.traceparent)
+	query.Del(q.tracestate)
+	stripped := r.URL.EscapedPath()
+	if encoded := query.Encode(); encoded != "" {
+		stripped += "?" + encoded
+	}
+	return ctx, []attribute.KeyValue{semconv.HTTPTargetKey.String(stripped)}
+}
@@ -0,0 +1,79 @@
+package otelchi
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSDKIntegrationWithQueryTraceContext(t *testing.T) {
+	const (
+		queryTraceparent  = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+		headerTraceparent = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+	)
+	for _, tc := range []struct {
+		name    string
+		target  string
+		header  string
+		traceID string
+		strip   string
+		state   string
+	}{
+		{
+			name:    "query",
+			target:  "/events?topic=news&tp=" + queryTraceparent + "&ts=vendor%3Dvalue",
+			traceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			strip:   "/events?topic=news",
+			state:   "vendor=value",
+		},
+		{
+			name:    "query only",
+			target:  "/events?tp=" + queryTraceparent,
+			traceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			strip:   "/events",
+		},
+		{
+			name:    "header first",
+			target:  "/events?tp=" + queryTraceparent,
+			header:  headerTraceparent,
+			traceID: "0af7651916cd43dd8448eb211c80319c",
+			strip:   "/events",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			sr := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider()
+			provider.RegisterSpanProcessor(sr)
+
+			router := chi.NewRouter()
+			router.Use(Middleware("foobar",
+				WithTracerProvider(provider),
+				WithPropagators(propagation.TraceContext{}),
+				WithQueryTraceContext("tp", "ts"),
+			))
+			router.HandleFunc("/events", ok)
+
+			r := httptest.NewRequest("GET", tc.target, nil)
+			if tc.header != "" {
+				r.Header.Set("traceparent", tc.header)
+			}
+			router.ServeHTTP(httptest.NewRecorder(), r)
+
+			spans := sr.Ended()
+			require.Len(t, spans, 1)
+			assert.Equal(t, tc.traceID, spans[0].SpanContext().TraceID().String())
+			assertSpan(t, spans[0], "/events", trace.SpanKindServer,
+				attribute.String("http.target", tc.strip),
+			)
+			assert.Equal(t, tc.state, spans[0].Parent().TraceState().String())
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package otelchi
+
+import (
+	"context"
+	"net/http"
+)
+
+type recorderKey struct{}
+
+// ResponseRecorder exposes the response details tracked by the middleware
+// for the request being served. Other middlewares and handlers can use it
+// to read the response status and size without wrapping the
+// http.ResponseWriter a second time.
+//
+// A ResponseRecorder is only valid until the otelchi middleware returns,
+// the same way an http.ResponseWriter is only valid until ServeHTTP
+// returns. It must not be retained after the request is complete.
+type ResponseRecorder interface {
+	// Written reports whether the handler has written the response header
+	// or any part of the response body.
+	Written() bool
+	// Status returns the response status code, or 0 if nothing has been
+	// written yet.
+	Status() int
+	// BytesWritten returns the number of response body bytes written so far.
+	BytesWritten() int64
+	// Header returns a snapshot of the current response headers. Changes
+	// to the returned map are not reflected in the response.
+	Header() http.Header
+}
+
+// RecorderFromContext returns the ResponseRecorder of the request being
+// traced by the middleware, if any.
+func RecorderFromContext(ctx context.Context) (ResponseRecorder, bool) {
+	rrw, ok := ctx.Value(recorderKey{}).(*recordingResponseWriter)
+	return rrw, ok
+}
+
+func contextWithRecorder(ctx context.Context, rrw *recordingResponseWriter) context.Context {
+	return context.WithValue(ctx, recorderKey{}, rrw)
+}
+
+func (rrw *recordingResponseWriter) Written() bool {
+	return rrw.written
+}
+
+func (rrw *recordingResponseWriter) Status() int {
+	return rrw.status
+}
+
+func (rrw *recordingResponseWriter) BytesWritten() int64 {
+	return rrw.bytesWritten
+}
+
+func (rrw *recordingResponseWriter) Header() http.Header {
+	return rrw.writer.Header().Clone()
+}
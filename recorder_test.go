@@ -0,0 +1,52 @@
+package otelchi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorderFromContext(t *testing.T) {
+	var (
+		before ResponseRecorder
+		status int
+		size   int64
+		header http.Header
+	)
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar"))
+	// middleware registered after otelchi reads what otelchi recorded
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec, ok := RecorderFromContext(r.Context())
+			require.True(t, ok)
+			before = rec
+			assert.False(t, rec.Written())
+			next.ServeHTTP(w, r)
+			status = rec.Status()
+			size = rec.BytesWritten()
+			header = rec.Header()
+		})
+	})
+	router.HandleFunc("/user/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-User", "123")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("hello"))
+	})
+
+	r := httptest.NewRequest("GET", "/user/123", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+
+	require.NotNil(t, before)
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, int64(5), size)
+	assert.Equal(t, "123", header.Get("X-User"))
+
+	_, ok := RecorderFromContext(r.Context())
+	assert.False(t, ok)
+}
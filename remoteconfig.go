@@ -0,0 +1,101 @@
+package otelchi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel"
+)
+
+// RemoteConfigSource is a remote endpoint serving capture settings as YAML
+// or JSON, see CaptureConfig.PollRemote.
+type RemoteConfigSource struct {
+	URL string
+	// Header is sent along with the requests, e.g. to authenticate them.
+	Header http.Header
+	// Interval is the time between two fetches, one minute if not positive.
+	Interval time.Duration
+	// Client sends the requests, http.DefaultClient if nil.
+	Client *http.Client
+}
+
+// PollRemote fetches the settings served by source, then fetches them again
+// every interval until ctx is done, in the background. The settings are
+// only transferred again once changed, as told by their ETag. Failing to
+// fetch the settings leaves them unchanged, the error being reported to the
+// global OTel error handler.
+func (c *CaptureConfig) PollRemote(ctx context.Context, source RemoteConfigSource) {
+	poller := &remoteConfigPoller{config: c, source: source}
+	if poller.source.Client == nil {
+		poller.source.Client = http.DefaultClient
+	}
+	if poller.source.Interval <= 0 {
+		poller.source.Interval = defaultRemoteConfigInterval
+	}
+	go poller.run(ctx)
+}
+
+const defaultRemoteConfigInterval = time.Minute
+
+type remoteConfigPoller struct {
+	config *CaptureConfig
+	source RemoteConfigSource
+	etag   string
+}
+
+func (p *remoteConfigPoller) run(ctx context.Context) {
+	ticker := time.NewTicker(p.source.Interval)
+	defer ticker.Stop()
+	for {
+		if err := p.fetch(ctx); err != nil && ctx.Err() == nil {
+			otel.Handle(fmt.Errorf("otelchi: remote capture config %s: %w", p.source.URL, err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// fetch fetches the settings and applies them, unless they are unchanged.
+func (p *remoteConfigPoller) fetch(ctx context.Context) error {
+	// a fetch must not outlast the next one
+	ctx, cancel := context.WithTimeout(ctx, p.source.Interval)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.source.URL, nil)
+	if err != nil {
+		return err
+	}
+	for name, values := range p.source.Header {
+		req.Header[name] = values
+	}
+	if p.etag != "" {
+		req.Header.Set("If-None-Match", p.etag)
+	}
+	resp, err := p.source.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	settings, err := parseCaptureSettings(data)
+	if err != nil {
+		return err
+	}
+	p.config.Update(settings)
+	p.etag = resp.Header.Get("ETag")
+	return nil
+}
@@ -0,0 +1,85 @@
+package otelchi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCaptureConfigPollRemote(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		settings = `{"max_body_size": 16}`
+		etag     = `"v1"`
+		sent     []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("If-None-Match") == etag {
+			sent = append(sent, "unchanged")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		sent = append(sent, etag)
+		w.Header().Set("ETag", etag)
+		_, _ = w.Write([]byte(settings))
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := NewCaptureConfig(CaptureSettings{})
+	c.PollRemote(ctx, RemoteConfigSource{
+		URL:      server.URL,
+		Header:   http.Header{"Authorization": []string{"Bearer token"}},
+		Interval: 5 * time.Millisecond,
+	})
+	assert.Eventually(t, func() bool {
+		return c.Settings().MaxBodySize == 16
+	}, time.Second, time.Millisecond)
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(sent) > 1
+	}, time.Second, time.Millisecond)
+
+	mu.Lock()
+	settings, etag = `{"max_body_size": 32}`, `"v2"`
+	mu.Unlock()
+	assert.Eventually(t, func() bool {
+		return c.Settings().MaxBodySize == 32
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, `"v1"`, sent[0])
+	// the settings are not transferred again until they change
+	assert.Equal(t, "unchanged", sent[1])
+	assert.Contains(t, sent, `"v2"`)
+}
+
+func TestCaptureConfigPollRemoteDefaultInterval(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"max_body_size": 16}`))
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := NewCaptureConfig(CaptureSettings{})
+	c.PollRemote(ctx, RemoteConfigSource{URL: server.URL})
+	assert.Eventually(t, func() bool {
+		return c.Settings().MaxBodySize == 16
+	}, time.Second, time.Millisecond)
+}
@@ -0,0 +1,37 @@
+package otelchi
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+const requestIDKey = attribute.Key("http.request.id")
+
+// requestIDCorrelation correlates the spans with the request IDs of the
+// chi RequestID middleware.
+type requestIDCorrelation struct {
+	// generate is set to generate the request IDs missing from the
+	// context
+	generate bool
+}
+
+// correlate records the request ID of ctx on span. When ctx has no request
+// ID and generation is enabled, the trace ID is used as request ID: it is
+// put into the returned context, for middleware.GetReqID, and into the
+// response header.
+func (c *requestIDCorrelation) correlate(ctx context.Context, span oteltrace.Span, header http.Header) context.Context {
+	id := middleware.GetReqID(ctx)
+	if id == "" && c.generate && span.SpanContext().HasTraceID() {
+		id = span.SpanContext().TraceID().String()
+		ctx = context.WithValue(ctx, middleware.RequestIDKey, id)
+		header.Set(middleware.RequestIDHeader, id)
+	}
+	if id != "" {
+		span.SetAttributes(requestIDKey.String(id))
+	}
+	return ctx
+}
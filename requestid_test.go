@@ -0,0 +1,68 @@
+package otelchi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSDKIntegrationWithRequestIDCorrelation(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(middleware.RequestID)
+	router.Use(Middleware("foobar",
+		WithTracerProvider(provider),
+		WithRequestIDCorrelation(false),
+	))
+	router.HandleFunc("/user/{id}", ok)
+
+	r := httptest.NewRequest("GET", "/user/123", nil)
+	r.Header.Set("X-Request-Id", "req-123")
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	spans := sr.Ended()
+	require.Len(t, spans, 1)
+	assertSpan(t, spans[0], "/user/{id}", trace.SpanKindServer,
+		attribute.String("http.request.id", "req-123"),
+	)
+}
+
+func TestSDKIntegrationWithRequestIDGeneration(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	var requestID string
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar",
+		WithTracerProvider(provider),
+		WithRequestIDCorrelation(true),
+	))
+	router.HandleFunc("/user/{id}", func(w http.ResponseWriter, r *http.Request) {
+		requestID = middleware.GetReqID(r.Context())
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/user/123", nil))
+
+	spans := sr.Ended()
+	require.Len(t, spans, 1)
+	traceID := spans[0].SpanContext().TraceID().String()
+	assert.Equal(t, traceID, requestID)
+	assert.Equal(t, traceID, w.Header().Get("X-Request-Id"))
+	assertSpan(t, spans[0], "/user/{id}", trace.SpanKindServer,
+		attribute.String("http.request.id", traceID),
+	)
+}
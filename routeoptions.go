@@ -0,0 +1,90 @@
+package otelchi
+
+import (
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+)
+
+// routeOptions are the options overriding the configuration of the routes
+// matching pattern.
+type routeOptions struct {
+	pattern string
+	opts    []Option
+}
+
+// routeOverrides dispatches requests to the traceware configured for the
+// route pattern they match, if any.
+type routeOverrides struct {
+	matcher    *routeMatcher
+	tracewares map[string]traceware
+}
+
+// newRouteOverrides returns the overrides of the given routes, each of them
+// configured by the global options followed by its own options, and sharing
+// the stateful components of parent. It returns nil if there is no route.
+// Invalid patterns are reported to the global OTel error handler and
+// ignored.
+func newRouteOverrides(serverName string, globalOpts []Option, routes []routeOptions, parent traceware) *routeOverrides {
+	if len(routes) == 0 {
+		return nil
+	}
+	o := &routeOverrides{
+		matcher:    newRouteMatcher(),
+		tracewares: make(map[string]traceware, len(routes)),
+	}
+	for _, route := range routes {
+		if err := o.matcher.add(route.pattern); err != nil {
+			otel.Handle(fmt.Errorf("otelchi: invalid route pattern %q: %w", route.pattern, err))
+			continue
+		}
+		opts := append(globalOpts[:len(globalOpts):len(globalOpts)], route.opts...)
+		cfg := newConfig(opts)
+		cfg.RouteOptions = nil
+		if parent.stateStore != nil {
+			cfg.StateStore = parent.stateStore
+		}
+		tw := newTraceware(serverName, cfg)
+		tw.shareState(parent)
+		o.tracewares[route.pattern] = tw
+	}
+	return o
+}
+
+// shareState replaces the stateful components of tw by those of parent, so
+// that a route override only changes how its requests are captured and
+// named: the budgets and caches of the middleware stay global, and its
+// metrics are recorded by the same instruments.
+func (tw *traceware) shareState(parent traceware) {
+	tw.spanNameGuard = parent.spanNameGuard
+	tw.captureLimiter = parent.captureLimiter
+	tw.routeCache = parent.routeCache
+	tw.metrics = parent.metrics
+}
+
+// withHandler returns a copy of the overrides tracing handler.
+func (o *routeOverrides) withHandler(handler http.Handler) *routeOverrides {
+	if o == nil {
+		return nil
+	}
+	c := &routeOverrides{matcher: o.matcher, tracewares: make(map[string]traceware, len(o.tracewares))}
+	for pattern, tw := range o.tracewares {
+		tw.handler = handler
+		c.tracewares[pattern] = tw
+	}
+	return c
+}
+
+// match returns the traceware of the route pattern matched by r.
+func (o *routeOverrides) match(r *http.Request) (traceware, bool) {
+	if o == nil {
+		return traceware{}, false
+	}
+	pattern, ok := o.matcher.match(r)
+	if !ok {
+		return traceware{}, false
+	}
+	tw, ok := o.tracewares[pattern]
+	return tw, ok
+}
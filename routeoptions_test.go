@@ -0,0 +1,81 @@
+package otelchi
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSDKIntegrationWithRouteOptions(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar",
+		WithTracerProvider(provider),
+		WithMetadataOnly(true),
+		WithRouteOptions("/api/orders/*", WithMetadataOnly(false)),
+		WithRouteOptions("/internal/*", WithFilter(func(*http.Request) bool { return false })),
+		WithRouteOptions("invalid"),
+	))
+	echo := func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(body)
+	}
+	router.HandleFunc("/login", echo)
+	router.HandleFunc("/api/orders/{id}", echo)
+	router.HandleFunc("/internal/stats", echo)
+
+	for _, path := range []string{"/login", "/api/orders/1", "/internal/stats"} {
+		r := httptest.NewRequest("POST", path, strings.NewReader(`{"id": 1}`))
+		router.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	spans := sr.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "/login", spans[0].Name())
+	for _, kv := range spans[0].Attributes() {
+		assert.NotEqual(t, attribute.Key("http.request.body"), kv.Key)
+	}
+	assertSpan(t, spans[1], "/api/orders/{id}", trace.SpanKindServer,
+		attribute.String("http.request.body", `{"id": 1}`),
+		attribute.String("http.response.body", `{"id": 1}`),
+	)
+}
+
+func TestSDKIntegrationWithRouteOptionsSharedState(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar",
+		WithTracerProvider(provider),
+		WithPathNormalizer(func(path string) string { return path }),
+		WithSpanNameLimit(1),
+		WithRouteOptions("/b", WithMetadataOnly(true)),
+	))
+	router.HandleFunc("/*", ok)
+
+	for _, path := range []string{"/a", "/b"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	// the override counts against the span name limit of the middleware
+	var names []string
+	for _, span := range sr.Ended() {
+		names = append(names, span.Name())
+	}
+	assert.Equal(t, []string{"/a", "GET <other>"}, names)
+}
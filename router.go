@@ -0,0 +1,19 @@
+package otelchi
+
+import "github.com/go-chi/chi/v5"
+
+// NewRouter returns a new chi router traced by the middleware configured
+// by opts, sparing the wiring of WithChiRoutes to the router itself: the
+// spans are named after the routes as soon as they start. With
+// WithPanicRecovery, the panics of the handlers are also recovered once
+// recorded on the spans. Metrics are recorded as with Middleware, see
+// WithMeterProvider.
+//
+//	router := otelchi.NewRouter("my-server", otelchi.WithPanicRecovery())
+//	router.Get("/users/{id}", getUser)
+func NewRouter(serverName string, opts ...Option) chi.Router {
+	router := chi.NewRouter()
+	opts = append(opts[:len(opts):len(opts)], WithChiRoutes(router))
+	router.Use(newMiddleware(serverName, newConfig(opts), opts))
+	return router
+}
@@ -0,0 +1,70 @@
+package otelchi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSDKIntegrationWithNewRouter(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	sampler := &nameRecordingSampler{}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSampler(sampler))
+	provider.RegisterSpanProcessor(sr)
+
+	router := NewRouter("foobar", WithTracerProvider(provider), WithPanicRecovery())
+	router.HandleFunc("/user/{id}", ok)
+	router.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	router.HandleFunc("/late-panic", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		panic("boom")
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/user/123", nil))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/panic", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	// the header already written is not written again
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/late-panic", nil))
+	assert.Equal(t, http.StatusAccepted, w.Code)
+
+	// the spans are named after the routes when they start
+	assert.Equal(t, []string{"/user/{id}", "/panic", "/late-panic"}, sampler.names)
+	spans := sr.Ended()
+	require.Len(t, spans, 3)
+	assertSpan(t, spans[0], "/user/{id}", trace.SpanKindServer,
+		attribute.String("http.route", "/user/{id}"),
+	)
+	assertSpan(t, spans[1], "/panic", trace.SpanKindServer,
+		attribute.String("error.type", "string"),
+		attribute.Int("http.status_code", http.StatusInternalServerError),
+	)
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.Equal(t, "handler panicked: boom", spans[1].Status().Description)
+	assertSpan(t, spans[2], "/late-panic", trace.SpanKindServer,
+		attribute.String("error.type", "string"),
+		attribute.Int("http.status_code", http.StatusAccepted),
+	)
+}
+
+func TestNewRouterWithoutPanicRecovery(t *testing.T) {
+	router := NewRouter("foobar", WithTracerProvider(sdktrace.NewTracerProvider()))
+	router.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	assert.PanicsWithValue(t, "boom", func() {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/panic", nil))
+	})
+}
@@ -0,0 +1,152 @@
+package otelchi
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	routeMatchDurationKey = attribute.Key("http.route.match.duration_ms")
+	routeMatchedKey       = attribute.Key("http.route.matched")
+)
+
+// routeMatchAttributes records the duration and outcome of matching the
+// request against the chi routes before the span starts.
+func routeMatchAttributes(elapsed time.Duration, matched bool) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		routeMatchDurationKey.Float64(float64(elapsed) / float64(time.Millisecond)),
+		routeMatchedKey.Bool(matched),
+	}
+}
+
+// routeContextPool holds the chi route contexts used to match requests
+// before they are routed.
+var routeContextPool = &sync.Pool{
+	New: func() interface{} {
+		return chi.NewRouteContext()
+	},
+}
+
+func getRouteContext() *chi.Context {
+	rctx := routeContextPool.Get().(*chi.Context)
+	rctx.Reset()
+	return rctx
+}
+
+func putRouteContext(rctx *chi.Context) {
+	routeContextPool.Put(rctx)
+}
+
+// routeMatcher finds which of a set of route patterns, in the chi routing
+// syntax, a request matches, before the request is routed.
+type routeMatcher struct {
+	mux *chi.Mux
+}
+
+func newRouteMatcher() *routeMatcher {
+	return &routeMatcher{mux: chi.NewMux()}
+}
+
+// add registers pattern, turning chi's panics on invalid patterns into
+// errors.
+func (m *routeMatcher) add(pattern string) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("%v", p)
+		}
+	}()
+	m.mux.Handle(pattern, http.NotFoundHandler())
+	return nil
+}
+
+// match returns the registered pattern matched by r.
+func (m *routeMatcher) match(r *http.Request) (string, bool) {
+	rctx := getRouteContext()
+	defer putRouteContext(rctx)
+	if !m.mux.Match(rctx, r.Method, r.URL.Path) {
+		return "", false
+	}
+	return strings.Join(rctx.RoutePatterns, ""), true
+}
+
+// routeCache is a bounded cache of the route patterns matched by the most
+// recently requested method and path pairs, evicting the least recently
+// used ones beyond its capacity.
+type routeCache struct {
+	mu      sync.Mutex
+	entries *lru
+}
+
+func newRouteCache(capacity int) *routeCache {
+	return &routeCache{entries: newLRU(capacity)}
+}
+
+func (c *routeCache) get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pattern, ok := c.entries.get(key)
+	if !ok {
+		return "", false
+	}
+	return pattern.(string), true
+}
+
+func (c *routeCache) add(key, pattern string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries.set(key, pattern)
+}
+
+// matchRoute returns the pattern of the route of routes matched by r, if any,
+// looking it up in the route cache first.
+func (tw traceware) matchRoute(r *http.Request, routes chi.Routes) string {
+	var key string
+	if tw.routeCache != nil {
+		key = r.Method + " " + r.URL.Path
+		if pattern, ok := tw.routeCache.get(key); ok {
+			return pattern
+		}
+	}
+	rctx := getRouteContext()
+	defer putRouteContext(rctx)
+	path := r.URL.Path
+	if parent := chi.RouteContext(r.Context()); parent != nil && parent.RoutePath != "" {
+		// chi routes on the path set by the parent router which mounted the
+		// routes, after its patterns, or by a middleware rewriting it, e.g.
+		// middleware.CleanPath
+		path = parent.RoutePath
+		rctx.RoutePatterns = append(rctx.RoutePatterns, parent.RoutePatterns...)
+	}
+	// chi walks the routers mounted on the routes, joining their patterns
+	if !routes.Match(rctx, r.Method, path) {
+		return ""
+	}
+	pattern := rctx.RoutePattern()
+	if tw.routeCache != nil {
+		// only matched routes are cached, so unknown paths do not evict
+		// the hot ones
+		tw.routeCache.add(key, pattern)
+	}
+	return pattern
+}
+
+// detectRoutes returns the routes of the chi router serving r, when the
+// middleware is used by the router which first routed the request, e.g.
+// through its Use method. The routes of mounted routers are not known from
+// the request, which their parent router already routed, matching some of
+// its patterns, and nil is returned.
+func detectRoutes(r *http.Request) chi.Routes {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil || len(rctx.RoutePatterns) > 0 {
+		return nil
+	}
+	return rctx.Routes
+}
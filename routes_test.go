@@ -0,0 +1,217 @@
+package otelchi
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSDKIntegrationWithRouteMatchTiming(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar",
+		WithTracerProvider(provider),
+		WithChiRoutes(router),
+		WithRouteMatchTiming(),
+	))
+	router.HandleFunc("/user/{id:[0-9]+}", ok)
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/user/123", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/user/abc", nil))
+
+	spans := sr.Ended()
+	require.Len(t, spans, 2)
+	assertSpan(t, spans[0], "/user/{id:[0-9]+}", trace.SpanKindServer,
+		attribute.Bool("http.route.matched", true),
+	)
+	assert.Contains(t, spans[1].Attributes(), attribute.Bool("http.route.matched", false))
+	for _, span := range spans {
+		var found bool
+		for _, a := range span.Attributes() {
+			if a.Key == "http.route.match.duration_ms" {
+				found = true
+				assert.Positive(t, a.Value.AsFloat64())
+			}
+		}
+		assert.True(t, found)
+	}
+}
+
+// matchCountingRoutes counts the route matches done through it.
+type matchCountingRoutes struct {
+	*chi.Mux
+	matches int
+}
+
+func (r *matchCountingRoutes) Match(rctx *chi.Context, method, path string) bool {
+	r.matches++
+	return r.Mux.Match(rctx, method, path)
+}
+
+func TestSDKIntegrationWithRouteCache(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	routes := &matchCountingRoutes{Mux: router}
+	router.Use(Middleware("foobar",
+		WithTracerProvider(provider),
+		WithChiRoutes(routes),
+		WithRouteCache(10),
+	))
+	router.HandleFunc("/user/{id}", ok)
+
+	for i := 0; i < 3; i++ {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/user/123", nil))
+	}
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/unknown", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/unknown", nil))
+
+	assert.Equal(t, 3, routes.matches)
+	spans := sr.Ended()
+	require.Len(t, spans, 5)
+	for _, span := range spans[:3] {
+		assertSpan(t, span, "/user/{id}", trace.SpanKindServer,
+			attribute.String("http.route", "/user/{id}"),
+		)
+	}
+}
+
+func TestRouteCacheEviction(t *testing.T) {
+	cache := newRouteCache(2)
+	cache.add("GET /a", "/a")
+	cache.add("GET /b", "/b")
+	_, _ = cache.get("GET /a")
+	cache.add("GET /c", "/c")
+
+	pattern, ok := cache.get("GET /a")
+	assert.True(t, ok)
+	assert.Equal(t, "/a", pattern)
+	_, ok = cache.get("GET /b")
+	assert.False(t, ok)
+	_, ok = cache.get("GET /c")
+	assert.True(t, ok)
+}
+
+func TestSDKIntegrationWithMountedRouters(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		use  func(router, sub chi.Router, opts ...Option)
+	}{
+		{
+			name: "parent router",
+			use: func(router, sub chi.Router, opts ...Option) {
+				router.Use(Middleware("foobar", append(opts, WithChiRoutes(router))...))
+			},
+		},
+		{
+			name: "mounted router",
+			use: func(router, sub chi.Router, opts ...Option) {
+				sub.Use(Middleware("foobar", append(opts, WithChiRoutes(sub))...))
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			sampler := &nameRecordingSampler{}
+			sr := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSampler(sampler))
+			provider.RegisterSpanProcessor(sr)
+
+			router := chi.NewRouter()
+			sub := chi.NewRouter()
+			tc.use(router, sub, WithTracerProvider(provider))
+			sub.Get("/users/{id}", ok)
+			sub.Route("/v2", func(r chi.Router) {
+				r.Get("/items/{id}", ok)
+			})
+			router.Mount("/api", sub)
+
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/users/1", nil))
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/v2/items/2", nil))
+
+			assert.Equal(t, []string{"/api/users/{id}", "/api/v2/items/{id}"}, sampler.names)
+			spans := sr.Ended()
+			require.Len(t, spans, 2)
+			assertSpan(t, spans[0], "/api/users/{id}", trace.SpanKindServer,
+				attribute.String("http.route", "/api/users/{id}"),
+			)
+			assertSpan(t, spans[1], "/api/v2/items/{id}", trace.SpanKindServer,
+				attribute.String("http.route", "/api/v2/items/{id}"),
+			)
+		})
+	}
+}
+
+func TestSDKIntegrationWithDetectedRoutes(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		use        func(router, sub chi.Router, opts ...Option)
+		startNames []string
+	}{
+		{
+			name: "router",
+			use: func(router, sub chi.Router, opts ...Option) {
+				router.Use(Middleware("foobar", opts...))
+			},
+			startNames: []string{"/api/users/{id}", ""},
+		},
+		{
+			// middleware.CleanPath sets the route path of the router
+			name: "clean path",
+			use: func(router, sub chi.Router, opts ...Option) {
+				router.Use(middleware.CleanPath, Middleware("foobar", opts...))
+			},
+			startNames: []string{"/api/users/{id}", ""},
+		},
+		{
+			name: "lazy",
+			use: func(router, sub chi.Router, opts ...Option) {
+				router.Use(Middleware("foobar", append(opts, WithLazyRouteNaming())...))
+			},
+			startNames: []string{"", ""},
+		},
+		{
+			// the routes of a mounted router are not detected
+			name: "mounted router",
+			use: func(router, sub chi.Router, opts ...Option) {
+				sub.Use(Middleware("foobar", opts...))
+			},
+			startNames: []string{"", ""},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			sampler := &nameRecordingSampler{}
+			sr := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSampler(sampler))
+			provider.RegisterSpanProcessor(sr)
+
+			router := chi.NewRouter()
+			sub := chi.NewRouter()
+			tc.use(router, sub, WithTracerProvider(provider))
+			sub.Get("/users/{id}", ok)
+			router.Mount("/api", sub)
+
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/users/1", nil))
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/unknown", nil))
+
+			assert.Equal(t, tc.startNames, sampler.names)
+			spans := sr.Ended()
+			require.Len(t, spans, 2)
+			assertSpan(t, spans[0], "/api/users/{id}", trace.SpanKindServer,
+				attribute.String("http.route", "/api/users/{id}"),
+			)
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package otelchi
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// routeSampling holds the sampling ratios of route patterns, applied by the
+// middleware before starting the span.
+type routeSampling struct {
+	matcher *routeMatcher
+	ratios  map[string]float64
+}
+
+// newRouteSampling returns the sampling of the given route patterns.
+// Invalid patterns are reported to the global OTel error handler and
+// ignored.
+func newRouteSampling(ratios map[string]float64) *routeSampling {
+	s := &routeSampling{matcher: newRouteMatcher(), ratios: make(map[string]float64, len(ratios))}
+	for pattern, ratio := range ratios {
+		if err := s.matcher.add(pattern); err != nil {
+			otel.Handle(fmt.Errorf("otelchi: invalid route pattern %q: %w", pattern, err))
+			continue
+		}
+		s.ratios[pattern] = ratio
+	}
+	return s
+}
+
+// ratio returns the sampling ratio of the request, whose route pattern is
+// given when it is already known.
+func (s *routeSampling) ratio(r *http.Request, routePattern string) (float64, bool) {
+	if routePattern == "" {
+		var ok bool
+		if routePattern, ok = s.matcher.match(r); !ok {
+			return 0, false
+		}
+	}
+	ratio, ok := s.ratios[routePattern]
+	return ratio, ok
+}
+
+// drop decides whether to drop the request and, if so, returns the context
+// to serve it with: the span context of the request is not sampled, so
+// that the decision is propagated to the handler and to the downstream
+// services. The decision depends on the trace ID only, like the
+// sdktrace.TraceIDRatioBased sampler, so that all the services of a trace
+// using the same ratio make the same decision.
+func (s *routeSampling) drop(ctx context.Context, r *http.Request, routePattern string) (context.Context, bool) {
+	ratio, ok := s.ratio(r, routePattern)
+	if !ok || ratio >= 1 {
+		return ctx, false
+	}
+
+	parent := oteltrace.SpanContextFromContext(ctx)
+	traceID := parent.TraceID()
+	if !traceID.IsValid() {
+		_, _ = rand.Read(traceID[:])
+	}
+	bound := uint64(ratio * (1 << 63))
+	if ratio > 0 && binary.BigEndian.Uint64(traceID[8:16])>>1 < bound {
+		return ctx, false
+	}
+
+	var spanID oteltrace.SpanID
+	_, _ = rand.Read(spanID[:])
+	spanCtx := oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: parent.TraceFlags().WithSampled(false),
+		TraceState: parent.TraceState(),
+	})
+	return oteltrace.ContextWithSpanContext(ctx, spanCtx), true
+}
@@ -0,0 +1,66 @@
+package otelchi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSDKIntegrationWithRouteSampleRatios(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar",
+		WithTracerProvider(provider),
+		WithPropagators(propagation.TraceContext{}),
+		WithRouteSampleRatios(map[string]float64{
+			"/checkout":    1,
+			"/search":      0,
+			"/user/{id}/*": 0.5,
+		}),
+	))
+	var handlerCtx trace.SpanContext
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		handlerCtx = trace.SpanContextFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}
+	router.HandleFunc("/checkout", handler)
+	router.HandleFunc("/search", handler)
+	router.HandleFunc("/user/{id}/*", handler)
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/checkout", nil))
+	require.Len(t, sr.Ended(), 1)
+
+	r := httptest.NewRequest("GET", "/search", nil)
+	r.Header.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	router.ServeHTTP(httptest.NewRecorder(), r)
+	require.Len(t, sr.Ended(), 1)
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", handlerCtx.TraceID().String())
+	assert.NotEqual(t, "b7ad6b7169203331", handlerCtx.SpanID().String())
+	assert.False(t, handlerCtx.IsSampled())
+
+	// the decision only depends on the trace ID
+	for _, testCase := range []struct {
+		traceparent string
+		sampled     bool
+	}{
+		{traceparent: "00-0af7651916cd43dd0000000000000001-b7ad6b7169203331-01", sampled: true},
+		{traceparent: "00-0af7651916cd43ddffffffffffffffff-b7ad6b7169203331-01", sampled: false},
+	} {
+		ended := len(sr.Ended())
+		r := httptest.NewRequest("GET", "/user/123/profile", nil)
+		r.Header.Set("traceparent", testCase.traceparent)
+		router.ServeHTTP(httptest.NewRecorder(), r)
+		assert.Equal(t, testCase.sampled, len(sr.Ended()) > ended, testCase.traceparent)
+	}
+}
@@ -0,0 +1,59 @@
+package otelchi
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+)
+
+// RouteSampler is an sdktrace.Sampler that delegates the sampling decision
+// to a sampler chosen by the http.route attribute of the span being
+// started. Spans without a route, or with a route that has no sampler,
+// are handled by the fallback sampler.
+//
+// The middleware only knows the route when the span starts if it is
+// configured with WithRouteSamplerHint (or WithChiRoutes), so the sampler
+// must be paired with one of them.
+type RouteSampler struct {
+	routes   map[string]sdktrace.Sampler
+	fallback sdktrace.Sampler
+}
+
+var _ sdktrace.Sampler = (*RouteSampler)(nil)
+
+// NewRouteSampler returns a RouteSampler using the given sampler for each
+// route pattern. When fallback is nil, spans of other routes are sampled
+// according to sdktrace.ParentBased(sdktrace.AlwaysSample()).
+func NewRouteSampler(routes map[string]sdktrace.Sampler, fallback sdktrace.Sampler) *RouteSampler {
+	if fallback == nil {
+		fallback = sdktrace.ParentBased(sdktrace.AlwaysSample())
+	}
+	return &RouteSampler{routes: routes, fallback: fallback}
+}
+
+// ShouldSample implements sdktrace.Sampler.
+func (s *RouteSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	for _, attr := range p.Attributes {
+		if attr.Key != semconv.HTTPRouteKey {
+			continue
+		}
+		if sampler, ok := s.routes[attr.Value.AsString()]; ok {
+			return sampler.ShouldSample(p)
+		}
+		break
+	}
+	return s.fallback.ShouldSample(p)
+}
+
+// Description implements sdktrace.Sampler.
+func (s *RouteSampler) Description() string {
+	routes := make([]string, 0, len(s.routes))
+	for route, sampler := range s.routes {
+		routes = append(routes, fmt.Sprintf("%s:%s", route, sampler.Description()))
+	}
+	sort.Strings(routes)
+	return fmt.Sprintf("RouteSampler{routes:[%s],fallback:%s}", strings.Join(routes, ","), s.fallback.Description())
+}
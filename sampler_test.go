@@ -0,0 +1,37 @@
+package otelchi
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestRouteSampler(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	sampler := NewRouteSampler(map[string]sdktrace.Sampler{
+		"/health": sdktrace.NeverSample(),
+	}, nil)
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSampler(sampler))
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar",
+		WithTracerProvider(provider),
+		WithRouteSamplerHint(router),
+	))
+	router.HandleFunc("/health", ok)
+	router.HandleFunc("/user/{id}", ok)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/health", nil))
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/user/123", nil))
+
+	require.Len(t, sr.Ended(), 1)
+	assert.Equal(t, "/user/{id}", sr.Ended()[0].Name())
+	assert.Equal(t, "RouteSampler{routes:[/health:AlwaysOffSampler],fallback:ParentBased{root:AlwaysOnSampler,remoteParentSampled:AlwaysOnSampler,remoteParentNotSampled:AlwaysOffSampler,localParentSampled:AlwaysOnSampler,localParentNotSampled:AlwaysOffSampler}}", sampler.Description())
+}
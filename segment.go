@@ -0,0 +1,75 @@
+package otelchi
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+
+	otelcontrib "go.opentelemetry.io/contrib"
+)
+
+const middlewareShortCircuitKey = attribute.Key("otelchi.middleware.short_circuit")
+
+type segmentKey struct{}
+
+// middlewareSegment is the span timing the work a middleware does before
+// calling the next handler.
+type middlewareSegment struct {
+	span   oteltrace.Span
+	parent oteltrace.Span
+	ended  bool
+}
+
+func (s *middlewareSegment) end() {
+	if !s.ended {
+		s.ended = true
+		s.span.End()
+	}
+}
+
+// TraceMiddleware wraps middleware, e.g. an auth, rate limiting or
+// decompression middleware, in a child span of the span of the request
+// named name, so that its work shows up as a separate timed segment
+// instead of being lumped into the handler time:
+//
+//	router.Use(otelchi.Middleware("my-server"))
+//	router.Use(otelchi.TraceMiddleware("auth", authMiddleware))
+//
+// The span starts when the middleware is entered and ends when it calls
+// the next handler, which is served under the span of the request again,
+// with the context values added by the middleware. Middlewares that do
+// not call the next handler, e.g. to reject the request, have their span
+// ended when they return, with the otelchi.middleware.short_circuit
+// attribute set. The span is started with the tracer provider of the span
+// of the request.
+func TraceMiddleware(name string, middleware func(next http.Handler) http.Handler) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		wrapped := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			if seg, ok := ctx.Value(segmentKey{}).(*middlewareSegment); ok && !seg.ended {
+				seg.end()
+				ctx = oteltrace.ContextWithSpan(ctx, seg.parent)
+				r = r.WithContext(ctx)
+			}
+			next.ServeHTTP(w, r)
+		}))
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			parent := oteltrace.SpanFromContext(r.Context())
+			tracer := parent.TracerProvider().Tracer(
+				tracerName,
+				oteltrace.WithInstrumentationVersion(otelcontrib.SemVersion()),
+			)
+			ctx, span := tracer.Start(r.Context(), name, oteltrace.WithSpanKind(oteltrace.SpanKindInternal))
+			seg := &middlewareSegment{span: span, parent: parent}
+			defer func() {
+				if !seg.ended {
+					span.SetAttributes(middlewareShortCircuitKey.Bool(true))
+					seg.end()
+				}
+			}()
+			wrapped.ServeHTTP(w, r.WithContext(context.WithValue(ctx, segmentKey{}, seg)))
+		})
+	}
+}
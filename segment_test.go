@@ -0,0 +1,67 @@
+package otelchi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type userKey struct{}
+
+func auth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey{}, "foo")))
+	})
+}
+
+func TestSDKIntegrationWithTraceMiddleware(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	var handlerSpan trace.SpanContext
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar", WithTracerProvider(provider)))
+	router.Use(TraceMiddleware("auth", auth))
+	router.HandleFunc("/user/{id}", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "foo", r.Context().Value(userKey{}))
+		handlerSpan = trace.SpanContextFromContext(r.Context())
+	})
+
+	r := httptest.NewRequest("GET", "/user/123", nil)
+	r.Header.Set("Authorization", "Bearer token")
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	spans := sr.Ended()
+	require.Len(t, spans, 2)
+	segment, server := spans[0], spans[1]
+	assert.Equal(t, "auth", segment.Name())
+	assert.Equal(t, trace.SpanKindInternal, segment.SpanKind())
+	assert.Equal(t, server.SpanContext().SpanID(), segment.Parent().SpanID())
+	// the handler is served under the server span, after the segment
+	assert.Equal(t, server.SpanContext(), handlerSpan)
+	assert.False(t, segment.EndTime().After(server.EndTime()))
+	assert.NotContains(t, segment.Attributes(), attribute.Bool("otelchi.middleware.short_circuit", true))
+
+	// rejected requests
+	sr = tracetest.NewSpanRecorder()
+	provider.RegisterSpanProcessor(sr)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/user/123", nil))
+	spans = sr.Ended()
+	require.Len(t, spans, 2)
+	assert.Contains(t, spans[0].Attributes(), attribute.Bool("otelchi.middleware.short_circuit", true))
+	assert.Contains(t, spans[1].Attributes(), attribute.Int("http.status_code", http.StatusUnauthorized))
+}
@@ -0,0 +1,31 @@
+package otelchi
+
+import (
+	"net"
+	"net/http"
+)
+
+// requestServerName returns the name of the server handling r, given by
+// WithServerNameFn, or the name given to Middleware when there is none.
+func (tw traceware) requestServerName(r *http.Request) string {
+	if tw.serverNameFn != nil {
+		if name := tw.serverNameFn(r); name != "" {
+			return name
+		}
+	}
+	return tw.serverName
+}
+
+// HostServerName returns the host of r, without port, as server name. It
+// is meant for WithServerNameFn, to name the virtual hosts served by a
+// single middleware.
+func HostServerName(r *http.Request) string {
+	host := r.Host
+	if host == "" && r.URL != nil {
+		host = r.URL.Host
+	}
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		return name
+	}
+	return host
+}
@@ -0,0 +1,57 @@
+package otelchi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSDKIntegrationWithServerNameFn(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	var names []string
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar",
+		WithTracerProvider(provider),
+		WithServerNameFn(HostServerName),
+	))
+	router.HandleFunc("/user/{id}", func(w http.ResponseWriter, r *http.Request) {
+		name, _ := ServerNameFromContext(r.Context())
+		names = append(names, name)
+	})
+
+	r := httptest.NewRequest("GET", "/user/123", nil)
+	r.Host = "api.example.com:8080"
+	router.ServeHTTP(httptest.NewRecorder(), r)
+	r = httptest.NewRequest("GET", "/user/123", nil)
+	r.Host = ""
+	r.URL.Host = ""
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	assert.Equal(t, []string{"api.example.com", "foobar"}, names)
+	spans := sr.Ended()
+	require.Len(t, spans, 2)
+	assertSpan(t, spans[0], "/user/{id}", trace.SpanKindServer,
+		attribute.String("http.server_name", "api.example.com"),
+	)
+	assertSpan(t, spans[1], "/user/{id}", trace.SpanKindServer,
+		attribute.String("http.server_name", "foobar"),
+	)
+	var serverNames int
+	for _, a := range spans[0].Attributes() {
+		if a.Key == "http.server_name" {
+			serverNames++
+		}
+	}
+	assert.Equal(t, 1, serverNames)
+}
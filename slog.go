@@ -0,0 +1,63 @@
+//go:build go1.21
+// +build go1.21
+
+package otelchi
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/go-chi/chi/v5"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// LogHandler is a slog.Handler adding the trace context, the chi route and
+// the server name of the request being served to the records logged with
+// its context, for logs to be joined to traces. Records logged without a
+// span in their context are passed through unchanged.
+//
+//	logger := slog.New(otelchi.NewLogHandler(slog.NewJSONHandler(os.Stdout, nil)))
+//	logger.InfoContext(r.Context(), "user created")
+type LogHandler struct {
+	handler slog.Handler
+}
+
+// NewLogHandler returns a LogHandler passing the records to handler.
+func NewLogHandler(handler slog.Handler) *LogHandler {
+	return &LogHandler{handler: handler}
+}
+
+// Enabled implements slog.Handler.
+func (h *LogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler.
+func (h *LogHandler) Handle(ctx context.Context, record slog.Record) error {
+	if spanCtx := oteltrace.SpanContextFromContext(ctx); spanCtx.IsValid() {
+		record = record.Clone()
+		record.AddAttrs(
+			slog.String(LogTraceIDKey, spanCtx.TraceID().String()),
+			slog.String(LogSpanIDKey, spanCtx.SpanID().String()),
+		)
+		if rctx := chi.RouteContext(ctx); rctx != nil {
+			if pattern := rctx.RoutePattern(); pattern != "" {
+				record.AddAttrs(slog.String(LogRouteKey, pattern))
+			}
+		}
+		if serverName, ok := ServerNameFromContext(ctx); ok {
+			record.AddAttrs(slog.String(LogServerNameKey, serverName))
+		}
+	}
+	return h.handler.Handle(ctx, record)
+}
+
+// WithAttrs implements slog.Handler.
+func (h *LogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &LogHandler{handler: h.handler.WithAttrs(attrs)}
+}
+
+// WithGroup implements slog.Handler.
+func (h *LogHandler) WithGroup(name string) slog.Handler {
+	return &LogHandler{handler: h.handler.WithGroup(name)}
+}
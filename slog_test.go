@@ -0,0 +1,55 @@
+//go:build go1.21
+// +build go1.21
+
+package otelchi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestLogHandler(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	var buf bytes.Buffer
+	logger := slog.New(NewLogHandler(slog.NewJSONHandler(&buf, nil))).With("service", "users")
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar", WithTracerProvider(provider)))
+	router.HandleFunc("/user/{id}", func(w http.ResponseWriter, r *http.Request) {
+		logger.InfoContext(r.Context(), "user fetched")
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/user/123", nil))
+	require.Len(t, sr.Ended(), 1)
+	spanCtx := sr.Ended()[0].SpanContext()
+
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "user fetched", record["msg"])
+	assert.Equal(t, "users", record["service"])
+	assert.Equal(t, spanCtx.TraceID().String(), record["trace_id"])
+	assert.Equal(t, spanCtx.SpanID().String(), record["span_id"])
+	assert.Equal(t, "/user/{id}", record["http.route"])
+	assert.Equal(t, "foobar", record["http.server_name"])
+
+	// records logged outside of a request are left alone
+	buf.Reset()
+	logger.InfoContext(context.Background(), "started")
+	record = nil
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.NotContains(t, record, "trace_id")
+}
@@ -0,0 +1,35 @@
+package otelchi
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+const (
+	slowRequestEventName    = "slow_request"
+	slowRequestElapsedKey   = attribute.Key("slow_request.elapsed_ms")
+	slowRequestThresholdKey = attribute.Key("slow_request.threshold_ms")
+)
+
+// slowRequest records that the handler took elapsed to serve r, more than
+// the slow request threshold, in a slow_request span event and in the slow
+// requests counter. The counter is recorded whether or not the span is
+// sampled.
+func (tw traceware) slowRequest(ctx context.Context, span oteltrace.Span, r *http.Request, routePattern string, elapsed time.Duration) {
+	attrs := []attribute.KeyValue{
+		slowRequestElapsedKey.Float64(float64(elapsed) / float64(time.Millisecond)),
+		slowRequestThresholdKey.Float64(float64(tw.slowThreshold) / float64(time.Millisecond)),
+	}
+	if routePattern != "" {
+		attrs = append(attrs, semconv.HTTPRouteKey.String(routePattern))
+	}
+	span.AddEvent(slowRequestEventName, oteltrace.WithAttributes(attrs...))
+	if tw.metrics != nil {
+		tw.metrics.slowRequest(ctx, r.Method, routePattern)
+	}
+}
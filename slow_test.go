@@ -0,0 +1,46 @@
+package otelchi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSDKIntegrationWithSlowRequestThreshold(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar", WithTracerProvider(provider), WithSlowRequestThreshold(20*time.Millisecond)))
+	router.HandleFunc("/user/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("slow") != "" {
+			time.Sleep(30 * time.Millisecond)
+		}
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/user/123", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/user/123?slow=1", nil))
+
+	spans := sr.Ended()
+	require.Len(t, spans, 2)
+	assert.Empty(t, spans[0].Events())
+	require.Len(t, spans[1].Events(), 1)
+	event := spans[1].Events()[0]
+	assert.Equal(t, "slow_request", event.Name)
+	assert.Contains(t, event.Attributes, attribute.String("http.route", "/user/{id}"))
+	assert.Contains(t, event.Attributes, attribute.Float64("slow_request.threshold_ms", 20))
+	for _, a := range event.Attributes {
+		if a.Key == "slow_request.elapsed_ms" {
+			assert.GreaterOrEqual(t, a.Value.AsFloat64(), float64(30))
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package otelchi
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// defaultStackTraceDepth is the maximum number of frames of the stack
+// traces when none is given to WithStackTraces.
+const defaultStackTraceDepth = 32
+
+// DefaultStackTraceSkippedPrefixes returns the prefixes of the functions
+// whose frames are skipped from the stack traces when none is given to
+// WithStackTraces: the frames of the Go runtime, of net/http, of chi and
+// its middlewares, and of this middleware.
+func DefaultStackTraceSkippedPrefixes() []string {
+	return []string{
+		"runtime.",
+		"net/http.",
+		"github.com/go-chi/chi/v5",
+		tracerName + ".",
+	}
+}
+
+// stackTraces captures the stack traces of the errors and panics of the
+// handlers.
+type stackTraces struct {
+	maxDepth        int
+	skippedPrefixes []string
+}
+
+func newStackTraces(maxDepth int, skippedPrefixes []string) *stackTraces {
+	if maxDepth <= 0 {
+		maxDepth = defaultStackTraceDepth
+	}
+	if len(skippedPrefixes) == 0 {
+		skippedPrefixes = DefaultStackTraceSkippedPrefixes()
+	}
+	return &stackTraces{maxDepth: maxDepth, skippedPrefixes: skippedPrefixes}
+}
+
+// capture returns the stack trace of the calling goroutine, formatted like
+// runtime/debug.Stack, skipping the given number of callers of capture and
+// the frames of the skipped functions, with at most maxDepth frames.
+func (s *stackTraces) capture(skip int) string {
+	// look past the skipped frames
+	pcs := make([]uintptr, s.maxDepth+32)
+	n := runtime.Callers(skip+2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var b strings.Builder
+	depth := 0
+	for depth < s.maxDepth {
+		frame, more := frames.Next()
+		if !s.skipped(frame.Function) {
+			b.WriteString(frame.Function)
+			b.WriteString("\n\t")
+			b.WriteString(frame.File)
+			b.WriteByte(':')
+			b.WriteString(strconv.Itoa(frame.Line))
+			b.WriteByte('\n')
+			depth++
+		}
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
+
+func (s *stackTraces) skipped(function string) bool {
+	for _, prefix := range s.skippedPrefixes {
+		if strings.HasPrefix(function, prefix) {
+			return true
+		}
+	}
+	return false
+}
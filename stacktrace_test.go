@@ -0,0 +1,71 @@
+package otelchi
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func failingHandler(w http.ResponseWriter, r *http.Request) {
+	RecordError(r.Context(), errors.New("database unavailable"))
+	w.WriteHeader(http.StatusServiceUnavailable)
+}
+
+func panickingHandler(w http.ResponseWriter, r *http.Request) {
+	panic("nil map")
+}
+
+func TestSDKIntegrationWithStackTraces(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() { _ = recover() }()
+			next.ServeHTTP(w, r)
+		})
+	})
+	router.Use(Middleware("foobar",
+		WithTracerProvider(provider),
+		WithStackTraces(2, "runtime.", "net/http.", "github.com/go-chi/chi/v5", tracerName+".traceware"),
+	))
+	router.HandleFunc("/fail", failingHandler)
+	router.HandleFunc("/panic", panickingHandler)
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/fail", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/panic", nil))
+
+	spans := sr.Ended()
+	require.Len(t, spans, 2)
+	for i, function := range []string{"failingHandler", "panickingHandler"} {
+		events := spans[i].Events()
+		require.Len(t, events, 1)
+		var stack string
+		for _, a := range events[0].Attributes {
+			if a.Key == "exception.stacktrace" {
+				stack = a.Value.AsString()
+			}
+		}
+		lines := strings.Split(strings.TrimSuffix(stack, "\n"), "\n")
+		require.Len(t, lines, 4, stack)
+		assert.Equal(t, tracerName+"."+function, lines[0])
+		assert.Contains(t, lines[1], "stacktrace_test.go:")
+	}
+}
+
+func TestStackTracesDefaultSkippedPrefixes(t *testing.T) {
+	stack := newStackTraces(0, nil).capture(0)
+	// the frames of this package, the testing package aside, are skipped
+	assert.True(t, strings.HasPrefix(stack, "testing.tRunner\n"), stack)
+	assert.NotContains(t, stack, "runtime.")
+}
@@ -0,0 +1,114 @@
+package otelchi
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// StateStore is a key-value store keeping state across requests, used by
+// the features needing it, like WithIdempotencyDetection. The default store
+// is in memory, see NewMemoryStateStore; a store shared by the replicas of
+// a service, e.g. backed by Redis, makes these features consistent across
+// them. Implementations must be safe for concurrent use.
+type StateStore interface {
+	// Get returns the value of key, and whether it is set.
+	Get(ctx context.Context, key string) (string, bool, error)
+	// Set sets the value of key, expiring after ttl, or never if ttl is 0.
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+	// Incr increments the integer value of key, which is 0 when it is not
+	// set, and returns the new value. The ttl is only applied when the key
+	// is created.
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+}
+
+// MemoryStateStore is an in-memory StateStore, evicting the least recently
+// used keys beyond its capacity.
+type MemoryStateStore struct {
+	mu      sync.Mutex
+	entries *lru
+	now     func() time.Time
+}
+
+var _ StateStore = (*MemoryStateStore)(nil)
+
+type memoryEntry struct {
+	value   string
+	expires time.Time
+}
+
+// defaultStateStoreCapacity is the capacity of the store used when the
+// features needing one are enabled without WithStateStore.
+const defaultStateStoreCapacity = 10000
+
+// NewMemoryStateStore returns a MemoryStateStore keeping at most capacity
+// keys.
+func NewMemoryStateStore(capacity int) *MemoryStateStore {
+	return &MemoryStateStore{
+		entries: newLRU(capacity),
+		now:     time.Now,
+	}
+}
+
+// get returns the live entry of key, marking it as recently used.
+func (s *MemoryStateStore) get(key string) (*memoryEntry, bool) {
+	value, ok := s.entries.get(key)
+	if !ok {
+		return nil, false
+	}
+	entry := value.(*memoryEntry)
+	if !entry.expires.IsZero() && !s.now().Before(entry.expires) {
+		s.entries.remove(key)
+		return nil, false
+	}
+	return entry, true
+}
+
+func (s *MemoryStateStore) set(key, value string, ttl time.Duration) {
+	var expires time.Time
+	if ttl > 0 {
+		expires = s.now().Add(ttl)
+	}
+	s.entries.set(key, &memoryEntry{value: value, expires: expires})
+}
+
+// Get implements StateStore.
+func (s *MemoryStateStore) Get(_ context.Context, key string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.get(key)
+	if !ok {
+		return "", false, nil
+	}
+	return entry.value, true, nil
+}
+
+// Set implements StateStore.
+func (s *MemoryStateStore) Set(_ context.Context, key, value string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.set(key, value, ttl)
+	return nil
+}
+
+// Incr implements StateStore.
+func (s *MemoryStateStore) Incr(_ context.Context, key string, ttl time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.get(key)
+	if !ok {
+		s.set(key, "1", ttl)
+		return 1, nil
+	}
+	n, err := strconv.ParseInt(entry.value, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	n++
+	entry.value = strconv.FormatInt(n, 10)
+	return n, nil
+}
@@ -0,0 +1,47 @@
+package otelchi
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStateStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(0, 0)
+	store := NewMemoryStateStore(2)
+	store.now = func() time.Time { return now }
+
+	require.NoError(t, store.Set(ctx, "a", "1", 0))
+	require.NoError(t, store.Set(ctx, "b", "2", time.Minute))
+	value, ok, err := store.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "1", value)
+
+	// "b" is the least recently used key
+	require.NoError(t, store.Set(ctx, "c", "3", 0))
+	_, ok, _ = store.Get(ctx, "b")
+	assert.False(t, ok)
+
+	n, err := store.Incr(ctx, "c", 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), n)
+	n, err = store.Incr(ctx, "d", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	now = now.Add(time.Minute)
+	_, ok, _ = store.Get(ctx, "d")
+	assert.False(t, ok)
+	n, err = store.Incr(ctx, "d", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	require.NoError(t, store.Set(ctx, "nan", "x", 0))
+	_, err = store.Incr(ctx, "nan", 0)
+	assert.Error(t, err)
+}
@@ -0,0 +1,51 @@
+package otelchi
+
+import (
+	"net/http"
+	"path"
+	"strings"
+)
+
+// DefaultStaticAssetPatterns returns the patterns filtered out by
+// WithStaticAssetFilter when none is given.
+func DefaultStaticAssetPatterns() []string {
+	return []string{
+		"/static/", "/assets/", "/favicon.ico",
+		"*.js", "*.css", "*.map", "*.png", "*.jpg", "*.jpeg", "*.gif", "*.svg", "*.ico", "*.webp",
+		"*.woff", "*.woff2", "*.ttf", "*.eot",
+	}
+}
+
+// staticAssetFilter returns a filter rejecting requests whose path matches
+// one of the patterns: "*.ext" patterns match the extension, patterns
+// ending with a slash match the path prefix and other patterns match the
+// path exactly. Extensions are matched case-insensitively.
+func staticAssetFilter(patterns []string) func(r *http.Request) bool {
+	var (
+		extensions = make(map[string]bool)
+		prefixes   []string
+		paths      = make(map[string]bool)
+	)
+	for _, pattern := range patterns {
+		switch {
+		case strings.HasPrefix(pattern, "*."):
+			extensions[strings.ToLower(pattern[1:])] = true
+		case strings.HasSuffix(pattern, "/"):
+			prefixes = append(prefixes, pattern)
+		default:
+			paths[pattern] = true
+		}
+	}
+	return func(r *http.Request) bool {
+		p := r.URL.Path
+		if paths[p] || extensions[strings.ToLower(path.Ext(p))] {
+			return false
+		}
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(p, prefix) {
+				return false
+			}
+		}
+		return true
+	}
+}
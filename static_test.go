@@ -0,0 +1,36 @@
+package otelchi
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStaticAssetFilter(t *testing.T) {
+	filter := staticAssetFilter(DefaultStaticAssetPatterns())
+
+	testCases := []struct {
+		path string
+		want bool
+	}{
+		{path: "/user/123", want: true},
+		{path: "/static/logo", want: false},
+		{path: "/assets/app/main.txt", want: false},
+		{path: "/app.JS", want: false},
+		{path: "/img/logo.png", want: false},
+		{path: "/favicon.ico", want: false},
+		{path: "/staticfile", want: true},
+		{path: "/api/report.pdf", want: true},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.path, func(t *testing.T) {
+			assert.Equal(t, testCase.want, filter(httptest.NewRequest("GET", testCase.path, nil)))
+		})
+	}
+
+	filter = staticAssetFilter([]string{"/public/", "*.wasm"})
+	assert.False(t, filter(httptest.NewRequest("GET", "/public/index.html", nil)))
+	assert.False(t, filter(httptest.NewRequest("GET", "/app.wasm", nil)))
+	assert.True(t, filter(httptest.NewRequest("GET", "/app.js", nil)))
+}
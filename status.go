@@ -0,0 +1,61 @@
+package otelchi
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+const noWriteKey = attribute.Key("http.response.no_write")
+
+// ZeroStatusPolicy tells how to record the status of responses for which
+// the handler never called Write or WriteHeader, e.g. because it hijacked
+// the connection or produced no output.
+type ZeroStatusPolicy int
+
+const (
+	// ZeroStatusRecord records the status as 0, which is reported as an
+	// invalid status code. This is the default.
+	ZeroStatusRecord ZeroStatusPolicy = iota
+	// ZeroStatusInferOK records the status as 200, which is what net/http
+	// sends when the handler returns without writing anything.
+	ZeroStatusInferOK
+	// ZeroStatusNoWrite records no status code, the http.response.no_write
+	// attribute instead, and leaves the span status unset.
+	ZeroStatusNoWrite
+)
+
+// recordStatus records the response status in the span attributes and
+// status, following the zero status policy when nothing was written.
+func (tw traceware) recordStatus(span oteltrace.Span, rrw *recordingResponseWriter) {
+	status := rrw.status
+	if !rrw.written {
+		switch tw.zeroStatusPolicy {
+		case ZeroStatusInferOK:
+			status = http.StatusOK
+		case ZeroStatusNoWrite:
+			span.SetAttributes(noWriteKey.Bool(true))
+			return
+		}
+	}
+
+	// set status code attribute
+	span.SetAttributes(semconv.HTTPStatusCodeKey.Int(status))
+
+	// set span status
+	spanStatus, spanMessage := tw.spanStatus(status)
+	span.SetStatus(spanStatus, spanMessage)
+}
+
+// spanStatus returns the span status of a response with the given status
+// code, mapped by WithSpanStatusMapper or following the semantic
+// conventions.
+func (tw traceware) spanStatus(status int) (codes.Code, string) {
+	if tw.spanStatusMapper != nil {
+		return tw.spanStatusMapper(status)
+	}
+	return semconv.SpanStatusFromHTTPStatusCode(status)
+}
@@ -0,0 +1,80 @@
+package otelchi
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSDKIntegrationWithZeroStatusPolicy(t *testing.T) {
+	testCases := []struct {
+		name       string
+		policy     ZeroStatusPolicy
+		wantAttr   attribute.KeyValue
+		wantStatus codes.Code
+	}{
+		{name: "record", policy: ZeroStatusRecord, wantAttr: attribute.Int("http.status_code", 0), wantStatus: codes.Error},
+		{name: "infer OK", policy: ZeroStatusInferOK, wantAttr: attribute.Int("http.status_code", http.StatusOK), wantStatus: codes.Unset},
+		{name: "no write", policy: ZeroStatusNoWrite, wantAttr: attribute.Bool("http.response.no_write", true), wantStatus: codes.Unset},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			sr := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider()
+			provider.RegisterSpanProcessor(sr)
+
+			router := chi.NewRouter()
+			router.Use(Middleware("foobar", WithTracerProvider(provider), WithZeroStatusPolicy(testCase.policy)))
+			router.HandleFunc("/noop", func(w http.ResponseWriter, r *http.Request) {})
+
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/noop", nil))
+
+			require.Len(t, sr.Ended(), 1)
+			span := sr.Ended()[0]
+			assert.Contains(t, span.Attributes(), testCase.wantAttr)
+			assert.Equal(t, testCase.wantStatus, span.Status().Code)
+		})
+	}
+}
+
+func TestSDKIntegrationWithSpanStatusMapper(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar",
+		WithTracerProvider(provider),
+		// only server errors and canceled requests are errors
+		WithSpanStatusMapper(func(status int) (codes.Code, string) {
+			if status >= 500 || status == 499 {
+				return codes.Error, fmt.Sprintf("status %d", status)
+			}
+			return codes.Unset, ""
+		}),
+	))
+	router.HandleFunc("/user/{status}", func(w http.ResponseWriter, r *http.Request) {
+		status, _ := strconv.Atoi(chi.URLParam(r, "status"))
+		w.WriteHeader(status)
+	})
+
+	for _, status := range []string{"404", "499", "503"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/user/"+status, nil))
+	}
+
+	spans := sr.Ended()
+	require.Len(t, spans, 3)
+	assert.Equal(t, sdktrace.Status{Code: codes.Unset}, spans[0].Status())
+	assert.Equal(t, sdktrace.Status{Code: codes.Error, Description: "status 499"}, spans[1].Status())
+	assert.Equal(t, sdktrace.Status{Code: codes.Error, Description: "status 503"}, spans[2].Status())
+}
@@ -0,0 +1,61 @@
+package otelchi
+
+import (
+	"mime"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+const (
+	responseStreamingKey = attribute.Key("http.response.streaming")
+
+	responseChunkEventName = "http.response.chunk"
+	responseChunkBytesKey  = attribute.Key("http.response.chunk.bytes")
+	responseChunkTotalKey  = attribute.Key("http.response.chunk.total_bytes")
+)
+
+func isEventStreamContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "text/event-stream"
+}
+
+// beforeHeaders is called before the response headers are sent.
+func (rrw *recordingResponseWriter) beforeHeaders(header http.Header) {
+	rrw.prepareHTMLInjection(header)
+	if isEventStreamContentType(header.Get("Content-Type")) {
+		rrw.startStreaming()
+	}
+}
+
+// startStreaming stops buffering the response body, which is a stream of
+// unbounded length.
+func (rrw *recordingResponseWriter) startStreaming() {
+	rrw.streaming = true
+	rrw.responseBody = rrw.responseBody[:0]
+}
+
+// streamChunks emits the chunk events of streamed responses.
+type streamChunks struct {
+	span      oteltrace.Span
+	interval  time.Duration
+	clock     func() time.Time
+	lastEvent time.Time
+	lastBytes int64
+}
+
+// flushed adds a chunk event for the bytes written since the last one,
+// unless it was added less than the interval ago.
+func (c *streamChunks) flushed(bytesWritten int64) {
+	flushed := now(c.clock)
+	if bytesWritten == c.lastBytes || flushed.Sub(c.lastEvent) < c.interval {
+		return
+	}
+	c.span.AddEvent(responseChunkEventName, oteltrace.WithTimestamp(flushed), oteltrace.WithAttributes(
+		responseChunkBytesKey.Int64(bytesWritten-c.lastBytes),
+		responseChunkTotalKey.Int64(bytesWritten),
+	))
+	c.lastEvent, c.lastBytes = flushed, bytesWritten
+}
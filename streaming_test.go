@@ -0,0 +1,59 @@
+package otelchi
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSDKIntegrationWithStreamedResponse(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar", WithTracerProvider(provider), WithStreamChunkEvents(0)))
+	router.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher, ok := w.(http.Flusher)
+		require.True(t, ok)
+		for i := 0; i < 3; i++ {
+			fmt.Fprintf(w, "data: %d\n\n", i)
+			flusher.Flush()
+		}
+	})
+	router.HandleFunc("/flushed", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/events", nil))
+	assert.Equal(t, "data: 0\n\ndata: 1\n\ndata: 2\n\n", w.Body.String())
+	assert.True(t, w.Flushed)
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/flushed", nil))
+
+	spans := sr.Ended()
+	require.Len(t, spans, 2)
+	for _, span := range spans {
+		assertSpan(t, span, span.Name(), trace.SpanKindServer, attribute.Bool("http.response.streaming", true))
+		for _, kv := range span.Attributes() {
+			assert.NotEqual(t, attribute.Key("http.response.body"), kv.Key)
+		}
+	}
+	events := spans[0].Events()
+	require.Len(t, events, 3)
+	assert.Equal(t, "http.response.chunk", events[2].Name)
+	assert.Contains(t, events[2].Attributes, attribute.Int64("http.response.chunk.bytes", 9))
+	assert.Contains(t, events[2].Attributes, attribute.Int64("http.response.chunk.total_bytes", 27))
+}
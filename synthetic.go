@@ -0,0 +1,66 @@
+package otelchi
+
+import (
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const userAgentSyntheticTypeKey = attribute.Key("user_agent.synthetic.type")
+
+// The values of user_agent.synthetic.type.
+const (
+	syntheticTypeBot  = "bot"
+	syntheticTypeTest = "test"
+)
+
+// syntheticUserAgents are substrings of the lowercased user agents of
+// well-known synthetic monitors, probes and crawlers, with their synthetic
+// type.
+var syntheticUserAgents = []struct {
+	substring     string
+	syntheticType string
+}{
+	{"pingdom", syntheticTypeTest},
+	{"uptimerobot", syntheticTypeTest},
+	{"statuscake", syntheticTypeTest},
+	{"datadogsynthetics", syntheticTypeTest},
+	{"newrelicpinger", syntheticTypeTest},
+	{"site24x7", syntheticTypeTest},
+	{"kube-probe/", syntheticTypeTest},
+	{"elb-healthchecker/", syntheticTypeTest},
+	{"googlehc/", syntheticTypeTest},
+	{"googlebot", syntheticTypeBot},
+	{"bingbot", syntheticTypeBot},
+	{"yandexbot", syntheticTypeBot},
+	{"duckduckbot", syntheticTypeBot},
+	{"baiduspider", syntheticTypeBot},
+	{"applebot", syntheticTypeBot},
+	{"ahrefsbot", syntheticTypeBot},
+	{"semrushbot", syntheticTypeBot},
+	{"facebookexternalhit", syntheticTypeBot},
+}
+
+// syntheticType returns the synthetic type of the traffic sent with
+// userAgent, "test" for monitors and probes, "bot" for crawlers, or an
+// empty string when it does not look synthetic.
+func syntheticType(userAgent string) string {
+	if userAgent == "" {
+		return ""
+	}
+	userAgent = strings.ToLower(userAgent)
+	for _, synthetic := range syntheticUserAgents {
+		if strings.Contains(userAgent, synthetic.substring) {
+			return synthetic.syntheticType
+		}
+	}
+	return ""
+}
+
+func syntheticAttributes(r *http.Request) []attribute.KeyValue {
+	if synthetic := syntheticType(r.UserAgent()); synthetic != "" {
+		return []attribute.KeyValue{userAgentSyntheticTypeKey.String(synthetic)}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package otelchi
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSyntheticType(t *testing.T) {
+	for userAgent, expected := range map[string]string{
+		"Pingdom.com_bot_version_1.4_(http://www.pingdom.com/)":                  "test",
+		"Mozilla/5.0+(compatible; UptimeRobot/2.0; http://www.uptimerobot.com/)": "test",
+		"kube-probe/1.27": "test",
+		"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)": "bot",
+		"Mozilla/5.0 (X11; Linux x86_64; rv:109.0) Gecko/20100101 Firefox/115.0":   "",
+		"": "",
+	} {
+		assert.Equal(t, expected, syntheticType(userAgent), userAgent)
+	}
+}
+
+func TestSDKIntegrationWithSyntheticTraffic(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		opts  []Option
+		spans int
+	}{
+		{name: "detection", opts: []Option{WithSyntheticTrafficDetection()}, spans: 2},
+		{name: "filter", opts: []Option{WithSyntheticTrafficDetection(), WithSyntheticTrafficFilter()}, spans: 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			sr := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider()
+			provider.RegisterSpanProcessor(sr)
+
+			router := chi.NewRouter()
+			router.Use(Middleware("foobar", append(tc.opts, WithTracerProvider(provider))...))
+			router.HandleFunc("/user/{id}", ok)
+
+			r := httptest.NewRequest("GET", "/user/123", nil)
+			r.Header.Set("User-Agent", "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)")
+			router.ServeHTTP(httptest.NewRecorder(), r)
+			r = httptest.NewRequest("GET", "/user/123", nil)
+			r.Header.Set("User-Agent", "curl/8.0.1")
+			router.ServeHTTP(httptest.NewRecorder(), r)
+
+			spans := sr.Ended()
+			require.Len(t, spans, tc.spans)
+			if tc.spans == 2 {
+				assert.Contains(t, spans[0].Attributes(), attribute.String("user_agent.synthetic.type", "bot"))
+			}
+			for _, a := range spans[len(spans)-1].Attributes() {
+				assert.NotEqual(t, attribute.Key("user_agent.synthetic.type"), a.Key)
+			}
+		})
+	}
+}
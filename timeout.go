@@ -0,0 +1,53 @@
+package otelchi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+const (
+	timeoutEventName = "http.server.timeout"
+	timeoutKey       = attribute.Key("http.server.timeout_ms")
+)
+
+// Timeout is a drop-in replacement of the chi middleware.Timeout, which
+// cancels the context of the request after timeout and answers with a 504
+// Gateway Timeout when the handler returns with the context deadline
+// exceeded. Used after the otelchi middleware, it also marks the server
+// span of timed out requests with an error.type=timeout attribute, an
+// http.server.timeout event carrying the timeout, and an error status, so
+// that they can be told apart from handlers legitimately answering with a
+// 504.
+func Timeout(timeout time.Duration) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer func() {
+				cancel()
+				if ctx.Err() == context.DeadlineExceeded {
+					if owner, ok := ctx.Value(spanOwnerKey{}).(*spanOwner); ok {
+						owner.timeout = timeout
+					}
+					w.WriteHeader(http.StatusGatewayTimeout)
+				}
+			}()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// recordTimeout marks the span of a request whose handler was cut off
+// after timeout.
+func recordTimeout(span oteltrace.Span, timeout time.Duration) {
+	span.SetAttributes(errorTypeKey.String("timeout"))
+	span.AddEvent(timeoutEventName, oteltrace.WithAttributes(
+		timeoutKey.Float64(float64(timeout)/float64(time.Millisecond)),
+	))
+	span.SetStatus(codes.Error, fmt.Sprintf("handler timed out after %s", timeout))
+}
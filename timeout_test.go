@@ -0,0 +1,54 @@
+package otelchi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSDKIntegrationWithTimeout(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar", WithTracerProvider(provider)))
+	router.Use(Timeout(10 * time.Millisecond))
+	router.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	router.HandleFunc("/gateway", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGatewayTimeout)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/slow", nil))
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/gateway", nil))
+
+	spans := sr.Ended()
+	require.Len(t, spans, 2)
+	assertSpan(t, spans[0], "/slow", trace.SpanKindServer,
+		attribute.Int("http.status_code", http.StatusGatewayTimeout),
+		attribute.String("error.type", "timeout"),
+	)
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, "handler timed out after 10ms", spans[0].Status().Description)
+	require.Len(t, spans[0].Events(), 1)
+	assert.Equal(t, "http.server.timeout", spans[0].Events()[0].Name)
+	assert.Equal(t, []attribute.KeyValue{attribute.Float64("http.server.timeout_ms", 10)}, spans[0].Events()[0].Attributes)
+
+	// a handler answering with a 504 did not time out
+	assert.Contains(t, spans[1].Attributes(), attribute.String("error.type", "504"))
+	assert.Empty(t, spans[1].Events())
+}
@@ -0,0 +1,61 @@
+package otelchi
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	tlsProtocolNameKey      = attribute.Key("tls.protocol.name")
+	tlsProtocolVersionKey   = attribute.Key("tls.protocol.version")
+	tlsCipherKey            = attribute.Key("tls.cipher")
+	tlsNextProtocolKey      = attribute.Key("tls.next_protocol")
+	tlsResumedKey           = attribute.Key("tls.resumed")
+	tlsServerNameKey        = attribute.Key("tls.client.server_name")
+	tlsClientCertificateKey = attribute.Key("tls.client.certificate.presented")
+)
+
+// tlsAttributes returns the attributes of the TLS connection r was
+// received on, if any.
+func tlsAttributes(r *http.Request) []attribute.KeyValue {
+	state := r.TLS
+	if state == nil {
+		return nil
+	}
+	name, version := tlsVersion(state.Version)
+	attrs := []attribute.KeyValue{
+		tlsProtocolNameKey.String(name),
+		tlsProtocolVersionKey.String(version),
+		tlsCipherKey.String(tls.CipherSuiteName(state.CipherSuite)),
+		tlsResumedKey.Bool(state.DidResume),
+		tlsClientCertificateKey.Bool(len(state.PeerCertificates) > 0),
+	}
+	if state.NegotiatedProtocol != "" {
+		attrs = append(attrs, tlsNextProtocolKey.String(state.NegotiatedProtocol))
+	}
+	if state.ServerName != "" {
+		attrs = append(attrs, tlsServerNameKey.String(state.ServerName))
+	}
+	return attrs
+}
+
+// tlsVersion returns the protocol name and version of a TLS version
+// number, as tls.VersionName only exists since Go 1.21.
+func tlsVersion(version uint16) (name, ver string) {
+	switch version {
+	case tls.VersionTLS10:
+		return "tls", "1.0"
+	case tls.VersionTLS11:
+		return "tls", "1.1"
+	case tls.VersionTLS12:
+		return "tls", "1.2"
+	case tls.VersionTLS13:
+		return "tls", "1.3"
+	case tls.VersionSSL30:
+		return "ssl", "3.0"
+	}
+	return "tls", fmt.Sprintf("0x%04X", version)
+}
@@ -0,0 +1,63 @@
+package otelchi
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSDKIntegrationWithTLS(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar", WithTracerProvider(provider)))
+	router.HandleFunc("/user", ok)
+
+	r := httptest.NewRequest("GET", "/user", nil)
+	r.TLS = &tls.ConnectionState{
+		Version:            tls.VersionTLS13,
+		CipherSuite:        tls.TLS_AES_128_GCM_SHA256,
+		NegotiatedProtocol: "h2",
+		ServerName:         "api.example.com",
+		PeerCertificates:   []*x509.Certificate{{}},
+	}
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	require.Len(t, sr.Ended(), 1)
+	assertSpan(t, sr.Ended()[0], "/user", trace.SpanKindServer,
+		attribute.String("tls.protocol.name", "tls"),
+		attribute.String("tls.protocol.version", "1.3"),
+		attribute.String("tls.cipher", "TLS_AES_128_GCM_SHA256"),
+		attribute.String("tls.next_protocol", "h2"),
+		attribute.Bool("tls.resumed", false),
+		attribute.String("tls.client.server_name", "api.example.com"),
+		attribute.Bool("tls.client.certificate.presented", true),
+	)
+}
+
+func TestTLSAttributesWithoutTLS(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	assert.Nil(t, tlsAttributes(r))
+}
+
+func TestTLSVersion(t *testing.T) {
+	for version, want := range map[uint16][2]string{
+		tls.VersionTLS10: {"tls", "1.0"},
+		tls.VersionTLS12: {"tls", "1.2"},
+		0x0305:           {"tls", "0x0305"},
+	} {
+		name, ver := tlsVersion(version)
+		assert.Equal(t, want, [2]string{name, ver})
+	}
+}
@@ -0,0 +1,125 @@
+package otelchi
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+const truncatedSuffixFormat = "…[truncated %d bytes]"
+
+// valueLimit truncates the string attributes longer than limit bytes, which
+// exporters would otherwise drop.
+type valueLimit struct {
+	limit int
+	// companion records the number of bytes truncated in a <key>.truncated
+	// attribute instead of a suffix of the value
+	companion bool
+}
+
+// truncate returns attrs with their string values truncated. attrs is only
+// copied when a value is.
+func (l *valueLimit) truncate(attrs []attribute.KeyValue) []attribute.KeyValue {
+	var truncated []attribute.KeyValue
+	for i, kv := range attrs {
+		var dropped int
+		switch kv.Value.Type() {
+		case attribute.STRING:
+			var s string
+			if s, dropped = l.truncateString(kv.Value.AsString()); dropped > 0 {
+				kv = kv.Key.String(s)
+			}
+		case attribute.STRINGSLICE:
+			values := kv.Value.AsStringSlice()
+			for j, v := range values {
+				s, n := l.truncateString(v)
+				values[j] = s
+				dropped += n
+			}
+			if dropped > 0 {
+				kv = kv.Key.StringSlice(values)
+			}
+		}
+		if dropped == 0 {
+			if truncated != nil {
+				truncated = append(truncated, kv)
+			}
+			continue
+		}
+		if truncated == nil {
+			truncated = make([]attribute.KeyValue, i, len(attrs)+1)
+			copy(truncated, attrs[:i])
+		}
+		truncated = append(truncated, kv)
+		if l.companion {
+			truncated = append(truncated, attribute.Int(string(kv.Key)+".truncated", dropped))
+		}
+	}
+	if truncated == nil {
+		return attrs
+	}
+	return truncated
+}
+
+// truncateString returns s truncated to the limit, on a rune boundary, and
+// the number of bytes dropped. Without companion attributes, the suffix
+// marking the truncation is counted in the limit.
+func (l *valueLimit) truncateString(s string) (string, int) {
+	if len(s) <= l.limit {
+		return s, 0
+	}
+	keep := l.limit
+	if !l.companion {
+		// the number of bytes dropped is at most len(s), so its suffix is
+		// at least as long
+		keep -= len(fmt.Sprintf(truncatedSuffixFormat, len(s)))
+		if keep < 0 {
+			keep = 0
+		}
+	}
+	for keep > 0 && !utf8.RuneStart(s[keep]) {
+		keep--
+	}
+	dropped := len(s) - keep
+	if l.companion {
+		return s[:keep], dropped
+	}
+	return s[:keep] + fmt.Sprintf(truncatedSuffixFormat, dropped), dropped
+}
+
+// truncatingSpan truncates the string attributes set on the span, including
+// the attributes of its events.
+type truncatingSpan struct {
+	oteltrace.Span
+	limit *valueLimit
+}
+
+func (s *truncatingSpan) SetAttributes(kv ...attribute.KeyValue) {
+	s.Span.SetAttributes(s.limit.truncate(kv)...)
+}
+
+func (s *truncatingSpan) AddEvent(name string, options ...oteltrace.EventOption) {
+	s.Span.AddEvent(name, s.eventOptions(options)...)
+}
+
+func (s *truncatingSpan) RecordError(err error, options ...oteltrace.EventOption) {
+	s.Span.RecordError(err, s.eventOptions(options)...)
+}
+
+// eventOptions returns options with the attributes they set truncated.
+func (s *truncatingSpan) eventOptions(options []oteltrace.EventOption) []oteltrace.EventOption {
+	cfg := oteltrace.NewEventConfig(options...)
+	attrs := cfg.Attributes()
+	truncated := s.limit.truncate(attrs)
+	if len(attrs) == 0 || &truncated[0] == &attrs[0] {
+		// nothing was truncated
+		return options
+	}
+	return []oteltrace.EventOption{
+		oteltrace.WithAttributes(truncated...),
+		oteltrace.WithTimestamp(cfg.Timestamp()),
+		oteltrace.WithStackTrace(cfg.StackTrace()),
+	}
+}
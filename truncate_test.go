@@ -0,0 +1,82 @@
+package otelchi
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestValueLimitTruncate(t *testing.T) {
+	attrs := []attribute.KeyValue{
+		attribute.String("short", "abc"),
+		attribute.String("long", strings.Repeat("x", 40)),
+		attribute.StringSlice("slice", []string{"abc", strings.Repeat("é", 20)}),
+		attribute.Int("int", 1),
+	}
+
+	l := &valueLimit{limit: 30}
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String("short", "abc"),
+		attribute.String("long", strings.Repeat("x", 7)+"…[truncated 33 bytes]"),
+		attribute.StringSlice("slice", []string{"abc", strings.Repeat("é", 3) + "…[truncated 34 bytes]"}),
+		attribute.Int("int", 1),
+	}, l.truncate(attrs))
+	// the attributes are not modified in place
+	assert.Equal(t, strings.Repeat("x", 40), attrs[1].Value.AsString())
+
+	l = &valueLimit{limit: 5, companion: true}
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String("short", "abc"),
+		attribute.String("long", "xxxxx"),
+		attribute.Int("long.truncated", 35),
+		attribute.StringSlice("slice", []string{"abc", "éé"}),
+		attribute.Int("slice.truncated", 36),
+		attribute.Int("int", 1),
+	}, l.truncate(attrs))
+
+	short := attrs[:1]
+	assert.Equal(t, &short[0], &l.truncate(short)[0])
+}
+
+func TestSDKIntegrationWithAttributeValueLimit(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar",
+		WithTracerProvider(provider),
+		WithAttributeValueLimit(32, false),
+		WithErrorEvents(),
+	))
+	router.HandleFunc("/user/{id}", func(w http.ResponseWriter, r *http.Request) {
+		RecordError(r.Context(), errors.New("failed"))
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(strings.Repeat("y", 100)))
+	})
+
+	r := httptest.NewRequest("GET", "/user/"+strings.Repeat("1", 50), nil)
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	spans := sr.Ended()
+	require.Len(t, spans, 1)
+	assertSpan(t, spans[0], "/user/{id}", trace.SpanKindServer,
+		attribute.String("http.target", "/user/111…[truncated 47 bytes]"),
+		attribute.String("http.response.body", strings.Repeat("y", 8)+"…[truncated 92 bytes]"),
+	)
+	for _, a := range spans[0].Attributes() {
+		if a.Value.Type() == attribute.STRING {
+			assert.LessOrEqual(t, len(a.Value.AsString()), 32, a.Key)
+		}
+	}
+}
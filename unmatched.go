@@ -0,0 +1,41 @@
+package otelchi
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	defaultNotFoundSpanName         = "route not found"
+	defaultMethodNotAllowedSpanName = "method not allowed"
+
+	urlPathKey = attribute.Key("url.path")
+)
+
+// unmatchedSpanNames names the spans of the requests matching no route,
+// which would otherwise be unnamed. The names are prefixed by the request
+// method, e.g. "GET route not found", keeping their cardinality bounded:
+// unknown methods are replaced by _OTHER.
+type unmatchedSpanNames struct {
+	notFound         string
+	methodNotAllowed string
+}
+
+var defaultUnmatchedSpanNames = &unmatchedSpanNames{
+	notFound:         defaultNotFoundSpanName,
+	methodNotAllowed: defaultMethodNotAllowedSpanName,
+}
+
+// spanName returns the name of the span of an unmatched request, answered
+// with status.
+func (n *unmatchedSpanNames) spanName(method string, status int) string {
+	name := n.notFound
+	if status == http.StatusMethodNotAllowed {
+		name = n.methodNotAllowed
+	}
+	if name == "" {
+		return ""
+	}
+	return boundedMethod(method) + " " + name
+}
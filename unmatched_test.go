@@ -0,0 +1,78 @@
+package otelchi
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSDKIntegrationWithUnmatchedRoutes(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		opts  []Option
+		names []string
+	}{
+		{
+			name:  "default names",
+			names: []string{"GET route not found", "POST method not allowed"},
+		},
+		{
+			name:  "custom names",
+			opts:  []Option{WithUnmatchedSpanNames("unknown", "")},
+			names: []string{"GET unknown", ""},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			sr := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider()
+			provider.RegisterSpanProcessor(sr)
+
+			router := chi.NewRouter()
+			router.Use(Middleware("foobar", append(tc.opts, WithTracerProvider(provider))...))
+			router.Get("/user/{id}", ok)
+
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/nope/123", nil))
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/user/123", nil))
+
+			spans := sr.Ended()
+			require.Len(t, spans, 2)
+			assertSpan(t, spans[0], tc.names[0], trace.SpanKindServer,
+				attribute.String("url.path", "/nope/123"),
+				attribute.Int("http.status_code", 404),
+			)
+			assertSpan(t, spans[1], tc.names[1], trace.SpanKindServer,
+				attribute.String("url.path", "/user/123"),
+				attribute.Int("http.status_code", 405),
+			)
+			for _, span := range spans {
+				for _, a := range span.Attributes() {
+					assert.NotEqual(t, semconv.HTTPRouteKey, a.Key)
+				}
+			}
+		})
+	}
+}
+
+func TestSDKIntegrationWithUnmatchedRouteUnknownMethod(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar", WithTracerProvider(provider)))
+	router.Get("/user/{id}", ok)
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("X-RANDOM-1", "/nope/123", nil))
+
+	require.Len(t, sr.Ended(), 1)
+	// chi does not route unknown methods
+	assert.Equal(t, "_OTHER method not allowed", sr.Ended()[0].Name())
+}
@@ -0,0 +1,152 @@
+package otelchi
+
+import (
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	userAgentNameKey    = attribute.Key("user_agent.name")
+	userAgentVersionKey = attribute.Key("user_agent.version")
+	osNameKey           = attribute.Key("os.name")
+	osVersionKey        = attribute.Key("os.version")
+)
+
+// UserAgent is the client described by a User-Agent header.
+type UserAgent struct {
+	// Name is the name of the client, e.g. "Firefox" or "curl".
+	Name string
+	// Version is the version of the client.
+	Version string
+	// OSName is the name of the operating system of the client, e.g.
+	// "Android".
+	OSName string
+	// OSVersion is the version of the operating system of the client.
+	OSVersion string
+}
+
+// UserAgentParser parses User-Agent headers, for WithUserAgentParser.
+// Implementations must be safe for concurrent use.
+type UserAgentParser interface {
+	Parse(userAgent string) UserAgent
+}
+
+// UserAgentParserFunc is a func implementing UserAgentParser.
+type UserAgentParserFunc func(userAgent string) UserAgent
+
+// Parse implements UserAgentParser.
+func (f UserAgentParserFunc) Parse(userAgent string) UserAgent {
+	return f(userAgent)
+}
+
+// DefaultUserAgentParser returns a lightweight UserAgentParser, recognizing
+// the major browsers and operating systems from their well-known tokens,
+// and naming the other clients, like curl or okhttp, after their first
+// product token.
+func DefaultUserAgentParser() UserAgentParser {
+	return UserAgentParserFunc(parseUserAgent)
+}
+
+// browserTokens are the product tokens of the major browsers, in the order
+// they must be looked for, since browsers also send the tokens of the
+// browsers they derive from.
+var browserTokens = []struct {
+	token string
+	name  string
+}{
+	{"Edg/", "Edge"},
+	{"OPR/", "Opera"},
+	{"SamsungBrowser/", "Samsung Internet"},
+	{"Firefox/", "Firefox"},
+	{"CriOS/", "Chrome"},
+	{"Chrome/", "Chrome"},
+}
+
+func parseUserAgent(userAgent string) UserAgent {
+	var ua UserAgent
+	ua.OSName, ua.OSVersion = parseOS(userAgent)
+	for _, browser := range browserTokens {
+		if version, ok := tokenValue(userAgent, browser.token); ok {
+			ua.Name, ua.Version = browser.name, version
+			return ua
+		}
+	}
+	if strings.Contains(userAgent, "Safari/") {
+		ua.Name = "Safari"
+		ua.Version, _ = tokenValue(userAgent, "Version/")
+		return ua
+	}
+	// other clients are named after their first product token, e.g.
+	// curl/8.0.1
+	product := userAgent
+	if i := strings.IndexByte(product, ' '); i >= 0 {
+		product = product[:i]
+	}
+	if i := strings.IndexByte(product, '/'); i > 0 && product[:i] != "Mozilla" {
+		ua.Name, ua.Version = product[:i], product[i+1:]
+	}
+	return ua
+}
+
+func parseOS(userAgent string) (name, version string) {
+	switch {
+	case strings.Contains(userAgent, "Windows NT "):
+		version, _ = tokenValue(userAgent, "Windows NT ")
+		return "Windows", version
+	case strings.Contains(userAgent, "Android"):
+		version, _ = tokenValue(userAgent, "Android ")
+		return "Android", version
+	case strings.Contains(userAgent, "iPhone") || strings.Contains(userAgent, "iPad"):
+		version, ok := tokenValue(userAgent, "iPhone OS ")
+		if !ok {
+			version, _ = tokenValue(userAgent, "CPU OS ")
+		}
+		return "iOS", strings.ReplaceAll(version, "_", ".")
+	case strings.Contains(userAgent, "Mac OS X"):
+		version, _ = tokenValue(userAgent, "Mac OS X ")
+		return "macOS", strings.ReplaceAll(version, "_", ".")
+	case strings.Contains(userAgent, "CrOS"):
+		return "ChromeOS", ""
+	case strings.Contains(userAgent, "Linux"):
+		return "Linux", ""
+	}
+	return "", ""
+}
+
+// tokenValue returns the value following token in userAgent, up to the
+// next separator.
+func tokenValue(userAgent, token string) (string, bool) {
+	i := strings.Index(userAgent, token)
+	if i < 0 {
+		return "", false
+	}
+	value := userAgent[i+len(token):]
+	if end := strings.IndexAny(value, " ;)"); end >= 0 {
+		value = value[:end]
+	}
+	return value, true
+}
+
+func userAgentAttributes(parser UserAgentParser, r *http.Request) []attribute.KeyValue {
+	userAgent := r.UserAgent()
+	if userAgent == "" {
+		return nil
+	}
+	ua := parser.Parse(userAgent)
+	var attrs []attribute.KeyValue
+	if ua.Name != "" {
+		attrs = append(attrs, userAgentNameKey.String(ua.Name))
+	}
+	if ua.Version != "" {
+		attrs = append(attrs, userAgentVersionKey.String(ua.Version))
+	}
+	if ua.OSName != "" {
+		attrs = append(attrs, osNameKey.String(ua.OSName))
+	}
+	if ua.OSVersion != "" {
+		attrs = append(attrs, osVersionKey.String(ua.OSVersion))
+	}
+	return attrs
+}
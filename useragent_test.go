@@ -0,0 +1,72 @@
+package otelchi
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestDefaultUserAgentParser(t *testing.T) {
+	for userAgent, expected := range map[string]UserAgent{
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/114.0.0.0 Safari/537.36": {
+			Name: "Chrome", Version: "114.0.0.0", OSName: "Windows", OSVersion: "10.0",
+		},
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/114.0.0.0 Safari/537.36 Edg/114.0.1823.51": {
+			Name: "Edge", Version: "114.0.1823.51", OSName: "Windows", OSVersion: "10.0",
+		},
+		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.5 Safari/605.1.15": {
+			Name: "Safari", Version: "16.5", OSName: "macOS", OSVersion: "10.15.7",
+		},
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 16_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.5 Mobile/15E148 Safari/604.1": {
+			Name: "Safari", Version: "16.5", OSName: "iOS", OSVersion: "16.5",
+		},
+		"Mozilla/5.0 (Android 13; Mobile; rv:109.0) Gecko/114.0 Firefox/114.0": {
+			Name: "Firefox", Version: "114.0", OSName: "Android", OSVersion: "13",
+		},
+		"Mozilla/5.0 (X11; Linux x86_64; rv:109.0) Gecko/20100101 Firefox/115.0": {
+			Name: "Firefox", Version: "115.0", OSName: "Linux",
+		},
+		"curl/8.0.1":        {Name: "curl", Version: "8.0.1"},
+		"okhttp/4.11.0":     {Name: "okhttp", Version: "4.11.0"},
+		"Mozilla/5.0":       {},
+		"some custom agent": {},
+	} {
+		assert.Equal(t, expected, DefaultUserAgentParser().Parse(userAgent), userAgent)
+	}
+}
+
+func TestSDKIntegrationWithUserAgentParser(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar",
+		WithTracerProvider(provider),
+		WithUserAgentParser(nil),
+	))
+	router.HandleFunc("/user/{id}", ok)
+
+	r := httptest.NewRequest("GET", "/user/123", nil)
+	r.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:109.0) Gecko/20100101 Firefox/115.0")
+	router.ServeHTTP(httptest.NewRecorder(), r)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/user/123", nil))
+
+	spans := sr.Ended()
+	require.Len(t, spans, 2)
+	assertSpan(t, spans[0], "/user/{id}", trace.SpanKindServer,
+		attribute.String("user_agent.name", "Firefox"),
+		attribute.String("user_agent.version", "115.0"),
+		attribute.String("os.name", "Linux"),
+	)
+	for _, a := range spans[1].Attributes() {
+		assert.NotEqual(t, attribute.Key("user_agent.name"), a.Key)
+	}
+}
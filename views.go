@@ -0,0 +1,37 @@
+//go:build go1.18
+// +build go1.18
+
+package otelchi
+
+import (
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/aggregation"
+)
+
+// DurationView returns a view setting the bucket boundaries, in
+// milliseconds, of the request duration histogram recorded by the
+// middleware. The metric API does not let instrumentations choose the
+// boundaries of their histograms, so the view is to be registered with the
+// meter provider passed to WithMeterProvider, e.g.:
+//
+//	provider := sdkmetric.NewMeterProvider(
+//		sdkmetric.WithReader(reader),
+//		sdkmetric.WithView(otelchi.DurationView(0.1, 0.25, 0.5, 1, 2.5, 5, 10)),
+//	)
+//
+// The default boundaries of the SDK suit neither sub-millisecond internal
+// APIs nor multi-second batch endpoints. Other views of the instruments of
+// the middleware can be built with sdkmetric.NewView and MeterScope.
+func DurationView(boundaries ...float64) sdkmetric.View {
+	return sdkmetric.NewView(
+		sdkmetric.Instrument{Name: otelMetricNames.duration, Scope: MeterScope()},
+		sdkmetric.Stream{Aggregation: aggregation.ExplicitBucketHistogram{Boundaries: boundaries}},
+	)
+}
+
+// MeterScope returns the instrumentation scope of the instruments of the
+// middleware, to match them in views.
+func MeterScope() instrumentation.Scope {
+	return instrumentation.Scope{Name: tracerName}
+}
@@ -0,0 +1,57 @@
+package otelchi
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// WebSocketPolicy tells when the span of a WebSocket upgrade request ends.
+type WebSocketPolicy int
+
+const (
+	// WebSocketSpanUntilClose keeps the span open until the handler
+	// returns, which is usually when the connection is closed. This is the
+	// default.
+	WebSocketSpanUntilClose WebSocketPolicy = iota
+	// WebSocketSpanUntilUpgrade ends the span when the handler hijacks the
+	// connection to switch protocols, so that the span covers the
+	// handshake only.
+	WebSocketSpanUntilUpgrade
+)
+
+// isWebSocketUpgrade reports whether r asks to switch to the WebSocket
+// protocol.
+func isWebSocketUpgrade(r *http.Request) bool {
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		return false
+	}
+	for _, value := range r.Header.Values("Connection") {
+		for _, token := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// endAtUpgrade returns the hijack callback ending the span of an upgrade
+// request, named after the route the request was routed to.
+func (tw traceware) endAtUpgrade(span oteltrace.Span, r *http.Request, routePattern string, rrw *recordingResponseWriter) func() {
+	return func() {
+		if routePattern == "" {
+			if rctx := chi.RouteContext(r.Context()); rctx != nil {
+				if pattern := rctx.RoutePattern(); pattern != "" {
+					span.SetAttributes(semconv.HTTPRouteKey.String(pattern))
+					span.SetName(tw.guardSpanName(r.Context(), r.Method, addPrefixToSpanName(tw.reqMethodInSpanName, r.Method, pattern)))
+				}
+			}
+		}
+		tw.recordStatus(span, rrw)
+		span.End(oteltrace.WithTimestamp(now(tw.clock)))
+	}
+}
@@ -0,0 +1,65 @@
+package otelchi
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestIsWebSocketUpgrade(t *testing.T) {
+	r := httptest.NewRequest("GET", "/ws", nil)
+	assert.False(t, isWebSocketUpgrade(r))
+	r.Header.Set("Upgrade", "WebSocket")
+	assert.False(t, isWebSocketUpgrade(r))
+	r.Header.Set("Connection", "keep-alive, Upgrade")
+	assert.True(t, isWebSocketUpgrade(r))
+}
+
+func TestSDKIntegrationWithWebSocketPolicy(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	closed := make(chan struct{})
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar", WithTracerProvider(provider), WithWebSocketPolicy(WebSocketSpanUntilUpgrade)))
+	router.HandleFunc("/ws/{room}", func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		require.NoError(t, err)
+		defer conn.Close()
+		_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+		_ = rw.Flush()
+		<-closed
+	})
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("GET /ws/lobby HTTP/1.1\r\nHost: example.com\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n"))
+	require.NoError(t, err)
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+
+	// the span ended at upgrade time, while the connection is still open
+	require.Eventually(t, func() bool { return len(sr.Ended()) == 1 }, time.Second, time.Millisecond)
+	close(closed)
+	assertSpan(t, sr.Ended()[0], "/ws/{room}", trace.SpanKindServer,
+		attribute.String("network.protocol.name", "websocket"),
+		attribute.Int("http.status_code", http.StatusSwitchingProtocols),
+	)
+}
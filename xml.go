@@ -0,0 +1,166 @@
+package otelchi
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"sort"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const redactedValue = "[REDACTED]"
+
+// xmlCapture configures the analysis of XML request and response bodies.
+type xmlCapture struct {
+	extractors []xmlExtractor
+	redact     map[string]bool
+}
+
+// xmlExtractor extracts the text content of the first element matching
+// path. Supported paths are absolute element paths ("/Envelope/Body/Id")
+// and descendant paths ("//Id", "//Body/Id"); elements are matched by their
+// local name.
+type xmlExtractor struct {
+	key  attribute.Key
+	path string
+}
+
+func newXMLCapture(extractors map[string]string, redact []string) *xmlCapture {
+	c := &xmlCapture{redact: make(map[string]bool, len(redact))}
+	for _, name := range redact {
+		c.redact[name] = true
+	}
+	for name, path := range extractors {
+		c.extractors = append(c.extractors, xmlExtractor{key: attribute.Key(name), path: path})
+	}
+	sort.Slice(c.extractors, func(i, j int) bool {
+		return c.extractors[i].key < c.extractors[j].key
+	})
+	return c
+}
+
+func (e xmlExtractor) matches(path string) bool {
+	if strings.HasPrefix(e.path, "//") {
+		suffix := e.path[1:]
+		return path == suffix[1:] || strings.HasSuffix(path, suffix)
+	}
+	return path == e.path
+}
+
+// isXMLContentType reports whether contentType denotes an XML document,
+// e.g. text/xml, application/xml or application/soap+xml.
+func isXMLContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return strings.HasSuffix(mediaType, "/xml") || strings.HasSuffix(mediaType, "+xml")
+}
+
+// analyze checks body for well-formedness and returns the structural
+// attributes prefixed by prefix (e.g. "http.request.body.xml"), the
+// extracted field attributes, and the body with the content of redacted
+// elements replaced. The original body is returned when nothing was
+// redacted. When elements are to be redacted but the body is malformed,
+// it can't be scrubbed: a placeholder is returned instead, along with an
+// error telling why.
+func (c *xmlCapture) analyze(prefix string, body []byte) ([]attribute.KeyValue, []byte, error) {
+	var (
+		dec        = xml.NewDecoder(bytes.NewReader(body))
+		stack      []string
+		redacted   int // depth of the outermost redacted element, 0 if none
+		root       string
+		elements   int
+		found      = make(map[attribute.Key]bool, len(c.extractors))
+		attrs      []attribute.KeyValue
+		ranges     [][2]int64
+		offset     int64
+		wellFormed = true
+		parseErr   error
+	)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			wellFormed = false
+			parseErr = err
+			break
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if root == "" {
+				root = t.Name.Local
+			}
+			elements++
+			stack = append(stack, t.Name.Local)
+			if redacted == 0 && c.redact[t.Name.Local] {
+				redacted = len(stack)
+			}
+		case xml.EndElement:
+			if redacted == len(stack) {
+				redacted = 0
+			}
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			text := strings.TrimSpace(string(t))
+			if text == "" || len(stack) == 0 {
+				break
+			}
+			if redacted > 0 {
+				ranges = append(ranges, [2]int64{offset, dec.InputOffset()})
+				text = redactedValue
+			}
+			path := "/" + strings.Join(stack, "/")
+			for _, e := range c.extractors {
+				if !found[e.key] && e.matches(path) {
+					found[e.key] = true
+					attrs = append(attrs, e.key.String(text))
+				}
+			}
+		}
+		offset = dec.InputOffset()
+	}
+	if root == "" && wellFormed {
+		wellFormed = false
+		parseErr = errors.New("no root element")
+	}
+
+	attrs = append(attrs, attribute.Bool(prefix+".well_formed", wellFormed))
+	if root != "" {
+		attrs = append(attrs,
+			attribute.String(prefix+".root", root),
+			attribute.Int(prefix+".element_count", elements),
+		)
+	}
+	if !wellFormed {
+		// do not risk exporting a partially redacted document
+		if len(c.redact) > 0 {
+			return attrs, []byte(redactedValue), fmt.Errorf("malformed XML: %w", parseErr)
+		}
+		return attrs, body, nil
+	}
+	return attrs, redactRanges(body, ranges), nil
+}
+
+func redactRanges(body []byte, ranges [][2]int64) []byte {
+	if len(ranges) == 0 {
+		return body
+	}
+	var (
+		out  = make([]byte, 0, len(body))
+		last int64
+	)
+	for _, r := range ranges {
+		out = append(out, body[last:r[0]]...)
+		out = append(out, redactedValue...)
+		last = r[1]
+	}
+	return append(out, body[last:]...)
+}
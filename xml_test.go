@@ -0,0 +1,73 @@
+package otelchi
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestXMLCaptureAnalyze(t *testing.T) {
+	c := newXMLCapture(map[string]string{
+		"app.user_id":  "/Envelope/Body/GetUser/Id",
+		"app.password": "//Password",
+	}, []string{"Credentials"})
+
+	body := `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+  <soap:Body><GetUser><Id>42</Id><Credentials><Password>hunter2</Password></Credentials></GetUser></soap:Body>
+</soap:Envelope>`
+	attrs, redacted, err := c.analyze("http.request.body.xml", []byte(body))
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []attribute.KeyValue{
+		attribute.String("app.user_id", "42"),
+		attribute.String("app.password", "[REDACTED]"),
+		attribute.Bool("http.request.body.xml.well_formed", true),
+		attribute.String("http.request.body.xml.root", "Envelope"),
+		attribute.Int("http.request.body.xml.element_count", 6),
+	}, attrs)
+	assert.Equal(t, strings.Replace(body, "hunter2", "[REDACTED]", 1), string(redacted))
+
+	attrs, redacted, err = c.analyze("http.request.body.xml", []byte(`<a><Credentials>secret</a>`))
+	assert.Error(t, err)
+	assert.Contains(t, attrs, attribute.Bool("http.request.body.xml.well_formed", false))
+	assert.Equal(t, "[REDACTED]", string(redacted))
+}
+
+func TestSDKIntegrationWithXMLCapture(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider()
+	provider.RegisterSpanProcessor(sr)
+
+	router := chi.NewRouter()
+	router.Use(Middleware("foobar",
+		WithTracerProvider(provider),
+		WithXMLCapture(map[string]string{"app.status": "/Response/Status"}, "Token"),
+	))
+	router.HandleFunc("/soap", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/xml")
+		_, _ = w.Write([]byte(`<Response><Status>OK</Status></Response>`))
+	})
+
+	r := httptest.NewRequest("POST", "/soap", strings.NewReader(`<Request><Token>abc</Token></Request>`))
+	r.Header.Set("Content-Type", "text/xml; charset=utf-8")
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	require.Len(t, sr.Ended(), 1)
+	assertSpan(t, sr.Ended()[0], "/soap", trace.SpanKindServer,
+		attribute.String("http.request.body", "<Request><Token>[REDACTED]</Token></Request>"),
+		attribute.String("http.request.body.xml.root", "Request"),
+		attribute.String("http.response.body.xml.root", "Response"),
+		attribute.Bool("http.response.body.xml.well_formed", true),
+		attribute.String("app.status", "OK"),
+	)
+}